@@ -3,30 +3,22 @@ package main
 import (
 	"flag"
 	"fmt"
-	"image/color"
-	"image/png"
-	"image"
 	"os"
 	"path/filepath"
 	"strings"
-	"bytes"
-	"github.com/ProfElements/go-files/pkg/formats/cftkk/crt"
+
+	"github.com/ProfElements/go-files/internal/cli"
 )
 
 /*
-NAME:
-DESCRIPTION:
-USAGE:
+NAME: CRTWork
+DESCRIPTION: Converts .krt textures to png and back, a shorthand for gofiles convert.
+USAGE: CRTWork [-inputfile file] [-outputfile file] [file]
 */
 var inputFile string
 var outputFile string
 
 func main() {
-
-	if len(os.Args) < 2 {
-		panic("We need atleast one krt file to continue.")
-	}
-
 	flag.StringVar(&inputFile, "inputfile", "", " Input file path pointing to a texture")
 
 	flag.StringVar(&outputFile, "outputfile", "", "Output file path for the resultant png")
@@ -34,81 +26,26 @@ func main() {
 	flag.Parse()
 
 	if inputFile == "" {
-		inputFile = os.Args[1]
+		inputFile = flag.Arg(0)
 	}
-
-	if filepath.Ext(inputFile) != "" && filepath.Ext(inputFile) != ".krt" && filepath.Ext(inputFile) != ".png" {
-		panic("We need either a png or krt file to continue")
+	if inputFile == "" {
+		fmt.Fprintln(os.Stderr, "usage: CRTWork [-inputfile file] [-outputfile file] [file]")
+		os.Exit(cli.ExitUsage)
 	}
-	
-	strPath := filepath.Base(inputFile)
-	strPath = strings.Trim(strPath, filepath.Ext(strPath))
-
-	if filepath.Ext(inputFile) == ".krt" || filepath.Ext(inputFile) == "" {
-		file, err := crt.ReadKRT(inputFile)
-
-		if err != nil {
-			fmt.Printf("Something went wrong wither opening %v, %v\n", inputFile, err)
-		}
-
-		rgba, err := file.DecodeFromKRT()
-		if err != nil {
-			fmt.Printf("Something went wrong with decoding the KRTImage to rgba %v", err)
-		}
-
-		rgbaFile, err := os.Create(strPath + ".png")
-		if err != nil {
-			fmt.Printf("Something went wrong with creating the png file %v", err)
-		}
-		defer rgbaFile.Close()
-
-		err = png.Encode(rgbaFile, rgba)
-		if err != nil {
-			fmt.Printf("Something when wrong with encoding the png file %v", err)
-		}
 
-	} else {
-		raw, err := os.ReadFile(inputFile)
-		if err != nil {
-			fmt.Printf("something went wrong while reading the file")
-		}
-		rgba, err := png.Decode(bytes.NewBuffer(raw))
-		if err != nil { fmt.Printf("Decoding png did something wrong") }			
-		
-		if rgba.ColorModel() != color.RGBAModel {
-			img := image.NewRGBA(image.Rect(0, 0, rgba.Bounds().Dx(), rgba.Bounds().Dy()))
-			//Convert to RGBA8
-			for y := rgba.Bounds().Min.Y; y < rgba.Bounds().Max.Y; y++ {
-				for x := rgba.Bounds().Min.X; x < rgba.Bounds().Max.X; x++ {
-					c := color.RGBAModel.Convert(rgba.At(x, y)).(color.RGBA)
-					
-
-					img.Set(x, y, c)
-				}
-			}
-			
-			krtimage, err := crt.EncodeToKRT(img)
-			if err != nil {
-				fmt.Printf("Something happened while encoding png to krt")
-			}
-
-			err = krtimage.WriteKRT(strPath + ".krt")
-			if err != nil {
-				fmt.Printf("WRITING THE THING DIDNT WORKRKKKK")
-			}
-	  }
-				
-		pngImage, ok := rgba.(*image.RGBA) 
-		if !ok {
-			panic("PANICCING BECAUSE NOT RGBA")
+	//A png becomes a krt and anything else a png, named after the input in the working directory.
+	if outputFile == "" {
+		strPath := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+		if strings.EqualFold(filepath.Ext(inputFile), ".png") {
+			outputFile = strPath + ".krt"
+		} else {
+			outputFile = strPath + ".png"
 		}
+	}
 
-		krtimage, err := crt.EncodeToKRT(pngImage)
-		if err != nil { fmt.Printf("Something wrong happened while encoding to krt") }
-				
-		err = krtimage.WriteKRT(strPath + ".krt")
-		if err != nil {
-			fmt.Printf("WRITING THE THING DIDNT WORKRKKKK")
-		}
+	args := []string{"convert", inputFile, outputFile}
+	if !strings.EqualFold(filepath.Ext(inputFile), ".png") {
+		args = []string{"convert", "-format", "krt", inputFile, outputFile}
 	}
+	os.Exit(cli.Run(args))
 }
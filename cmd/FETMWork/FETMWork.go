@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/ProfElements/go-files/internal/cli"
 	"github.com/ProfElements/go-files/pkg/formats/cftkk/fetm"
 )
 
@@ -19,11 +20,30 @@ var inputFile string
 var outputFile string
 var isJson bool
 var isRaw bool
+var isText bool
+var isValidate bool
+var isClasses bool
+
+/*
+classesUsage ...
+The help of the -classes flag every command of FETMWork takes
+*/
+const classesUsage = "Register the provisional Pickup, Spawner and Trigger layouts, their parameters get names like position.x"
 
 func main() {
 
 	if len(os.Args) < 2 {
-		panic("We need atleast one fetm file to continue.")
+		fmt.Fprintln(os.Stderr, "usage: FETMWork [diff, merge, edit] [flags] [-inputfile] file")
+		os.Exit(cli.ExitUsage)
+	}
+
+	switch os.Args[1] {
+	case "diff":
+		os.Exit(runDiff(os.Args[2:]))
+	case "merge":
+		os.Exit(runMerge(os.Args[2:]))
+	case "edit":
+		os.Exit(runEdit(os.Args[2:]))
 	}
 
 	flag.StringVar(&inputFile, "inputfile", "", " Input file path pointing to either a fetm file or a json file.")
@@ -32,9 +52,24 @@ func main() {
 
 	flag.BoolVar(&isRaw, "raw", true, "A flag to specify whether to output raw or constructed json")
 	flag.BoolVar(&isJson, "json", false, "Whether to convert json to fetm")
+	flag.BoolVar(&isText, "text", false, "Use the indented text format instead of json, in both directions")
+	flag.BoolVar(&isValidate, "validate", false, "Only validate the given fetm files, print every problem and exit 1 if there are any but warnings")
+	flag.BoolVar(&isClasses, "classes", false, classesUsage)
 
 	flag.Parse()
 
+	if isClasses {
+		fetm.RegisterKrustyKrabClasses()
+	}
+
+	if isValidate {
+		files := flag.Args()
+		if inputFile != "" {
+			files = append([]string{inputFile}, files...)
+		}
+		os.Exit(cli.Run(append([]string{"validate", "-format", "fetm"}, files...)))
+	}
+
 	if inputFile == "" {
 		inputFile = os.Args[1]
 	}
@@ -55,14 +90,22 @@ func parseData(data []byte) {
 	var err error
 
 	if isJson {
-		file, err = fetm.DecodeFromJSON(data, isRaw)
+		if isText {
+			file, err = fetm.DecodeFromText(data)
+		} else {
+			file, err = fetm.DecodeFromJSON(data, isRaw)
+		}
+		if err != nil {
+			fmt.Printf("something happened when decoding the input %v\n", err)
+			return
+		}
 		data, err = file.Write()
 		if err != nil {
 			fmt.Printf("something happened when encoding to json")
 		}
 
 		strPath := filepath.Base(inputFile)
-		strPath = strings.Trim(strPath, filepath.Ext(strPath))
+		strPath = strings.TrimSuffix(strPath, filepath.Ext(strPath))
 		fmt.Printf(strPath + ".fetm")
 
 		if outputFile == "" {
@@ -73,7 +116,18 @@ func parseData(data []byte) {
 
 	} else {
 		file, err = fetm.Read(data)
-		json, err := file.EncodeToJSON(isRaw)
+		if err != nil {
+			fmt.Printf("something happened when reading the fetm file %v\n", err)
+			return
+		}
+		var json []byte
+		ext := ".json"
+		if isText {
+			json, err = file.EncodeToText()
+			ext = ".txt"
+		} else {
+			json, err = file.EncodeToJSON(isRaw)
+		}
 
 		if err != nil {
 			fmt.Printf("something happened when encoding to json")
@@ -81,10 +135,10 @@ func parseData(data []byte) {
 
 		strPath := filepath.Base(inputFile)
 		strPath = strings.TrimSuffix(strPath, filepath.Ext(strPath))
-		fmt.Printf(strPath + ".json")
+		fmt.Printf(strPath + ext)
 
 		if outputFile == "" {
-			os.WriteFile(strPath+".json", json, 0777)
+			os.WriteFile(strPath+ext, json, 0777)
 		} else {
 			os.WriteFile(outputFile, json, 0777)
 		}
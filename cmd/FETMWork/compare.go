@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ProfElements/go-files/internal/cli"
+	"github.com/ProfElements/go-files/pkg/formats/cftkk/fetm"
+)
+
+/*
+runDiff ...
+FETMWork diff [-classes] old new, print every change from old to new. Exits 1 when they differ.
+*/
+func runDiff(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	classes := flags.Bool("classes", false, classesUsage)
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		fmt.Println("usage: FETMWork diff [-classes] old new")
+		return cli.ExitUsage
+	}
+	if *classes {
+		fetm.RegisterKrustyKrabClasses()
+	}
+
+	a, err := loadFETM(flags.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		return cli.ExitUsage
+	}
+	b, err := loadFETM(flags.Arg(1))
+	if err != nil {
+		fmt.Println(err)
+		return cli.ExitUsage
+	}
+
+	diff := fetm.Diff(a, b)
+	if diff.Empty() {
+		return cli.ExitOK
+	}
+	fmt.Print(diff.String())
+
+	return cli.ExitFailure
+}
+
+/*
+runMerge ...
+FETMWork merge [-classes] [-o output] base ours theirs, merge both sides into output, ours by default.
+Conflicts are printed and keep our side, the exit code is 1 when there were any.
+*/
+func runMerge(args []string) int {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	output := flags.String("o", "", "Output file for the merged fetm, text or json, by its extension. Defaults to ours.")
+	classes := flags.Bool("classes", false, classesUsage)
+	flags.Parse(args)
+
+	if flags.NArg() != 3 {
+		fmt.Println("usage: FETMWork merge [-classes] [-o output] base ours theirs")
+		return cli.ExitUsage
+	}
+	if *classes {
+		fetm.RegisterKrustyKrabClasses()
+	}
+
+	var files [3]*fetm.FETM
+	for idx, path := range flags.Args() {
+		file, err := loadFETM(path)
+		if err != nil {
+			fmt.Println(err)
+			return cli.ExitUsage
+		}
+		files[idx] = file
+	}
+
+	merged, conflicts := fetm.Merge(files[0], files[1], files[2])
+	for _, conflict := range conflicts {
+		fmt.Println("conflict " + conflict.String())
+	}
+
+	if *output == "" {
+		*output = flags.Arg(1)
+	}
+	err := saveFETM(*output, merged)
+	if err != nil {
+		fmt.Println(err)
+		return cli.ExitUsage
+	}
+
+	if len(conflicts) > 0 {
+		return cli.ExitFailure
+	}
+	return cli.ExitOK
+}
+
+/*
+loadFETM ...
+Read a fetm file, or its text or raw json form when the extension is .txt or .json
+*/
+func loadFETM(path string) (*fetm.FETM, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("something went wrong while opening %v, %v", path, err)
+	}
+
+	var file *fetm.FETM
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".txt":
+		file, err = fetm.DecodeFromText(data)
+	case ".json":
+		file, err = fetm.DecodeFromJSON(data, true)
+	default:
+		file, err = fetm.Read(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("something happened when reading %v %v", path, err)
+	}
+
+	return file, nil
+}
+
+func saveFETM(path string, file *fetm.FETM) error {
+	var data []byte
+	var err error
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".txt":
+		data, err = file.EncodeToText()
+	case ".json":
+		data, err = file.EncodeToJSON(true)
+	default:
+		data, err = file.Write()
+	}
+	if err != nil {
+		return fmt.Errorf("something happened when encoding %v %v", path, err)
+	}
+
+	return os.WriteFile(path, data, 0777)
+}
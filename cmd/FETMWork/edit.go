@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ProfElements/go-files/internal/cli"
+	"github.com/ProfElements/go-files/pkg/formats/cftkk/fetm"
+)
+
+type editOperation struct {
+	op    string
+	path  string
+	value string
+}
+
+/*
+editOperations ...
+The -set and -scale flags, kept in the order they were given
+*/
+type editOperations struct {
+	op         string
+	operations *[]editOperation
+}
+
+func (ops editOperations) String() string {
+	return ""
+}
+
+func (ops editOperations) Set(arg string) error {
+	split := strings.Index(arg, "=")
+	if split < 0 {
+		return fmt.Errorf("%v should look like path=value", arg)
+	}
+	*ops.operations = append(*ops.operations, editOperation{op: ops.op, path: strings.TrimSpace(arg[:split]), value: strings.TrimSpace(arg[split+1:])})
+	return nil
+}
+
+type selectorList []fetm.Selector
+
+func (list *selectorList) String() string {
+	return ""
+}
+
+func (list *selectorList) Set(arg string) error {
+	selector, err := fetm.ParseSelector(arg)
+	if err != nil {
+		return err
+	}
+	*list = append(*list, selector)
+	return nil
+}
+
+/*
+runEdit ...
+FETMWork edit [-dry-run] [-classes] -where selector... [-set path=value]... [-scale path=factor]... dir
+
+Apply the operations to the parameters of every selected node of every .fetm file under dir,
+in place. -where terms are and-ed, see fetm.ParseSelector. With -dry-run nothing is written
+and the changes every file would get are printed instead.
+*/
+func runEdit(args []string) int {
+	var selectors selectorList
+	var operations []editOperation
+
+	flags := flag.NewFlagSet("edit", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "Print the changes instead of writing them.")
+	flags.Var(&selectors, "where", "Select nodes, like class=Name, factory=Name, type=u16 3 or path=f32 1. Can be repeated.")
+	flags.Var(editOperations{op: "set", operations: &operations}, "set", "Set a parameter, like position.x=f32 1.5. Can be repeated.")
+	flags.Var(editOperations{op: "scale", operations: &operations}, "scale", "Scale an f32 or integer parameter, like 3=2. Can be repeated.")
+	classes := flags.Bool("classes", false, classesUsage)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 || len(selectors) == 0 || len(operations) == 0 {
+		fmt.Println("usage: FETMWork edit [-dry-run] [-classes] -where selector [-set path=value] [-scale path=factor] dir")
+		return cli.ExitUsage
+	}
+	if *classes {
+		fetm.RegisterKrustyKrabClasses()
+	}
+
+	status := cli.ExitOK
+	err := filepath.Walk(flags.Arg(0), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.ToLower(filepath.Ext(path)) != ".fetm" {
+			return nil
+		}
+
+		err = editFile(path, fetm.And(selectors...), operations, *dryRun)
+		if err != nil {
+			fmt.Printf("%v: %v\n", path, err)
+			status = cli.ExitFailure
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Println(err)
+		return cli.ExitUsage
+	}
+
+	return status
+}
+
+func editFile(path string, selector fetm.Selector, operations []editOperation, dryRun bool) error {
+	original, err := loadFETM(path)
+	if err != nil {
+		return err
+	}
+	edited, err := loadFETM(path)
+	if err != nil {
+		return err
+	}
+
+	query := edited.Select(selector)
+	if query.Len() == 0 {
+		return nil
+	}
+
+	for _, operation := range operations {
+		switch operation.op {
+		case "set":
+			value, err := fetm.ParseValue(operation.value)
+			if err != nil {
+				return err
+			}
+			err = query.Set(operation.path, value)
+			if err != nil {
+				return err
+			}
+		case "scale":
+			factor, err := strconv.ParseFloat(operation.value, 32)
+			if err != nil {
+				return fmt.Errorf("scale factor %v is not a number", operation.value)
+			}
+			err = query.Scale(operation.path, float32(factor))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	diff := fetm.Diff(original, edited)
+	if diff.Empty() {
+		return nil
+	}
+	fmt.Printf("%v, %v nodes selected\n%v", path, query.Len(), diff.String())
+
+	if dryRun {
+		return nil
+	}
+	return saveFETM(path, edited)
+}
@@ -2,103 +2,151 @@ package main
 
 import (
 	"fmt"
+	"image"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/ProfElements/go-files/internal/cli"
+	"github.com/ProfElements/go-files/pkg/formats/bmvg/gka"
+	"github.com/ProfElements/go-files/pkg/formats/bmvg/gmd"
 	"github.com/ProfElements/go-files/pkg/formats/bmvg/jam"
+	"github.com/ProfElements/go-files/pkg/formats/nintendo/tpl"
 )
 
 /*
 NAME: JAMWork
 DESCRIPTION: A basic manipulation tool for .jam archives.
-USAGE: JAMwork [-u, -p, --unpack, --pack] [JAM_ARCHIVE, JAM_DIRECTORY]
+USAGE: JAMwork [-u, -p, -g, --unpack, --pack, --gltf] [JAM_ARCHIVE, JAM_DIRECTORY]
 */
 
 func main() {
 	args := os.Args[1:]
 
 	/*
-		args[0] should be one of these: -u, -p, --unpack, --pack
+		args[0] should be one of these: -u, -p, -g, --unpack, --pack, --gltf
 		args[1] should be a path to either a jam archive, or a directory
 	*/
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: JAMWork [-u, -p, -g, --unpack, --pack, --gltf] [JAM_ARCHIVE, JAM_DIRECTORY]")
+		os.Exit(cli.ExitUsage)
+	}
 
+	//Unpacking and packing are gofiles extract and gofiles pack.
 	switch operation := args[0]; operation {
-	case "-u":
-		unpack()
-	case "--unpack":
-		unpack()
-	case "-p":
-		workFile := &jam.Work{}
-		files, err := ioutil.ReadDir(args[1])
-		if err != nil {
-			fmt.Printf("Reading directory failed.")
-
-		}
-		fileData := make([]jam.WorkFile, len(files))
-		for i, f := range files {
-			filePath := args[1] + strings.TrimSuffix(f.Name(), filepath.Ext(f.Name())) + filepath.Ext(f.Name())
-			if err != nil {
-				fmt.Printf("getting absolute path of files didnt work.")
-			}
-
-			binFile, err := ioutil.ReadFile(filePath)
-			if err != nil {
-				fmt.Printf("Reading file failed. %v \n", err)
-			}
-
-			fileData[i].FileName = strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
-			fileData[i].FileExt = strings.Trim(filepath.Ext(f.Name()), ".")
-			fileData[i].Data = binFile
-		}
-		workFile.Files = fileData
-		jamFile, err := jam.Encode(workFile)
-		if err != nil {
-			fmt.Printf("Encoding of data did not work.")
-		}
-
-		file, err := jam.Write(jamFile)
-		if err != nil {
-			fmt.Printf("Writing of jam file did not work")
-		}
-
-		err = ioutil.WriteFile("test.jam", file, 0644)
+	case "-u", "--unpack":
+		os.Exit(cli.Run([]string{"extract", args[1]}))
+	case "-p", "--pack":
+		os.Exit(cli.Run([]string{"pack", "-o", "test.jam", args[1]}))
+	case "-g", "--gltf":
+		exportModels()
+	default:
+		fmt.Fprintf(os.Stderr, "JAMWork: unknown operation %v\n", operation)
+		os.Exit(cli.ExitUsage)
 	}
-
 }
 
-func unpack() {
+func exportModels() {
 	args := os.Args[1:]
+	fmt.Println("The GMD and GKA layouts are provisional, members that don't follow them are skipped.")
 	directory := strings.TrimSuffix(filepath.Base(args[1]), filepath.Ext(filepath.Base(args[1])))
 
 	file, err := ioutil.ReadFile(args[1])
 	if err != nil {
 		fmt.Printf("Loading of the jam archive didn't work.")
+		return
 	}
 	jamFile, err := jam.Read(file)
 	if err != nil {
 		fmt.Printf("Reading of the jam archive didn't work.")
+		return
 	}
+	//Members that can't be decoded are reported, the models of the others are still exported.
 	workFile, err := jam.Decode(jamFile)
 	if err != nil {
-		fmt.Printf("Decoding of the jam archive didn't work.")
+		fmt.Printf("Decoding some members of the jam archive didn't work. %v\n", err)
 	}
 
-	_ = os.Mkdir(directory, 0644)
-	for i := 0; i < len(workFile.Files); i++ {
-		if workFile.Files[i].FileName == "" || workFile.Files[i].FileExt == "nil" || len(workFile.Files[i].Data) == 0 {
+	//Materials refer to textures by the name of the TPL member in the same archive.
+	textures := make(map[string]image.Image)
+	for _, member := range workFile.Files {
+		if strings.Trim(member.FileExt, "\x00") != "TPL" || len(member.Data) == 0 {
+			continue
+		}
+		tplFile, err := tpl.Read(member.Data)
+		if err != nil || tplFile == nil {
+			continue
+		}
+		tplWork, err := tpl.Decode(tplFile)
+		if err != nil || len(tplWork.Images) == 0 {
+			fmt.Printf("Decoding texture %v didn't work. %v\n", strings.Trim(member.FileName, "\x00"), err)
+			continue
+		}
+		textures[strings.Trim(member.FileName, "\x00")] = tplWork.Images[0]
+	}
 
-		} else {
+	var animations []*gka.Animation
+	for _, member := range workFile.Files {
+		if strings.Trim(member.FileExt, "\x00") != "GKA" || len(member.Data) == 0 {
+			continue
+		}
+		name := strings.Trim(member.FileName, "\x00")
 
-			output_file := filepath.Join(directory, strings.Trim(workFile.Files[i].FileName, "\x00")+"."+strings.Trim(workFile.Files[i].FileExt, "\x00"))
+		gkaFile, err := gka.Read(member.Data)
+		if err != nil {
+			fmt.Printf("Reading animation %v didn't work. %v\n", name, err)
+			continue
+		}
+		animation, err := gka.Decode(gkaFile)
+		if err != nil {
+			fmt.Printf("Decoding animation %v didn't work. %v\n", name, err)
+			continue
+		}
+		if animation.Name == "" {
+			animation.Name = name
+		}
+		animations = append(animations, animation)
+	}
 
-			fmt.Printf("File: "+strings.Trim(workFile.Files[i].FileName, "\x00")+"."+strings.Trim(workFile.Files[i].FileExt, "\x00")+" Data Size: %x"+"\n", len(workFile.Files[i].Data))
+	_ = os.Mkdir(directory, 0755)
+	for _, member := range workFile.Files {
+		if strings.Trim(member.FileExt, "\x00") != "GMD" || len(member.Data) == 0 {
+			continue
+		}
+		name := strings.Trim(member.FileName, "\x00")
 
-			err := ioutil.WriteFile(output_file, workFile.Files[i].Data, 0644)
-			if err != nil {
-				fmt.Printf("Writing the extracted files to the output path didnt work :(  %v\n ", err)
+		gmdFile, err := gmd.Read(member.Data)
+		if err != nil {
+			fmt.Printf("Reading model %v didn't work. %v\n", name, err)
+			continue
+		}
+		model, err := gmd.Decode(gmdFile)
+		if err != nil {
+			fmt.Printf("Decoding model %v didn't work. %v\n", name, err)
+			continue
+		}
+		doc, err := gmd.ExportGLTF(model, textures)
+		if err != nil {
+			fmt.Printf("Exporting model %v didn't work. %v\n", name, err)
+			continue
+		}
+		//Every animation whose bones are all in this model's skeleton is attached to it.
+		for _, animation := range animations {
+			if gka.AttachGLTF(doc, animation) == nil {
+				fmt.Printf("Attached animation %v to model %v\n", animation.Name, name)
 			}
 		}
+
+		glb, err := doc.WriteGLB()
+		if err != nil {
+			fmt.Printf("Writing model %v didn't work. %v\n", name, err)
+			continue
+		}
+
+		err = ioutil.WriteFile(filepath.Join(directory, name+".glb"), glb, 0644)
+		if err != nil {
+			fmt.Printf("Writing the exported model to the output path didnt work :(  %v\n ", err)
+		}
 	}
 }
@@ -0,0 +1,13 @@
+package main
+
+/*
+NAME: gofiles
+DESCRIPTION: One tool for every format of the module, see gofiles --help.
+USAGE: gofiles [detect, info, validate, extract, pack, convert] [flags] [arguments]
+*/
+
+import "github.com/ProfElements/go-files/internal/cli"
+
+func main() {
+	cli.Main()
+}
@@ -0,0 +1,514 @@
+package binstruct
+
+/*
+Read and write structs as the binary layouts of file formats, so a header is a struct
+declaration instead of a chain of binary.BigEndian.Uint32(data[idx:idx+4]) calls.
+
+Fields are laid out in order, each right after the one before. They can be fixed size
+integers and floats, arrays of them, nested structs, and strings and byte slices with a
+size tag. Blank fields, like `_ [4]byte`, are padding: they are skipped when reading and left
+as they are when writing. Other unexported fields aren't allowed, they would shift the layout.
+
+The `bin` tag changes how a field is laid out, options are separated by commas:
+
+	le, be      little or big endian for the field, and the fields of a nested struct
+	size=N      a string or []byte of exactly N bytes
+	at=N        the field starts N bytes from the start of its struct, past padding
+	ptr=Field   the field is at the offset Field holds, counted from the start of the data.
+	            It takes no room in the struct itself, and can be a struct, a pointer to one
+	            that stays nil when the offset is 0, or a []byte with len= or end=
+	len=Field   the []byte of a ptr field is as long as Field says
+	end=Field   the []byte of a ptr field ends at the offset Field holds
+
+A field that doesn't fit in the data comes back as a FieldError naming it.
+*/
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+FieldError ...
+A field of a struct that doesn't fit in the data, or points outside of it. Field is the path
+of the field, like "Header.Width", Offset where it is in the data and Err one of the formats
+errors with details.
+*/
+type FieldError struct {
+	Field  string
+	Offset int
+	Err    error
+}
+
+func (err *FieldError) Error() string {
+	return fmt.Sprintf("%v at 0x%X: %v", err.Field, err.Offset, err.Err)
+}
+
+func (err *FieldError) Unwrap() error {
+	return err.Err
+}
+
+/*
+Wrap ...
+Turn an error of Read or Write into a formats.FormatError of format, part names what was read,
+like "image 1 header", and the field is added to it
+*/
+func Wrap(err error, format string, part string) error {
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		return &formats.FormatError{Format: format, Offset: fieldErr.Offset, Field: part + " " + fieldErr.Field, Err: fieldErr.Err}
+	}
+	return err
+}
+
+/*
+Read ...
+Read the struct v points to from data at offset, in order unless a field says otherwise, or
+error. Strings and byte slices share the memory of data.
+*/
+func Read(data []byte, offset int, order binary.ByteOrder, v interface{}) error {
+	value, err := structValue(v)
+	if err != nil {
+		return err
+	}
+	return (&codec{data: data}).structure(value, offset, order, "")
+}
+
+/*
+Write ...
+Write the struct v, or the struct it points to, into data at offset, or error. data has to be
+large enough already, padding and whatever the struct doesn't cover is left as it is. Offsets
+and sizes are written as they are in v, Write doesn't lay anything out.
+*/
+func Write(data []byte, offset int, order binary.ByteOrder, v interface{}) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("binstruct can only write structs, not %T", v)
+	}
+	return (&codec{data: data, write: true}).structure(value, offset, order, "")
+}
+
+/*
+Size ...
+Return how many bytes the struct v, or the struct it points to, takes up in the data, not
+counting what its ptr fields point at, or error
+*/
+func Size(v interface{}) (int, error) {
+	structType := reflect.TypeOf(v)
+	if structType != nil && structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("binstruct can only size structs, not %T", v)
+	}
+
+	layout, err := layoutOf(structType)
+	if err != nil {
+		return 0, err
+	}
+	return layout.size, nil
+}
+
+func structValue(v interface{}) (reflect.Value, error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("binstruct can only read into a pointer to a struct, not %T", v)
+	}
+	return value.Elem(), nil
+}
+
+/*
+field ...
+Where a field of a struct is and how it is stored. offset is from the start of the struct,
+order is nil when the field uses the order of its struct.
+*/
+type field struct {
+	name   string
+	index  int
+	offset int
+	size   int
+	order  binary.ByteOrder
+	blank  bool
+	ptr    string
+	length string
+	end    string
+}
+
+type layout struct {
+	fields []field
+	size   int
+}
+
+func layoutOf(structType reflect.Type) (*layout, error) {
+	result := &layout{}
+	offset := 0
+
+	for i := 0; i < structType.NumField(); i++ {
+		structField := structType.Field(i)
+		f := field{name: structField.Name, index: i, blank: structField.Name == "_"}
+		if structField.PkgPath != "" && !f.blank {
+			return nil, fmt.Errorf("binstruct: %v has unexported field %v", structType, structField.Name)
+		}
+
+		for _, option := range strings.Split(structField.Tag.Get("bin"), ",") {
+			key, arg := option, ""
+			if eq := strings.Index(option, "="); eq >= 0 {
+				key, arg = option[:eq], option[eq+1:]
+			}
+
+			var err error
+			switch key {
+			case "":
+			case "le":
+				f.order = binary.LittleEndian
+			case "be":
+				f.order = binary.BigEndian
+			case "size":
+				f.size, err = parseInt(arg)
+			case "at":
+				var at int
+				at, err = parseInt(arg)
+				if err == nil && at < offset {
+					err = fmt.Errorf("is at 0x%X, before the end of the field before it at 0x%X", at, offset)
+				}
+				offset = at
+			case "ptr":
+				f.ptr = arg
+			case "len":
+				f.length = arg
+			case "end":
+				f.end = arg
+			default:
+				err = fmt.Errorf("has unknown option %q", option)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("binstruct: field %v of %v %v", structField.Name, structType, err)
+			}
+		}
+
+		if f.ptr != "" {
+			if err := checkPointer(structType, structField, f); err != nil {
+				return nil, fmt.Errorf("binstruct: field %v of %v %v", structField.Name, structType, err)
+			}
+			f.offset = -1
+			result.fields = append(result.fields, f)
+			continue
+		}
+
+		size, err := sizeOf(structField.Type, f.size)
+		if err != nil {
+			return nil, fmt.Errorf("binstruct: field %v of %v %v", structField.Name, structType, err)
+		}
+		f.offset, f.size = offset, size
+		offset += size
+		result.fields = append(result.fields, f)
+	}
+
+	result.size = offset
+	return result, nil
+}
+
+func checkPointer(structType reflect.Type, structField reflect.StructField, f field) error {
+	for _, name := range []string{f.ptr, f.length, f.end} {
+		if name == "" {
+			continue
+		}
+		target, ok := structType.FieldByName(name)
+		if !ok || !isInteger(target.Type.Kind()) {
+			return fmt.Errorf("refers to %v, which isn't an integer field", name)
+		}
+	}
+
+	fieldType := structField.Type
+	switch {
+	case fieldType.Kind() == reflect.Struct, fieldType.Kind() == reflect.Ptr && fieldType.Elem().Kind() == reflect.Struct:
+		if f.length != "" || f.end != "" {
+			return fmt.Errorf("points at a struct, it can't have len or end")
+		}
+	case fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Uint8:
+		if (f.length == "") == (f.end == "") {
+			return fmt.Errorf("points at a []byte, it needs either len or end")
+		}
+	default:
+		return fmt.Errorf("has type %v, ptr fields are structs, pointers to structs or []byte", fieldType)
+	}
+	return nil
+}
+
+/*
+sizeOf ...
+The number of bytes a field of fieldType takes up, tagged is its size tag or 0
+*/
+func sizeOf(fieldType reflect.Type, tagged int) (int, error) {
+	switch kind := fieldType.Kind(); {
+	case kind == reflect.String, kind == reflect.Slice && fieldType.Elem().Kind() == reflect.Uint8:
+		if tagged <= 0 {
+			return 0, fmt.Errorf("is a %v without a size", fieldType)
+		}
+		return tagged, nil
+	case tagged != 0:
+		return 0, fmt.Errorf("has a size, only strings and []byte can")
+	case isInteger(kind) && kind != reflect.Int && kind != reflect.Uint, kind == reflect.Float32, kind == reflect.Float64:
+		return int(fieldType.Size()), nil
+	case kind == reflect.Array:
+		elemSize, err := sizeOf(fieldType.Elem(), 0)
+		return elemSize * fieldType.Len(), err
+	case kind == reflect.Struct:
+		layout, err := layoutOf(fieldType)
+		if err != nil {
+			return 0, err
+		}
+		return layout.size, nil
+	}
+	return 0, fmt.Errorf("has type %v, which has no fixed size", fieldType)
+}
+
+func isInteger(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Uint64
+}
+
+func parseInt(arg string) (int, error) {
+	value, err := strconv.ParseInt(arg, 0, 32)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("has size or offset %q, which isn't a number", arg)
+	}
+	return int(value), nil
+}
+
+/*
+codec ...
+Reads or writes the fields of structs in data, which way is write
+*/
+type codec struct {
+	data  []byte
+	write bool
+}
+
+func (c *codec) structure(value reflect.Value, offset int, order binary.ByteOrder, path string) error {
+	layout, err := layoutOf(value.Type())
+	if err != nil {
+		return err
+	}
+
+	//Inline fields first, so ptr fields can use the offsets that were just read wherever they are.
+	for _, f := range layout.fields {
+		if f.blank || f.ptr != "" {
+			continue
+		}
+		fieldOrder := order
+		if f.order != nil {
+			fieldOrder = f.order
+		}
+		err := c.value(value.Field(f.index), offset+f.offset, f.size, fieldOrder, path+f.name)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, f := range layout.fields {
+		if f.ptr == "" {
+			continue
+		}
+		fieldOrder := order
+		if f.order != nil {
+			fieldOrder = f.order
+		}
+		err := c.pointer(value, f, fieldOrder, path+f.name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *codec) value(value reflect.Value, at int, size int, order binary.ByteOrder, path string) error {
+	switch value.Kind() {
+	case reflect.Struct:
+		return c.structure(value, at, order, path+".")
+	case reflect.Array:
+		elemSize := size / maxInt(value.Len(), 1)
+		for i := 0; i < value.Len(); i++ {
+			err := c.value(value.Index(i), at+i*elemSize, elemSize, order, fmt.Sprintf("%v[%v]", path, i))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if at < 0 || at+size > len(c.data) {
+		return &FieldError{Field: path, Offset: at, Err: fmt.Errorf("%w, it ends at 0x%X of 0x%X bytes", formats.ErrTruncated, at+size, len(c.data))}
+	}
+	raw := c.data[at : at+size]
+
+	switch value.Kind() {
+	case reflect.String:
+		if c.write {
+			copy(raw, fixedString(value.String(), size))
+		} else {
+			value.SetString(string(raw))
+		}
+	case reflect.Slice:
+		if c.write {
+			copy(raw, fixedString(string(value.Bytes()), size))
+		} else {
+			value.SetBytes(raw)
+		}
+	case reflect.Float32, reflect.Float64:
+		if c.write {
+			putUint(raw, order, floatBits(value))
+		} else {
+			setFloat(value, getUint(raw, order))
+		}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if c.write {
+			putUint(raw, order, uint64(value.Int()))
+		} else {
+			//Shifting up and back down again extends the sign of values shorter than 64 bits.
+			shift := uint(64 - size*8)
+			value.SetInt(int64(getUint(raw, order)<<shift) >> shift)
+		}
+	default:
+		if c.write {
+			putUint(raw, order, value.Uint())
+		} else {
+			value.SetUint(getUint(raw, order))
+		}
+	}
+
+	return nil
+}
+
+func (c *codec) pointer(parent reflect.Value, f field, order binary.ByteOrder, path string) error {
+	value := parent.Field(f.index)
+	at := integer(parent.FieldByName(f.ptr))
+
+	//Nothing to write is fine wherever it points.
+	if c.write && value.Kind() == reflect.Slice && value.Len() == 0 {
+		return nil
+	}
+	if at > uint64(len(c.data)) {
+		return &FieldError{Field: path, Offset: int(at), Err: fmt.Errorf("%w, %v 0x%X is past the end of the data at 0x%X", formats.ErrInvalid, f.ptr, at, len(c.data))}
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		return c.structure(value, int(at), order, path+".")
+	case reflect.Ptr:
+		if at == 0 {
+			if !c.write {
+				value.Set(reflect.Zero(value.Type()))
+			}
+			return nil
+		}
+		if value.IsNil() {
+			if c.write {
+				return nil
+			}
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return c.structure(value.Elem(), int(at), order, path+".")
+	}
+
+	size := uint64(value.Len())
+	if !c.write {
+		if f.length != "" {
+			size = integer(parent.FieldByName(f.length))
+		} else {
+			end := integer(parent.FieldByName(f.end))
+			if end < at {
+				return &FieldError{Field: path, Offset: int(at), Err: fmt.Errorf("%w, %v 0x%X is before the start at 0x%X", formats.ErrInvalid, f.end, end, at)}
+			}
+			size = end - at
+		}
+	}
+	if at+size > uint64(len(c.data)) {
+		return &FieldError{Field: path, Offset: int(at), Err: fmt.Errorf("%w, it ends at 0x%X of 0x%X bytes", formats.ErrTruncated, at+size, len(c.data))}
+	}
+
+	if c.write {
+		copy(c.data[at:], value.Bytes())
+	} else {
+		value.SetBytes(c.data[at : at+size])
+	}
+	return nil
+}
+
+func integer(value reflect.Value) uint64 {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Int() < 0 {
+			return math.MaxUint64
+		}
+		return uint64(value.Int())
+	}
+	return value.Uint()
+}
+
+func getUint(raw []byte, order binary.ByteOrder) uint64 {
+	switch len(raw) {
+	case 1:
+		return uint64(raw[0])
+	case 2:
+		return uint64(order.Uint16(raw))
+	case 4:
+		return uint64(order.Uint32(raw))
+	}
+	return order.Uint64(raw)
+}
+
+func putUint(raw []byte, order binary.ByteOrder, value uint64) {
+	switch len(raw) {
+	case 1:
+		raw[0] = uint8(value)
+	case 2:
+		order.PutUint16(raw, uint16(value))
+	case 4:
+		order.PutUint32(raw, uint32(value))
+	default:
+		order.PutUint64(raw, value)
+	}
+}
+
+func floatBits(value reflect.Value) uint64 {
+	if value.Kind() == reflect.Float32 {
+		return uint64(math.Float32bits(float32(value.Float())))
+	}
+	return math.Float64bits(value.Float())
+}
+
+func setFloat(value reflect.Value, bits uint64) {
+	if value.Kind() == reflect.Float32 {
+		value.SetFloat(float64(math.Float32frombits(uint32(bits))))
+		return
+	}
+	value.SetFloat(math.Float64frombits(bits))
+}
+
+/*
+fixedString ...
+Pad or cut str to exactly size bytes
+*/
+func fixedString(str string, size int) []byte {
+	buf := make([]byte, size)
+	copy(buf, str)
+	return buf
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,100 @@
+package binstruct
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+type entry struct {
+	Kind  uint16
+	Value int16 `bin:"le"`
+}
+
+type sample struct {
+	Magic   string `bin:"size=4"`
+	Count   uint32
+	_       [2]byte
+	Entries [2]entry
+	Scale   float32
+	Offset  uint32 `bin:"at=0x18"`
+	Length  uint8
+	Entry   *entry `bin:"ptr=Offset"`
+	Payload []byte `bin:"ptr=Offset,len=Length"`
+}
+
+var sampleData = []byte{
+	'T', 'E', 'S', 'T', 0x00, 0x00, 0x00, 0x02, 0xEE, 0xEE,
+	0x00, 0x01, 0xFE, 0xFF, 0x00, 0x02, 0x03, 0x00,
+	0x3F, 0xC0, 0x00, 0x00, 0xAA, 0xAA,
+	0x00, 0x00, 0x00, 0x20, 0x04,
+	0x00, 0x00, 0x00,
+	0x00, 0x07, 0x05, 0x00,
+}
+
+func TestRead(t *testing.T) {
+	size, err := Size(sample{})
+	if err != nil || size != 0x1D {
+		t.Fatalf("the sample is 0x%X bytes, %v", size, err)
+	}
+
+	value := sample{}
+	err = Read(sampleData, 0, binary.BigEndian, &value)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+
+	if value.Magic != "TEST" || value.Count != 2 || value.Scale != 1.5 || value.Offset != 0x20 || value.Length != 4 {
+		t.Fatalf("read %+v", value)
+	}
+	if value.Entries != [2]entry{{1, -2}, {2, 3}} {
+		t.Fatalf("read entries %v, the second field of each is little endian", value.Entries)
+	}
+	if value.Entry == nil || *value.Entry != (entry{7, 5}) || !bytes.Equal(value.Payload, sampleData[0x20:0x24]) {
+		t.Fatalf("read %v and % X at the offset", value.Entry, value.Payload)
+	}
+
+	written := make([]byte, len(sampleData))
+	copy(written, sampleData)
+	for idx := range written[:0x1D] {
+		if idx != 0x08 && idx != 0x09 && idx != 0x16 && idx != 0x17 {
+			written[idx] = 0x00
+		}
+	}
+	err = Write(written, 0, binary.BigEndian, value)
+	if err != nil || !bytes.Equal(written, sampleData) {
+		t.Fatalf("writing gave % X, %v", written, err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		field string
+		err   error
+	}{
+		{"truncated", sampleData[:0x0D], "Entries[0].Value", formats.ErrTruncated},
+		{"offset past the end", append(append([]byte{}, sampleData[:0x1B]...), 0x40, 0x04), "Entry", formats.ErrInvalid},
+		{"length past the end", append(append(append([]byte{}, sampleData[:0x1C]...), 0x40), sampleData[0x1D:]...), "Payload", formats.ErrTruncated},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Read(test.data, 0, binary.BigEndian, &sample{})
+
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) || fieldErr.Field != test.field || !errors.Is(err, test.err) {
+				t.Fatalf("reading gave %v, it has to be %v of %v", err, test.err, test.field)
+			}
+
+			var formatErr *formats.FormatError
+			if !errors.As(Wrap(err, "test", "sample"), &formatErr) || formatErr.Field != "sample "+test.field {
+				t.Fatalf("wrapping gave %v", formatErr)
+			}
+		})
+	}
+}
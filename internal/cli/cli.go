@@ -0,0 +1,172 @@
+package cli
+
+/*
+The gofiles command line. Every subcommand works on any format of the formats registry,
+cmd/gofiles runs it and the older single format tools forward to it.
+
+Every subcommand takes its flags before its arguments, prints its usage for -h and --help
+and exits with ExitOK, ExitFailure when a file couldn't be handled or ExitUsage when it was
+called wrong.
+*/
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	_ "github.com/ProfElements/go-files/pkg/formats/all"
+)
+
+const (
+	ExitOK      = 0 //Everything worked.
+	ExitFailure = 1 //A file couldn't be read, converted or has problems.
+	ExitUsage   = 2 //The command line was wrong.
+)
+
+var (
+	//Stdout gets the results of a command, Stderr its usage and errors.
+	Stdout io.Writer = os.Stdout
+	Stderr io.Writer = os.Stderr
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(cmd *command, args []string) int
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "detect", args: "file...", summary: "Print the most likely format of every file.", run: runDetect},
+		{name: "info", args: "file...", summary: "Print the format, size and contents of every file.", run: runInfo},
+		{name: "validate", args: "file...", summary: "Check that every file reads and decodes, print every problem.", run: runValidate},
+		{name: "extract", args: "file", summary: "Write the members of a container, and every image as png, to a directory.", run: runExtract},
+		{name: "pack", args: "directory | manifest.json", summary: "Build a container from the files of a directory, or repack what extract -recursive wrote.", run: runPack},
+		{name: "convert", args: "input output", summary: "Convert a file to the format of the output extension, like texture.tpl texture.png.", run: runConvert},
+	}
+}
+
+/*
+Main ...
+Run the command line of the process and exit with its status
+*/
+func Main() {
+	os.Exit(Run(os.Args[1:]))
+}
+
+/*
+Run ...
+Run a gofiles command line, args without the program name, and return the exit status
+*/
+func Run(args []string) int {
+	if len(args) == 0 {
+		usage(Stderr)
+		return ExitUsage
+	}
+
+	switch args[0] {
+	case "-h", "-help", "--help":
+		usage(Stdout)
+		return ExitOK
+	case "help":
+		if len(args) < 2 {
+			usage(Stdout)
+			return ExitOK
+		}
+		cmd, ok := lookupCommand(args[1])
+		if !ok {
+			fmt.Fprintf(Stderr, "gofiles: there is no command %v\n", args[1])
+			return ExitUsage
+		}
+		//The flags of a command are only defined when it runs, so it is run for its help.
+		stderr := Stderr
+		Stderr = Stdout
+		defer func() { Stderr = stderr }()
+		return cmd.run(cmd, []string{"-h"})
+	}
+
+	cmd, ok := lookupCommand(args[0])
+	if !ok {
+		fmt.Fprintf(Stderr, "gofiles: there is no command %v\n\n", args[0])
+		usage(Stderr)
+		return ExitUsage
+	}
+
+	return cmd.run(cmd, args[1:])
+}
+
+func usage(out io.Writer) {
+	fmt.Fprintf(out, "usage: gofiles command [flags] [arguments]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-9v %v\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(out, "\nRun gofiles help command, or gofiles command -h, for the flags of a command.\n")
+}
+
+func lookupCommand(name string) (*command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return nil, false
+}
+
+/*
+flagSet ...
+A flag set for the command that reports errors instead of exiting
+*/
+func (cmd *command) flagSet() *flag.FlagSet {
+	set := flag.NewFlagSet("gofiles "+cmd.name, flag.ContinueOnError)
+	set.SetOutput(Stderr)
+	set.Usage = func() { cmd.usage(set) }
+	return set
+}
+
+func (cmd *command) usage(set *flag.FlagSet) {
+	fmt.Fprintf(set.Output(), "usage: gofiles %v [flags] %v\n\n%v\n", cmd.name, cmd.args, cmd.summary)
+
+	hasFlags := false
+	set.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintf(set.Output(), "\nflags:\n")
+		set.PrintDefaults()
+	}
+}
+
+/*
+parse ...
+Parse the flags of the command and check the number of arguments left, min and max,
+max below 0 has no limit. When parsing fails, or help was asked for, the exit status
+is returned with false.
+*/
+func (cmd *command) parse(set *flag.FlagSet, args []string, min int, max int) (int, bool) {
+	err := set.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK, false
+	}
+	if err != nil {
+		return ExitUsage, false
+	}
+
+	if set.NArg() < min || (max >= 0 && set.NArg() > max) {
+		fmt.Fprintf(Stderr, "usage: gofiles %v [flags] %v\n", cmd.name, cmd.args)
+		return ExitUsage, false
+	}
+
+	return ExitOK, true
+}
+
+/*
+fail ...
+Print an error of the command and return ExitFailure
+*/
+func (cmd *command) fail(format string, args ...interface{}) int {
+	fmt.Fprintf(Stderr, "gofiles %v: %v\n", cmd.name, fmt.Sprintf(format, args...))
+	return ExitFailure
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+	"github.com/ProfElements/go-files/pkg/formats/cftkk/fetm"
+)
+
+/*
+runConvert ...
+gofiles convert [-format name] input output, convert a file to the format of the output
+extension. Images and textures convert to and from png, FETM levels to and from their raw
+json and text forms, any file to a wrapper like Yaz0 .szs, and a wrapped file to the format
+inside of it.
+*/
+func runConvert(cmd *command, args []string) int {
+	set := cmd.flagSet()
+	forced := set.String("format", "", "read the input as this format instead of detecting it")
+	if status, ok := cmd.parse(set, args, 2, 2); !ok {
+		return status
+	}
+	inPath, outPath := set.Arg(0), set.Arg(1)
+	outExt := strings.ToLower(filepath.Ext(outPath))
+
+	var data []byte
+	var err error
+	switch {
+	case isPNG(inPath) && *forced == "":
+		data, err = convertFromPNG(inPath, outExt)
+	case isFETMForm(inPath) && *forced == "":
+		data, err = convertFromFETMForm(inPath, outExt)
+	default:
+		var in *input
+		in, err = open(inPath, *forced)
+		if err == nil {
+			data, err = convertTo(in, outPath)
+		}
+	}
+	if err != nil {
+		return cmd.fail("%v", err)
+	}
+
+	//Images were already written by convertTo, one file each.
+	if data == nil {
+		return ExitOK
+	}
+	err = os.WriteFile(outPath, data, 0644)
+	if err != nil {
+		return cmd.fail("%v", err)
+	}
+
+	return ExitOK
+}
+
+func convertFromPNG(inPath string, outExt string) ([]byte, error) {
+	target, err := formatOfExtension(outExt)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := readPNG(inPath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := target.Encode(img)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %v as %v failed, %v", inPath, target.Name(), err)
+	}
+	return target.Write(file)
+}
+
+func isFETMForm(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".json" || ext == ".txt"
+}
+
+func convertFromFETMForm(inPath string, outExt string) ([]byte, error) {
+	if outExt != ".fetm" {
+		return nil, fmt.Errorf("only fetm levels convert from %v files", filepath.Ext(inPath))
+	}
+
+	data, err := os.ReadFile(inPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var level *fetm.FETM
+	if strings.ToLower(filepath.Ext(inPath)) == ".txt" {
+		level, err = fetm.DecodeFromText(data)
+	} else {
+		level, err = fetm.DecodeFromJSON(data, true)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding %v failed, %v", inPath, err)
+	}
+	return level.Write()
+}
+
+/*
+convertTo ...
+Convert an opened input to the format of the extension of outPath and return its bytes.
+Images are written straight away, one png each, and nil is returned for them.
+*/
+func convertTo(in *input, outPath string) ([]byte, error) {
+	outExt := strings.ToLower(filepath.Ext(outPath))
+
+	switch {
+	case outExt == ".png":
+		work, err := in.decode()
+		if err != nil {
+			return nil, fmt.Errorf("decoding %v failed, %v", in.name, err)
+		}
+		images := imagesOf(work)
+		if len(images) == 0 {
+			return nil, fmt.Errorf("%v is a %v file, it has no images", in.name, in.format.Name())
+		}
+		for idx, imagePath := range imagePaths(strings.TrimSuffix(outPath, filepath.Ext(outPath)), len(images)) {
+			err = writePNG(imagePath, images[idx])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+
+	case in.format.Name() == "fetm" && isFETMForm(outPath):
+		work, err := in.decode()
+		if err != nil {
+			return nil, err
+		}
+		if outExt == ".txt" {
+			return work.(*fetm.FETM).EncodeToText()
+		}
+		return work.(*fetm.FETM).EncodeToJSON(true)
+	}
+
+	target, err := formatOfExtension(outExt)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := target.(formats.Wrapper); ok {
+		file, err := target.Encode(in.raw)
+		if err != nil {
+			return nil, err
+		}
+		return target.Write(file)
+	}
+
+	if target.Name() != in.format.Name() {
+		return nil, fmt.Errorf("%v files can't be converted to %v", in.format.Name(), target.Name())
+	}
+	//The same format, this only unwraps the file or writes it the way Write does.
+	file, err := in.format.Read(in.data)
+	if err != nil {
+		return nil, err
+	}
+	return in.format.Write(file)
+}
+
+func formatOfExtension(ext string) (formats.Format, error) {
+	found := formats.ByExtension(ext)
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no format uses the extension %v", ext)
+	}
+	return found[0], nil
+}
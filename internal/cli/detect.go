@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+runDetect ...
+gofiles detect [-all] file..., print the most likely format of every file, or with -all
+every format it could be. Exits 1 when a file can't be read or isn't in a known format.
+*/
+func runDetect(cmd *command, args []string) int {
+	set := cmd.flagSet()
+	all := set.Bool("all", false, "print every format a file could be, most likely first")
+	if status, ok := cmd.parse(set, args, 1, -1); !ok {
+		return status
+	}
+
+	status := ExitOK
+	for _, path := range set.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			status = cmd.fail("%v", err)
+			continue
+		}
+
+		detections := formats.DetectAll(data)
+		if len(detections) == 0 {
+			fmt.Fprintf(Stdout, "%v: unknown\n", path)
+			status = ExitFailure
+			continue
+		}
+
+		if !*all {
+			fmt.Fprintf(Stdout, "%v: %v\n", path, detections[0])
+			continue
+		}
+		fmt.Fprintf(Stdout, "%v:\n", path)
+		for _, detection := range detections {
+			fmt.Fprintf(Stdout, "  %v\n", detection)
+		}
+	}
+
+	return status
+}
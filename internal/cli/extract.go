@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+runExtract ...
+gofiles extract [-o directory] [-png=false] [-format name] file, unwrap the file and write
+the members of the container inside of it to the directory, which defaults to the name of
+the file without its extension. Every member that holds images gets a png of each next to
+it. A file that is an image on its own just has its images written.
+
+With -recursive every member is detected, unwrapped and unpacked in turn, so containers in
+the file become directories, and a manifest.json records how every file was produced.
+*/
+func runExtract(cmd *command, args []string) int {
+	set := cmd.flagSet()
+	out := set.String("o", "", "the directory to extract to, the file name without its extension by default")
+	writeImages := set.Bool("png", true, "write every image as png next to the member it is in")
+	forced := set.String("format", "", "read the file as this format instead of detecting it")
+	recursive := set.Bool("recursive", false, "unwrap and unpack every member too and write a manifest.json")
+	if status, ok := cmd.parse(set, args, 1, 1); !ok {
+		return status
+	}
+
+	path := set.Arg(0)
+	in, err := open(path, *forced)
+	if err != nil {
+		return cmd.fail("%v", err)
+	}
+
+	dir := *out
+	if dir == "" {
+		dir = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return cmd.fail("%v", err)
+	}
+
+	if *recursive {
+		return extractRecursive(cmd, path, in.raw, *forced, dir, *writeImages)
+	}
+
+	container, ok := in.format.(formats.Container)
+	if !ok {
+		work, err := in.decode()
+		if err != nil {
+			return cmd.fail("decoding %v failed, %v", path, err)
+		}
+		images := imagesOf(work)
+		if len(images) == 0 {
+			return cmd.fail("%v is a %v file, it is neither a container nor an image", path, in.format.Name())
+		}
+		for idx, imagePath := range imagePaths(filepath.Join(dir, filepath.Base(path)), len(images)) {
+			err = writePNG(imagePath, images[idx])
+			if err != nil {
+				return cmd.fail("%v", err)
+			}
+			fmt.Fprintf(Stdout, "%v\n", imagePath)
+		}
+		return ExitOK
+	}
+
+	members, err := container.Unpack(in.data)
+	if err != nil {
+		return cmd.fail("unpacking %v failed, %v", path, err)
+	}
+
+	status := ExitOK
+	for _, member := range members {
+		memberPath, err := memberPath(dir, member.Name)
+		if err != nil {
+			status = cmd.fail("%v", err)
+			continue
+		}
+		err = os.WriteFile(memberPath, member.Data, 0644)
+		if err != nil {
+			status = cmd.fail("%v", err)
+			continue
+		}
+		fmt.Fprintf(Stdout, "%v 0x%X\n", memberPath, len(member.Data))
+
+		if !*writeImages {
+			continue
+		}
+		//Members that aren't a known image format are only written as they are.
+		memberIn, err := identify(member.Name, member.Data, "")
+		if err != nil {
+			continue
+		}
+		work, err := memberIn.decode()
+		if err != nil {
+			continue
+		}
+		images := imagesOf(work)
+		for idx, imagePath := range imagePaths(memberPath, len(images)) {
+			err = writePNG(imagePath, images[idx])
+			if err != nil {
+				status = cmd.fail("%v", err)
+				continue
+			}
+			fmt.Fprintf(Stdout, "%v\n", imagePath)
+		}
+	}
+
+	return status
+}
+
+const maxNesting = 16 //How many containers deep extract -recursive goes.
+
+/*
+extractRecursive ...
+Extract raw, read from path, into dir as a tree of every container in it and write the manifest
+*/
+func extractRecursive(cmd *command, path string, raw []byte, forced string, dir string, images bool) int {
+	ex := &extractor{cmd: cmd, dir: dir, images: images, status: ExitOK}
+	root := ex.extract(filepath.Base(path), raw, forced, dir, filepath.Join(dir, filepath.Base(path)), 0)
+
+	source := path
+	if absSource, err := filepath.Abs(path); err == nil {
+		if absDir, err := filepath.Abs(dir); err == nil {
+			if rel, err := filepath.Rel(absDir, absSource); err == nil {
+				source = rel
+			}
+		}
+	}
+
+	err := writeManifest(dir, &Manifest{Source: filepath.ToSlash(source), SHA256: hashOf(raw), Root: root})
+	if err != nil {
+		return cmd.fail("%v", err)
+	}
+
+	return ex.status
+}
+
+type extractor struct {
+	cmd    *command
+	dir    string
+	images bool
+	status int
+}
+
+/*
+extract ...
+Extract one file of the tree and return its manifest entry. A container becomes the directory
+dir, every other file is written to file unwrapped, with its images next to it.
+*/
+func (ex *extractor) extract(name string, raw []byte, forced string, dir string, file string, depth int) *Entry {
+	entry := &Entry{Name: name, Size: len(raw)}
+
+	data := raw
+	var format formats.Format
+	in, err := identify(name, raw, forced)
+	if err == nil {
+		data = in.data
+		format = in.format
+		for detection := in.detection; detection.Inner != nil; detection = *detection.Inner {
+			entry.Wrappers = append(entry.Wrappers, detection.Format.Name())
+		}
+
+		//A wrapper around data of no known format is still unwrapped.
+		if wrapper, ok := format.(formats.Wrapper); ok {
+			if inner, err := wrapper.Unwrap(data); err == nil {
+				entry.Wrappers = append(entry.Wrappers, format.Name())
+				data = inner
+				format = nil
+			}
+		}
+	}
+	if format != nil {
+		entry.Format = format.Name()
+	}
+
+	if container, ok := format.(formats.Container); ok && depth < maxNesting {
+		members, err := container.Unpack(data)
+		if err == nil {
+			err = os.MkdirAll(dir, 0755)
+		}
+		if err == nil {
+			entry.Path = ex.relative(dir)
+			for _, member := range members {
+				path, err := memberPath(dir, member.Name)
+				if err != nil {
+					//The member can't be written, it is kept in the manifest so repacking knows of it.
+					ex.status = ex.cmd.fail("%v", err)
+					entry.Members = append(entry.Members, &Entry{Name: member.Name, Size: len(member.Data), Error: err.Error()})
+					continue
+				}
+				entry.Members = append(entry.Members, ex.extract(member.Name, member.Data, "", path, path, depth+1))
+			}
+			return entry
+		}
+		entry.Error = fmt.Sprintf("unpacking failed, %v", err)
+	}
+
+	err = os.WriteFile(file, data, 0644)
+	if err != nil {
+		ex.status = ex.cmd.fail("%v", err)
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Path = ex.relative(file)
+	entry.SHA256 = hashOf(data)
+	fmt.Fprintf(Stdout, "%v 0x%X\n", file, len(data))
+
+	if !ex.images || format == nil {
+		return entry
+	}
+	work, err := in.decode()
+	if err != nil {
+		//Unsupported isn't corrupt, only what really failed is recorded as an error.
+		if !formats.Unsupported(err) && entry.Error == "" {
+			entry.Error = fmt.Sprintf("decoding failed, %v", err)
+		}
+		return entry
+	}
+	images := imagesOf(work)
+	for idx, imagePath := range imagePaths(file, len(images)) {
+		png, err := encodePNG(images[idx])
+		if err == nil {
+			err = os.WriteFile(imagePath, png, 0644)
+		}
+		if err != nil {
+			ex.status = ex.cmd.fail("%v", err)
+			continue
+		}
+		entry.Images = append(entry.Images, Image{Path: ex.relative(imagePath), SHA256: hashOf(png)})
+		fmt.Fprintf(Stdout, "%v\n", imagePath)
+	}
+
+	return entry
+}
+
+func (ex *extractor) relative(path string) string {
+	rel, err := filepath.Rel(ex.dir, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
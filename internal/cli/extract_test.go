@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+)
+
+/*
+run ...
+Run a gofiles command line and return its exit status and everything it printed
+*/
+func run(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+
+	out := &bytes.Buffer{}
+	Stdout, Stderr = out, out
+	defer func() { Stdout, Stderr = os.Stdout, os.Stderr }()
+
+	status := Run(args)
+	return status, out.String()
+}
+
+/*
+tree ...
+The files of a Yaz0 compressed JAM: a texture, a KRT texture, a JAM with a texture of its own
+and a text file
+*/
+type tree struct {
+	level  testgen.Texture
+	hud    testgen.Texture
+	icon   testgen.Texture
+	inner  testgen.Archive
+	outer  testgen.Archive
+	source string
+}
+
+func writeTree(t *testing.T) *tree {
+	t.Helper()
+
+	files := &tree{
+		level: testgen.TPL(testgen.TPLImage{Format: testgen.RGB5A3, Width: 8, Height: 8}),
+		hud:   testgen.KRT(testgen.KRTRGBA8, 8, 8),
+		icon:  testgen.TPL(testgen.TPLImage{Format: testgen.I8, Width: 8, Height: 4}),
+	}
+	files.inner = testgen.JAM("JAM2", testgen.Member{Name: "ICON", Ext: "TPL", Data: files.icon.Data})
+	files.outer = testgen.JAM("JAM2",
+		testgen.Member{Name: "LEVEL", Ext: "TPL", Data: files.level.Data},
+		testgen.Member{Name: "HUD", Ext: "KRT", Data: files.hud.Data},
+		testgen.Member{Name: "SUB", Ext: "JAM", Data: files.inner.Data},
+		testgen.Member{Name: "README", Ext: "TXT", Data: []byte("hello")},
+	)
+
+	files.source = filepath.Join(t.TempDir(), "level.szs")
+	err := os.WriteFile(files.source, testgen.Yaz0(files.outer.Data), 0644)
+	if err != nil {
+		t.Fatalf("writing the archive: %v", err)
+	}
+	return files
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	return data
+}
+
+func TestExtractRecursive(t *testing.T) {
+	files := writeTree(t)
+	dir := filepath.Join(filepath.Dir(files.source), "level")
+
+	status, out := run(t, "extract", "-recursive", "-o", dir, files.source)
+	if status != ExitOK {
+		t.Fatalf("extract exited with %v:\n%v", status, out)
+	}
+
+	//Members that know their size are written without the padding after them, the others with it.
+	want := map[string][]byte{
+		"LEVEL.TPL":        files.level.Data,
+		"HUD.KRT":          files.outer.Members[1].Data,
+		"SUB.JAM/ICON.TPL": files.icon.Data,
+		"README.TXT":       files.outer.Members[3].Data,
+	}
+	for path, data := range want {
+		if got := readFile(t, filepath.Join(dir, filepath.FromSlash(path))); !bytes.Equal(got, data) {
+			t.Fatalf("%v holds 0x%X bytes, it has to hold 0x%X", path, len(got), len(data))
+		}
+	}
+	for _, path := range []string{"LEVEL.TPL.png", "HUD.KRT.png", "SUB.JAM/ICON.TPL.png"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(path))); err != nil {
+			t.Fatalf("the png next to the texture isn't there, %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "README.TXT.png")); err == nil {
+		t.Fatalf("a png was written for the text file")
+	}
+
+	manifest, err := readManifest(filepath.Join(dir, manifestName))
+	if err != nil {
+		t.Fatalf("reading the manifest: %v", err)
+	}
+	if manifest.Source != "../level.szs" {
+		t.Fatalf("the source is %v", manifest.Source)
+	}
+	if manifest.SHA256 != hashOf(readFile(t, files.source)) {
+		t.Fatalf("the source hash is %v", manifest.SHA256)
+	}
+
+	root := manifest.Root
+	if root.Name != "level.szs" || root.Path != "." || root.Format != "jam" || !reflect.DeepEqual(root.Wrappers, []string{"yaz0"}) || len(root.Members) != 4 {
+		t.Fatalf("the root is %+v", root)
+	}
+
+	type described struct {
+		path    string
+		format  string
+		images  []string
+		members int
+	}
+	var got []described
+	var check func(entry *Entry)
+	check = func(entry *Entry) {
+		for _, member := range entry.Members {
+			images := []string{}
+			for _, img := range member.Images {
+				images = append(images, img.Path)
+				if img.SHA256 != hashOf(readFile(t, filepath.Join(dir, filepath.FromSlash(img.Path)))) {
+					t.Fatalf("the hash of %v is wrong", img.Path)
+				}
+			}
+			if member.SHA256 != "" && member.SHA256 != hashOf(readFile(t, filepath.Join(dir, filepath.FromSlash(member.Path)))) {
+				t.Fatalf("the hash of %v is wrong", member.Path)
+			}
+			if member.Error != "" || len(member.Wrappers) != 0 {
+				t.Fatalf("%v has the error %q and wrappers %v", member.Path, member.Error, member.Wrappers)
+			}
+			got = append(got, described{member.Path, member.Format, images, len(member.Members)})
+			check(member)
+		}
+	}
+	check(root)
+
+	wantEntries := []described{
+		{"LEVEL.TPL", "tpl", []string{"LEVEL.TPL.png"}, 0},
+		{"HUD.KRT", "krt", []string{"HUD.KRT.png"}, 0},
+		{"SUB.JAM", "jam", []string{}, 1},
+		{"SUB.JAM/ICON.TPL", "tpl", []string{"SUB.JAM/ICON.TPL.png"}, 0},
+		{"README.TXT", "", []string{}, 0},
+	}
+	if !reflect.DeepEqual(got, wantEntries) {
+		t.Fatalf("the manifest entries are\n%+v\nnot\n%+v", got, wantEntries)
+	}
+	if root.Members[2].SHA256 != "" || root.Members[0].SHA256 == "" {
+		t.Fatalf("only files have a hash, containers are directories")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	files := writeTree(t)
+	dir := filepath.Join(filepath.Dir(files.source), "level")
+
+	status, out := run(t, "extract", "-o", dir, files.source)
+	if status != ExitOK {
+		t.Fatalf("extract exited with %v:\n%v", status, out)
+	}
+
+	//Without -recursive the inner archive is a member like any other.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("reading the directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := []string{"HUD.KRT", "HUD.KRT.png", "LEVEL.TPL", "LEVEL.TPL.png", "README.TXT", "SUB.JAM"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("extracted %v, not %v", names, want)
+	}
+	if got := readFile(t, filepath.Join(dir, "SUB.JAM")); !bytes.HasPrefix(got, files.inner.Data) {
+		t.Fatalf("the inner archive was written as %v bytes", len(got))
+	}
+	if !strings.Contains(out, "LEVEL.TPL.png") {
+		t.Fatalf("the png isn't printed:\n%v", out)
+	}
+}
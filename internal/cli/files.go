@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+	"github.com/ProfElements/go-files/pkg/formats/nintendo/tpl"
+)
+
+/*
+input ...
+A file a command works on: its bytes and what they were detected as, and the bytes and
+format that are left once every wrapper around them, like Yaz0, is unwrapped
+*/
+type input struct {
+	name      string
+	raw       []byte
+	detection formats.Detection
+	data      []byte
+	format    formats.Format
+}
+
+func open(path string, forced string) (*input, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return identify(path, raw, forced)
+}
+
+/*
+identify ...
+Find the format of raw. A forced format name wins, then detection, and when nothing is
+detected the extension of name decides, so a file too broken to detect still gets a format.
+*/
+func identify(name string, raw []byte, forced string) (*input, error) {
+	in := &input{name: name, raw: raw}
+
+	var format formats.Format
+	if forced != "" {
+		var ok bool
+		format, ok = formats.Lookup(forced)
+		if !ok {
+			return nil, fmt.Errorf("there is no format %v", forced)
+		}
+	} else if detection, ok := formats.Detect(raw); ok {
+		in.detection = detection
+	} else if byExt := formats.ByExtension(filepath.Ext(name)); filepath.Ext(name) != "" && len(byExt) > 0 {
+		format = byExt[0]
+	} else {
+		return nil, fmt.Errorf("%v is not in a known format", name)
+	}
+
+	if format != nil {
+		in.detection = formats.Detection{Format: format, Confidence: 1}
+		if wrapper, ok := format.(formats.Wrapper); ok {
+			if inner, err := wrapper.Unwrap(raw); err == nil {
+				if detection, ok := formats.Detect(inner); ok {
+					in.detection.Inner = &detection
+				}
+			}
+		}
+	}
+
+	in.data = raw
+	detection := in.detection
+	for detection.Inner != nil {
+		data, err := detection.Format.(formats.Wrapper).Unwrap(in.data)
+		if err != nil {
+			return nil, fmt.Errorf("unwrapping the %v of %v failed, %v", detection.Format.Name(), name, err)
+		}
+		in.data = data
+		detection = *detection.Inner
+	}
+	in.format = detection.Format
+
+	return in, nil
+}
+
+/*
+decode ...
+Read and decode the unwrapped data of the input to the work of its format
+*/
+func (in *input) decode() (interface{}, error) {
+	file, err := in.format.Read(in.data)
+	if err != nil {
+		return nil, err
+	}
+	return in.format.Decode(file)
+}
+
+/*
+imagesOf ...
+Return the images in a work, none when the work isn't an image or texture
+*/
+func imagesOf(work interface{}) []image.Image {
+	switch work := work.(type) {
+	case image.Image:
+		return []image.Image{work}
+	case *tpl.Work:
+		images := make([]image.Image, len(work.Images))
+		for idx, img := range work.Images {
+			images[idx] = img
+		}
+		return images
+	}
+	return nil
+}
+
+/*
+imagePaths ...
+The png paths for images decoded from the file at path, LEVEL.TPL gets LEVEL.TPL.png and
+further images of the same file LEVEL.TPL.1.png, LEVEL.TPL.2.png and on. Keeping the
+extension keeps the images of LEVEL.TPL and LEVEL.KRT apart.
+*/
+func imagePaths(path string, count int) []string {
+	paths := make([]string, count)
+	for idx := range paths {
+		if idx == 0 {
+			paths[idx] = path + ".png"
+		} else {
+			paths[idx] = fmt.Sprintf("%v.%v.png", path, idx)
+		}
+	}
+	return paths
+}
+
+/*
+imageOwner ...
+The path of the file a png of imagePaths was decoded from, LEVEL.TPL.1.png belongs to LEVEL.TPL
+*/
+func imageOwner(path string) string {
+	owner := strings.TrimSuffix(path, filepath.Ext(path))
+	if index := filepath.Ext(owner); len(index) > 1 && strings.Trim(index[1:], "0123456789") == "" {
+		owner = strings.TrimSuffix(owner, index)
+	}
+	return owner
+}
+
+func isPNG(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".png")
+}
+
+func writePNG(path string, img image.Image) error {
+	data, err := encodePNG(img)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	err := png.Encode(buffer, img)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func readPNG(path string) (image.Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return png.Decode(bytes.NewReader(data))
+}
+
+/*
+memberPath ...
+Join a member name to dir, or error when the name would end up outside of dir
+*/
+func memberPath(dir string, name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("member name %q can't be used as a file name", name)
+	}
+	return filepath.Join(dir, name), nil
+}
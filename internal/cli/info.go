@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+	"github.com/ProfElements/go-files/pkg/formats/cftkk/fetm"
+)
+
+/*
+fileInfo ...
+What info -json prints for a file, the inspections go from the outermost wrapper inwards
+*/
+type fileInfo struct {
+	Path        string                `json:"path"`
+	Format      string                `json:"format"`
+	Size        int                   `json:"size"`
+	Inspections []*formats.Inspection `json:"inspections,omitempty"`
+}
+
+/*
+runInfo ...
+gofiles info [-format name] [-json] file..., print what every file is: its format, its size,
+the structure of every format that can be inspected, its header fields, tables and offsets,
+and what is inside of it, the members of a container or the sizes of the images of a texture.
+With -json the structure of every file is printed as JSON instead.
+*/
+func runInfo(cmd *command, args []string) int {
+	set := cmd.flagSet()
+	forced := set.String("format", "", "read the files as this format instead of detecting it")
+	asJSON := set.Bool("json", false, "print the structure of the files as JSON")
+	if status, ok := cmd.parse(set, args, 1, -1); !ok {
+		return status
+	}
+
+	status := ExitOK
+	infos := []fileInfo{}
+	for _, path := range set.Args() {
+		in, err := open(path, *forced)
+		if err != nil {
+			status = cmd.fail("%v", err)
+			continue
+		}
+
+		inspections, err := inspect(in)
+		if err != nil {
+			status = cmd.fail("inspecting %v failed, %v", path, err)
+			continue
+		}
+		if *asJSON {
+			infos = append(infos, fileInfo{Path: path, Format: in.detection.Format.Name(), Size: len(in.raw), Inspections: inspections})
+			continue
+		}
+
+		fmt.Fprintf(Stdout, "%v: %v\n", path, in.detection)
+		fmt.Fprintf(Stdout, "  size: 0x%X\n", len(in.raw))
+		if len(in.data) != len(in.raw) {
+			fmt.Fprintf(Stdout, "  unwrapped size: 0x%X\n", len(in.data))
+		}
+		for _, inspection := range inspections {
+			fmt.Fprintf(Stdout, "  %v\n", inspection.Format)
+			inspection.WriteText(Stdout, "    ")
+		}
+
+		if container, ok := in.format.(formats.Container); ok {
+			members, err := container.Unpack(in.data)
+			if err != nil {
+				status = cmd.fail("unpacking %v failed, %v", path, err)
+				continue
+			}
+			fmt.Fprintf(Stdout, "  members: %v\n", len(members))
+			for _, member := range members {
+				fmt.Fprintf(Stdout, "    %-14v 0x%X\n", member.Name, len(member.Data))
+			}
+			continue
+		}
+
+		work, err := in.decode()
+		if err != nil {
+			//A file that is only unsupported isn't a failure, what can't be decoded is said.
+			switch {
+			case errors.Is(err, formats.ErrNotSupported):
+			case formats.Unsupported(err):
+				fmt.Fprintf(Stdout, "  not decoded: %v\n", err)
+			default:
+				status = cmd.fail("decoding %v failed, %v", path, err)
+			}
+			continue
+		}
+		for idx, img := range imagesOf(work) {
+			fmt.Fprintf(Stdout, "  image %v: %vx%v\n", idx, img.Bounds().Dx(), img.Bounds().Dy())
+		}
+		if level, ok := work.(*fetm.FETM); ok {
+			fmt.Fprintf(Stdout, "  nodes: %v\n", len(level.Nodes))
+		}
+	}
+
+	if *asJSON {
+		raw, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return cmd.fail("encoding the structure failed, %v", err)
+		}
+		fmt.Fprintf(Stdout, "%s\n", raw)
+	}
+
+	return status
+}
+
+/*
+inspect ...
+Inspect every format of the input that is a formats.Inspector, its wrappers first and then
+the format they wrap
+*/
+func inspect(in *input) ([]*formats.Inspection, error) {
+	var inspections []*formats.Inspection
+
+	data := in.raw
+	detection := in.detection
+	for {
+		if inspector, ok := detection.Format.(formats.Inspector); ok {
+			file, err := detection.Format.Read(data)
+			if err != nil {
+				return nil, err
+			}
+			inspection, err := inspector.Inspect(file)
+			if err != nil {
+				return nil, err
+			}
+			inspections = append(inspections, inspection)
+		}
+
+		if detection.Inner == nil {
+			return inspections, nil
+		}
+		var err error
+		data, err = detection.Format.(formats.Wrapper).Unwrap(data)
+		if err != nil {
+			return nil, err
+		}
+		detection = *detection.Inner
+	}
+}
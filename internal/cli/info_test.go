@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+)
+
+func TestInfoHighVoltage(t *testing.T) {
+	dir := t.TempDir()
+	model := testgen.GMDOf(testgen.GMDModel{Materials: []testgen.GMDMaterial{{Name: "skin", Texture: "BODY"}}})
+	files := map[string][]byte{
+		"m.gmd": model,
+		"a.gka": testgen.GKAOf(testgen.GKAAnimation{Name: "wave"}),
+		"s.gsl": testgen.HV([]uint32{1, 2, 3, 4, 5}, []byte("gsl data")),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("writing %v: %v", name, err)
+		}
+
+		status, out := run(t, "info", path)
+		format := strings.TrimPrefix(filepath.Ext(name), ".")
+		if status != ExitOK || !strings.Contains(out, ": "+format+" (1.00)") || !strings.Contains(out, "file size") {
+			t.Fatalf("info on %v exited with %v:\n%v", name, status, out)
+		}
+	}
+
+	//The model says its material table runs past the file, that may be the provisional layout being wrong.
+	model[0x10] = 0xFF
+	path := filepath.Join(dir, "broken.gmd")
+	if err := os.WriteFile(path, model, 0644); err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	status, out := run(t, "validate", path)
+	if status != ExitOK || !strings.Contains(out, "not checked, the gmd layout is provisional") {
+		t.Fatalf("validate exited with %v:\n%v", status, out)
+	}
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const manifestName = "manifest.json"
+
+/*
+Manifest ...
+What extract -recursive wrote and how each file was produced, saved as manifest.json in
+the directory it extracted to. Paths are relative to that directory and use forward
+slashes, Source is relative to it too when it can be.
+*/
+type Manifest struct {
+	Source string `json:"source"`
+	SHA256 string `json:"sha256"`
+	Root   *Entry `json:"root"`
+}
+
+/*
+Entry ...
+One file of the extracted tree. Name is the member name in the parent container, Format the
+format of the data once the Wrappers around it, outermost first, are unwrapped. A container
+is a directory of its Members, every other file is written unwrapped at Path with SHA256 as
+its hash and Images as the pngs decoded from it. Error says why a file was written as it is
+instead of being unpacked or decoded.
+*/
+type Entry struct {
+	Name     string   `json:"name,omitempty"`
+	Path     string   `json:"path"`
+	Format   string   `json:"format,omitempty"`
+	Wrappers []string `json:"wrappers,omitempty"`
+	Size     int      `json:"size"`
+	SHA256   string   `json:"sha256,omitempty"`
+	Images   []Image  `json:"images,omitempty"`
+	Members  []*Entry `json:"members,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+/*
+Image ...
+A png decoded from an entry, with the hash it had when it was written
+*/
+type Image struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+func hashOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeManifest(dir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, manifestName), append(data, '\n'), 0644)
+}
+
+func readManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%v is not a manifest, %v", path, err)
+	}
+	if manifest.Root == nil {
+		return nil, fmt.Errorf("%v is not a manifest, it has no root", path)
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+runPack ...
+gofiles pack [-format name] [-o file] directory, build a container of every file in the
+directory, in file name order. A png named after another file, like LEVEL.TPL.png, is
+taken to be an image extract wrote and is left out. The format defaults to the one of
+the -o extension, or jam, and the output to the directory name with the format extension.
+Given a manifest.json instead of a directory it repacks what extract -recursive wrote.
+*/
+func runPack(cmd *command, args []string) int {
+	set := cmd.flagSet()
+	out := set.String("o", "", "the file to write, the directory name with the format extension by default")
+	name := set.String("format", "", "the container format to build, from the -o extension or jam by default")
+	if status, ok := cmd.parse(set, args, 1, 1); !ok {
+		return status
+	}
+	dir := set.Arg(0)
+
+	if info, err := os.Stat(dir); err == nil && info.Mode().IsRegular() {
+		return repack(cmd, dir, *out)
+	}
+
+	var format formats.Format
+	switch {
+	case *name != "":
+		var ok bool
+		format, ok = formats.Lookup(*name)
+		if !ok {
+			fmt.Fprintf(Stderr, "gofiles pack: there is no format %v\n", *name)
+			return ExitUsage
+		}
+	case *out != "" && len(formats.ByExtension(filepath.Ext(*out))) > 0:
+		format = formats.ByExtension(filepath.Ext(*out))[0]
+	default:
+		format, _ = formats.Lookup("jam")
+	}
+
+	packer, ok := format.(formats.Packer)
+	if !ok {
+		fmt.Fprintf(Stderr, "gofiles pack: %v files can't be packed\n", format.Name())
+		return ExitUsage
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return cmd.fail("%v", err)
+	}
+
+	names := make(map[string]bool)
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+
+	var members []formats.Member
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if isPNG(entry.Name()) && names[imageOwner(entry.Name())] {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return cmd.fail("%v", err)
+		}
+		members = append(members, formats.Member{Name: entry.Name(), Data: data})
+	}
+
+	data, err := packer.Pack(members)
+	if err != nil {
+		return cmd.fail("packing %v failed, %v", dir, err)
+	}
+
+	path := *out
+	if path == "" {
+		path = filepath.Clean(dir) + format.Extensions()[0]
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return cmd.fail("%v", err)
+	}
+	fmt.Fprintf(Stdout, "%v: %v members, 0x%X\n", path, len(members), len(data))
+
+	return ExitOK
+}
@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+repack ...
+gofiles pack [-o file] manifest.json, rebuild the file extract -recursive extracted from the
+files of its directory. The original file has to still be where the manifest says, unchanged,
+everything that wasn't edited is taken from it so it comes out byte for byte the same. Edited
+files are used as they are and edited pngs are encoded into the texture they came from, in
+its image format, then every container around them is rebuilt with the original member order
+and header and wrapped again. The output defaults to the original file name in the working
+directory.
+*/
+func repack(cmd *command, manifestPath string, out string) int {
+	manifest, err := readManifest(manifestPath)
+	if err != nil {
+		return cmd.fail("%v", err)
+	}
+	dir := filepath.Dir(manifestPath)
+
+	source := filepath.FromSlash(manifest.Source)
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(dir, source)
+	}
+	original, err := os.ReadFile(source)
+	if err != nil {
+		return cmd.fail("the extracted file is needed to repack, %v", err)
+	}
+	if hashOf(original) != manifest.SHA256 {
+		return cmd.fail("%v changed since it was extracted, extract it again", source)
+	}
+
+	rp := &repacker{dir: dir}
+	data, _, err := rp.rebuild(manifest.Root, original)
+	if err != nil {
+		return cmd.fail("%v", err)
+	}
+
+	if out == "" {
+		out = manifest.Root.Name
+	}
+	if sourceInfo, err := os.Stat(source); err == nil {
+		if outInfo, err := os.Stat(out); err == nil && os.SameFile(sourceInfo, outInfo) {
+			return cmd.fail("packing would overwrite the extracted file %v, give another -o", source)
+		}
+	}
+
+	err = os.WriteFile(out, data, 0644)
+	if err != nil {
+		return cmd.fail("%v", err)
+	}
+	fmt.Fprintf(Stdout, "%v: %v files changed, 0x%X\n", out, rp.changed, len(data))
+
+	return ExitOK
+}
+
+type repacker struct {
+	dir     string
+	changed int
+}
+
+/*
+rebuild ...
+Rebuild the file of an entry from its original bytes and what is extracted of it now, and
+report whether it changed. An unchanged file is returned as the original bytes.
+*/
+func (rp *repacker) rebuild(entry *Entry, original []byte) ([]byte, bool, error) {
+	data := original
+	for _, name := range entry.Wrappers {
+		wrapper, err := wrapperNamed(name)
+		if err != nil {
+			return nil, false, err
+		}
+		data, err = wrapper.Unwrap(data)
+		if err != nil {
+			return nil, false, fmt.Errorf("%v: unwrapping %v failed, %v", entry.Name, name, err)
+		}
+	}
+
+	var rebuilt []byte
+	var changed bool
+	var err error
+	switch {
+	case entry.SHA256 != "":
+		rebuilt, changed, err = rp.rebuildFile(entry, data)
+	case entry.Path != "":
+		rebuilt, changed, err = rp.rebuildContainer(entry, data)
+	}
+	if err != nil || !changed {
+		return original, false, err
+	}
+
+	for idx := len(entry.Wrappers) - 1; idx >= 0; idx-- {
+		format, _ := formats.Lookup(entry.Wrappers[idx])
+		file, err := format.Encode(rebuilt)
+		if err != nil {
+			return nil, false, fmt.Errorf("%v: wrapping in %v failed, %v", entry.Path, format.Name(), err)
+		}
+		rebuilt, err = format.Write(file)
+		if err != nil {
+			return nil, false, fmt.Errorf("%v: wrapping in %v failed, %v", entry.Path, format.Name(), err)
+		}
+	}
+
+	return rebuilt, true, nil
+}
+
+func (rp *repacker) rebuildContainer(entry *Entry, data []byte) ([]byte, bool, error) {
+	format, ok := formats.Lookup(entry.Format)
+	if !ok {
+		return nil, false, fmt.Errorf("%v: there is no format %v", entry.Path, entry.Format)
+	}
+	container, ok := format.(formats.Container)
+	if !ok {
+		return nil, false, fmt.Errorf("%v: %v is not a container", entry.Path, entry.Format)
+	}
+
+	members, err := container.Unpack(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("%v: unpacking failed, %v", entry.Path, err)
+	}
+	if len(members) != len(entry.Members) {
+		return nil, false, fmt.Errorf("%v: the container has %v members, the manifest %v", entry.Path, len(members), len(entry.Members))
+	}
+
+	changed := false
+	for idx, member := range members {
+		memberData, memberChanged, err := rp.rebuild(entry.Members[idx], member.Data)
+		if err != nil {
+			return nil, false, err
+		}
+		members[idx].Data = memberData
+		changed = changed || memberChanged
+	}
+	if !changed {
+		return data, false, nil
+	}
+
+	var rebuilt []byte
+	if repacker, ok := format.(formats.Repacker); ok {
+		rebuilt, err = repacker.Repack(data, members)
+	} else if packer, ok := format.(formats.Packer); ok {
+		rebuilt, err = packer.Pack(members)
+	} else {
+		err = fmt.Errorf("%v files can't be packed", format.Name())
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("%v: %v", entry.Path, err)
+	}
+
+	return rebuilt, true, nil
+}
+
+/*
+rebuildFile ...
+A file is changed when its extracted file or one of its pngs doesn't have the hash of the
+manifest anymore. Editing both is an error, the edits of one would be lost.
+*/
+func (rp *repacker) rebuildFile(entry *Entry, data []byte) ([]byte, bool, error) {
+	path := filepath.Join(rp.dir, filepath.FromSlash(entry.Path))
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	fileChanged := hashOf(current) != entry.SHA256
+
+	var editedImages []int
+	for idx, img := range entry.Images {
+		png, err := os.ReadFile(filepath.Join(rp.dir, filepath.FromSlash(img.Path)))
+		if err != nil {
+			return nil, false, err
+		}
+		if hashOf(png) != img.SHA256 {
+			editedImages = append(editedImages, idx)
+		}
+	}
+
+	switch {
+	case !fileChanged && len(editedImages) == 0:
+		return data, false, nil
+	case fileChanged && len(editedImages) > 0:
+		return nil, false, fmt.Errorf("%v and its png were both edited, only one of them can be", entry.Path)
+	case fileChanged:
+		rp.changed++
+		fmt.Fprintf(Stdout, "%v: edited\n", entry.Path)
+		return current, true, nil
+	}
+
+	format, _ := formats.Lookup(entry.Format)
+	replacer, ok := format.(formats.ImageReplacer)
+	if !ok {
+		return nil, false, fmt.Errorf("%v: %v files can't be encoded from png", entry.Path, entry.Format)
+	}
+	for _, idx := range editedImages {
+		img, err := readPNG(filepath.Join(rp.dir, filepath.FromSlash(entry.Images[idx].Path)))
+		if err != nil {
+			return nil, false, err
+		}
+		current, err = replacer.ReplaceImage(current, idx, img)
+		if err != nil {
+			return nil, false, fmt.Errorf("%v: %v", entry.Images[idx].Path, err)
+		}
+		fmt.Fprintf(Stdout, "%v: encoded from %v\n", entry.Path, entry.Images[idx].Path)
+	}
+	rp.changed++
+
+	return current, true, nil
+}
+
+func wrapperNamed(name string) (formats.Wrapper, error) {
+	format, ok := formats.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("there is no format %v", name)
+	}
+	wrapper, ok := format.(formats.Wrapper)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a wrapper format", name)
+	}
+	return wrapper, nil
+}
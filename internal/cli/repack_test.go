@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProfElements/go-files/pkg/formats/nintendo/tpl"
+	"github.com/ProfElements/go-files/pkg/formats/nintendo/yaz0"
+)
+
+/*
+extractTree ...
+Write the tree, extract it with -recursive next to it and return it and the manifest path
+*/
+func extractTree(t *testing.T) (*tree, string) {
+	t.Helper()
+
+	files := writeTree(t)
+	dir := filepath.Join(filepath.Dir(files.source), "level")
+	status, out := run(t, "extract", "-recursive", "-o", dir, files.source)
+	if status != ExitOK {
+		t.Fatalf("extract exited with %v:\n%v", status, out)
+	}
+	return files, filepath.Join(dir, manifestName)
+}
+
+func TestPackUnchanged(t *testing.T) {
+	files, manifest := extractTree(t)
+	packed := filepath.Join(filepath.Dir(files.source), "packed.szs")
+
+	status, out := run(t, "pack", "-o", packed, manifest)
+	if status != ExitOK {
+		t.Fatalf("pack exited with %v:\n%v", status, out)
+	}
+	if got := readFile(t, packed); !bytes.Equal(got, readFile(t, files.source)) {
+		t.Fatalf("packing the unchanged tree gave 0x%X bytes that differ from the 0x%X extracted", len(got), len(readFile(t, files.source)))
+	}
+}
+
+func TestPackEditedImage(t *testing.T) {
+	files, manifest := extractTree(t)
+	dir := filepath.Dir(manifest)
+
+	//Paint the icon of the inner archive grey, I8 holds that grey exactly.
+	edited := image.NewNRGBA(files.icon.Images[0].Rect)
+	for idx := range edited.Pix {
+		edited.Pix[idx] = 0x40
+		if idx%4 == 3 {
+			edited.Pix[idx] = 0xFF
+		}
+	}
+	edited.SetNRGBA(0, 0, color.NRGBA{0xC0, 0xC0, 0xC0, 0xFF})
+	pngFile, err := os.Create(filepath.Join(dir, "SUB.JAM", "ICON.TPL.png"))
+	if err != nil {
+		t.Fatalf("creating the png: %v", err)
+	}
+	err = png.Encode(pngFile, edited)
+	pngFile.Close()
+	if err != nil {
+		t.Fatalf("writing the png: %v", err)
+	}
+
+	packed := filepath.Join(filepath.Dir(files.source), "packed.szs")
+	status, out := run(t, "pack", "-o", packed, manifest)
+	if status != ExitOK {
+		t.Fatalf("pack exited with %v:\n%v", status, out)
+	}
+
+	compressed, err := yaz0.Read(readFile(t, packed))
+	if err != nil {
+		t.Fatalf("reading the packed file: %v", err)
+	}
+	got, err := yaz0.Decode(compressed)
+	if err != nil {
+		t.Fatalf("decompressing the packed file: %v", err)
+	}
+	want := files.outer.Data
+	if len(got) != len(want) {
+		t.Fatalf("the packed archive is 0x%X bytes, not 0x%X", len(got), len(want))
+	}
+
+	//Only the image data of the icon can differ, the outer and inner archive stay as they were.
+	icon, err := tpl.Read(files.icon.Data)
+	if err != nil {
+		t.Fatalf("reading the icon: %v", err)
+	}
+	iconStart := bytes.Index(want, files.icon.Data)
+	start := iconStart + int(icon.ImgTable[0].ImgHeader.ImgDataADR)
+	end := start + len(icon.ImgTable[0].ImgData)
+	if !bytes.Equal(got[:start], want[:start]) || !bytes.Equal(got[end:], want[end:]) {
+		t.Fatalf("bytes outside the image data of the icon at 0x%X-0x%X changed", start, end)
+	}
+	if bytes.Equal(got[start:end], want[start:end]) {
+		t.Fatalf("the image data of the icon didn't change")
+	}
+
+	packedIcon, err := tpl.Read(got[iconStart : iconStart+len(files.icon.Data)])
+	if err != nil {
+		t.Fatalf("reading the packed icon: %v", err)
+	}
+	if packedIcon.ImgTable[0].ImgHeader.Format != icon.ImgTable[0].ImgHeader.Format {
+		t.Fatalf("the icon is packed in format 0x%X", packedIcon.ImgTable[0].ImgHeader.Format)
+	}
+	work, err := tpl.Decode(packedIcon)
+	if err != nil {
+		t.Fatalf("decoding the packed icon: %v", err)
+	}
+	for y := 0; y < edited.Rect.Dy(); y++ {
+		for x := 0; x < edited.Rect.Dx(); x++ {
+			if work.Images[0].NRGBAAt(x, y) != edited.NRGBAAt(x, y) {
+				t.Fatalf("pixel %v,%v of the packed icon is %v, not %v", x, y, work.Images[0].NRGBAAt(x, y), edited.NRGBAAt(x, y))
+			}
+		}
+	}
+}
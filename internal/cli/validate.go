@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+	"github.com/ProfElements/go-files/pkg/formats/cftkk/fetm"
+)
+
+/*
+runValidate ...
+gofiles validate [-format name] file..., print every problem of every file as path: problem.
+FETM files get the checks of fetm.Validate, every other file is read and decoded, and the
+members of containers are validated too. Exits 1 when any file has a problem. A part of a file
+the format package doesn't support yet isn't a problem, it is printed as not checked, and
+neither are FETM warnings about the provisional entity class layouts or GMD and GKA files that
+don't follow their provisional layouts.
+*/
+func runValidate(cmd *command, args []string) int {
+	set := cmd.flagSet()
+	forced := set.String("format", "", "validate the files as this format instead of detecting it")
+	if status, ok := cmd.parse(set, args, 1, -1); !ok {
+		return status
+	}
+
+	status := ExitOK
+	for _, path := range set.Args() {
+		in, err := open(path, *forced)
+		if err != nil {
+			status = cmd.fail("%v", err)
+			continue
+		}
+
+		problems, notes := validate(in)
+		for _, problem := range problems {
+			fmt.Fprintf(Stdout, "%v: %v\n", path, problem)
+		}
+		for _, note := range notes {
+			fmt.Fprintf(Stdout, "%v: %v\n", path, note)
+		}
+		if len(problems) > 0 {
+			status = ExitFailure
+		}
+	}
+
+	return status
+}
+
+/*
+validate ...
+Return the problems of a file and notes that don't fail it: what of it couldn't be checked
+because it is unsupported and warnings
+*/
+func validate(in *input) ([]string, []string) {
+	var problems, notes []string
+	//A file that doesn't follow a provisional layout may still be right, the layout may be wrong.
+	provisional := in.format.Name() == "gmd" || in.format.Name() == "gka"
+	report := func(err error, format string, args ...interface{}) {
+		switch {
+		case errors.Is(err, formats.ErrNotSupported):
+		case formats.Unsupported(err):
+			notes = append(notes, "not checked, "+err.Error())
+		case provisional:
+			notes = append(notes, fmt.Sprintf("not checked, the %v layout is provisional, %v", in.format.Name(), err))
+		default:
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	if in.format.Name() == "fetm" {
+		for _, problem := range fetm.Validate(in.data) {
+			if problem.Warning {
+				notes = append(notes, problem.String())
+			} else {
+				problems = append(problems, problem.String())
+			}
+		}
+		return problems, notes
+	}
+
+	file, err := in.format.Read(in.data)
+	if err != nil {
+		report(err, "reading as %v failed, %v", in.format.Name(), err)
+		return problems, notes
+	}
+	_, err = in.format.Decode(file)
+	if err != nil {
+		report(err, "decoding as %v failed, %v", in.format.Name(), err)
+	}
+
+	container, ok := in.format.(formats.Container)
+	if !ok {
+		return problems, notes
+	}
+	members, err := container.Unpack(in.data)
+	if err != nil {
+		report(err, "unpacking failed, %v", err)
+		return problems, notes
+	}
+	for _, member := range members {
+		//Members of an unknown format have nothing to check.
+		memberIn, err := identify(member.Name, member.Data, "")
+		if err != nil {
+			continue
+		}
+		memberProblems, memberNotes := validate(memberIn)
+		for _, problem := range memberProblems {
+			problems = append(problems, member.Name+": "+problem)
+		}
+		for _, note := range memberNotes {
+			notes = append(notes, member.Name+": "+note)
+		}
+	}
+
+	return problems, notes
+}
@@ -0,0 +1,216 @@
+package testgen
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+/*
+HV ...
+Build a High Voltage file of the bmvg formats: the unknown header values, the size of the whole
+file and payload after them
+*/
+func HV(unknowns []uint32, payload []byte) []byte {
+	headerSize := 4*len(unknowns) + 4
+	out := make([]byte, headerSize, headerSize+len(payload))
+	for i, value := range unknowns {
+		binary.BigEndian.PutUint32(out[4*i:], value)
+	}
+	binary.BigEndian.PutUint32(out[headerSize-4:], uint32(headerSize+len(payload)))
+	return append(out, payload...)
+}
+
+/*
+GMD ...
+Build a GMD model around payload: the three unknown header values 1, 2 and 3 and the size of
+the whole file. The payload isn't checked, models with tables are built by GMDOf.
+*/
+func GMD(payload []byte) []byte {
+	return HV([]uint32{1, 2, 3}, payload)
+}
+
+type GMDMaterial struct {
+	Name    string
+	Texture string
+}
+
+type GMDBone struct {
+	Name        string
+	Parent      int32
+	Translation [3]float32
+	Rotation    [4]float32
+	Scale       [3]float32
+}
+
+/*
+GMDMesh ...
+A mesh of a generated model. Normals, UVs and Joints with Weights are left out of the vertices
+when they are empty, the mesh flags say which are there.
+*/
+type GMDMesh struct {
+	Name      string
+	Material  int32
+	Positions [][3]float32
+	Normals   [][3]float32
+	UVs       [][2]float32
+	Joints    [][4]uint8
+	Weights   [][4]float32
+	Indices   []uint16
+}
+
+type GMDModel struct {
+	Materials []GMDMaterial
+	Bones     []GMDBone
+	Meshes    []GMDMesh
+}
+
+/*
+GMDOf ...
+Build a GMD model with header values 1, 2 and 3. The section table is followed by the material,
+bone and mesh tables, then the vertices and indices of every mesh, each aligned to 4 bytes.
+*/
+func GMDOf(model GMDModel) []byte {
+	body := make([]byte, 0x18)
+	materials := 0x10 + len(body)
+	body = append(body, make([]byte, len(model.Materials)*0x40)...)
+	bones := 0x10 + len(body)
+	body = append(body, make([]byte, len(model.Bones)*0x50)...)
+	meshes := 0x10 + len(body)
+	body = append(body, make([]byte, len(model.Meshes)*0x40)...)
+
+	put := func(offset int, values ...uint32) {
+		for i, value := range values {
+			binary.BigEndian.PutUint32(body[offset-0x10+4*i:], value)
+		}
+	}
+	put(0x10, uint32(len(model.Materials)), uint32(materials), uint32(len(model.Bones)), uint32(bones), uint32(len(model.Meshes)), uint32(meshes))
+
+	for i, material := range model.Materials {
+		copy(body[materials-0x10+i*0x40:], material.Name)
+		copy(body[materials-0x10+i*0x40+0x20:], material.Texture)
+	}
+
+	for i, bone := range model.Bones {
+		at := bones + i*0x50
+		copy(body[at-0x10:], bone.Name)
+		put(at+0x20, uint32(bone.Parent))
+		put(at+0x24, floats(bone.Translation[:])...)
+		put(at+0x30, floats(bone.Rotation[:])...)
+		put(at+0x40, floats(bone.Scale[:])...)
+	}
+
+	for i, mesh := range model.Meshes {
+		var flags uint32
+		if len(mesh.Normals) > 0 {
+			flags |= 0x01
+		}
+		if len(mesh.UVs) > 0 {
+			flags |= 0x02
+		}
+		if len(mesh.Joints) > 0 {
+			flags |= 0x04
+		}
+
+		vertices := 0x10 + len(body)
+		for v, position := range mesh.Positions {
+			body = appendFloats(body, position[:]...)
+			if flags&0x01 != 0 {
+				body = appendFloats(body, mesh.Normals[v][:]...)
+			}
+			if flags&0x02 != 0 {
+				body = appendFloats(body, mesh.UVs[v][:]...)
+			}
+			if flags&0x04 != 0 {
+				body = append(body, mesh.Joints[v][:]...)
+				body = appendFloats(body, mesh.Weights[v][:]...)
+			}
+		}
+
+		indices := 0x10 + len(body)
+		for _, index := range mesh.Indices {
+			body = append(body, byte(index>>8), byte(index))
+		}
+		for len(body)%4 != 0 {
+			body = append(body, 0x00)
+		}
+
+		at := meshes + i*0x40
+		copy(body[at-0x10:], mesh.Name)
+		put(at+0x20, uint32(mesh.Material), flags, uint32(len(mesh.Positions)), uint32(vertices), uint32(len(mesh.Indices)), uint32(indices))
+	}
+
+	return GMD(body)
+}
+
+type GKAKey struct {
+	Time       float32
+	Value      [4]float32
+	InTangent  [4]float32
+	OutTangent [4]float32
+}
+
+/*
+GKATrack ...
+A track of a generated animation. Path is 0 for translation, 1 for rotation and 2 for scale,
+Interpolation 0 for linear, 1 for step and 2 for cubic spline, only those keys have tangents.
+*/
+type GKATrack struct {
+	Bone          string
+	Path          uint8
+	Interpolation uint8
+	Keys          []GKAKey
+}
+
+type GKAAnimation struct {
+	Name   string
+	Tracks []GKATrack
+}
+
+/*
+GKAOf ...
+Build a GKA animation with header values 1, 2 and 3. The name and the track table are followed
+by the keys of every track.
+*/
+func GKAOf(animation GKAAnimation) []byte {
+	body := make([]byte, 0x28+len(animation.Tracks)*0x30)
+	copy(body, animation.Name)
+	binary.BigEndian.PutUint32(body[0x20:], uint32(len(animation.Tracks)))
+	binary.BigEndian.PutUint32(body[0x24:], 0x38)
+
+	for i, track := range animation.Tracks {
+		keys := 0x10 + len(body)
+		for _, key := range track.Keys {
+			body = appendFloats(body, key.Time)
+			body = appendFloats(body, key.Value[:]...)
+			if track.Interpolation == 2 {
+				body = appendFloats(body, key.InTangent[:]...)
+				body = appendFloats(body, key.OutTangent[:]...)
+			}
+		}
+
+		at := 0x28 + i*0x30
+		copy(body[at:], track.Bone)
+		body[at+0x20] = track.Path
+		body[at+0x21] = track.Interpolation
+		binary.BigEndian.PutUint32(body[at+0x24:], uint32(len(track.Keys)))
+		binary.BigEndian.PutUint32(body[at+0x28:], uint32(keys))
+	}
+
+	return HV([]uint32{1, 2, 3}, body)
+}
+
+func floats(values []float32) []uint32 {
+	bits := make([]uint32, len(values))
+	for i, value := range values {
+		bits[i] = math.Float32bits(value)
+	}
+	return bits
+}
+
+func appendFloats(out []byte, values ...float32) []byte {
+	for _, value := range values {
+		out = append(out, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(out[len(out)-4:], math.Float32bits(value))
+	}
+	return out
+}
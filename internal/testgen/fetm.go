@@ -0,0 +1,77 @@
+package testgen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+/*
+Level ...
+A generated FETM level, how many values it holds and the entity class of each of its nodes
+*/
+type Level struct {
+	Data    []byte
+	Values  int
+	Classes []string
+}
+
+/*
+FETM ...
+Build a small level with a value of every kind: the header, a world with three parameters, a
+sector with two values in front of its marker, and a Pickup and a Spawner node, each with the
+position and name of its class.
+*/
+func FETM() Level {
+	level := Level{}
+	buffer := &bytes.Buffer{}
+	value := func(ident byte, data ...byte) {
+		buffer.WriteByte(ident)
+		buffer.Write(data)
+		level.Values++
+	}
+	str := func(text string) {
+		value(0x07, append([]byte(text), 0x00)...)
+	}
+	f32 := func(number float32) {
+		data := make([]byte, 4)
+		binary.BigEndian.PutUint32(data, math.Float32bits(number))
+		value(0x06, data...)
+	}
+	node := func(nodeType uint16, factory string, class string) {
+		value(0x03, byte(nodeType>>8), byte(nodeType))
+		str(factory)
+		str(class)
+		level.Classes = append(level.Classes, class)
+	}
+
+	//The header: magic, note, section count and an unknown value.
+	value(0x01, 0x7C)
+	str("note")
+	value(0x01, 0x02)
+	value(0x04, 0x00, 0x00, 0x00, 0x09)
+
+	str("world")
+	f32(1.5)
+	value(0x00, 0xFE)
+	value(0x02, 0xFF, 0xFE)
+
+	value(0x05, 0xDE, 0xAD, 0xBE, 0xEF)
+	value(0x03, 0x12, 0x34)
+	str("World Sector")
+	value(0x01, 0x03)
+
+	node(1, "Factory", "Pickup")
+	f32(2.5)
+	f32(0)
+	f32(-1)
+	str("coin")
+	node(2, "x", "Spawner")
+	f32(0)
+	f32(0)
+	f32(0)
+	str("start")
+
+	level.Data = buffer.Bytes()
+	return level
+}
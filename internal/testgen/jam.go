@@ -0,0 +1,117 @@
+package testgen
+
+import "encoding/binary"
+
+/*
+Member ...
+A member of a generated JAM archive, Name and Ext at most the name and extension size of its
+layout
+*/
+type Member struct {
+	Name string
+	Ext  string
+	Data []byte
+}
+
+/*
+Archive ...
+A generated JAM archive and its members. Members have no stored size, a reader sees a member
+run until the next one starts or the archive ends, so the Data of Members is what a reader
+gets back: the data it was given with the padding to the next member after it.
+*/
+type Archive struct {
+	Data    []byte
+	Members []Member
+}
+
+/*
+JAMLayout ...
+The parts of a generated JAM archive that differ between archives: the magic, the size of a
+name and an extension table entry and the unknown value after the magic
+*/
+type JAMLayout struct {
+	Magic    string
+	NameSize int
+	ExtSize  int
+	Unknown1 uint32
+}
+
+/*
+JAM ...
+Build a JAM archive with magic "FSTA" or "JAM2" holding members in their order, with 8 byte
+names, 4 byte extensions and unknown value 0
+*/
+func JAM(magic string, members ...Member) Archive {
+	return JAMOf(JAMLayout{Magic: magic, NameSize: 8, ExtSize: 4}, members...)
+}
+
+/*
+JAMOf ...
+Build a JAM archive with layout holding members in their order. Names and extensions are listed
+once each in the order they first appear, the member data follows the file table aligned to 0x20.
+*/
+func JAMOf(layout JAMLayout, members ...Member) Archive {
+	var names, exts []string
+	indexOf := func(table *[]string, name string) int {
+		for idx, existing := range *table {
+			if existing == name {
+				return idx
+			}
+		}
+		*table = append(*table, name)
+		return len(*table) - 1
+	}
+
+	type entry struct {
+		name int
+		ext  int
+	}
+	entries := make([]entry, len(members))
+	for idx, member := range members {
+		entries[idx] = entry{indexOf(&names, member.Name), indexOf(&exts, member.Ext)}
+	}
+
+	tableEnd := 0x20 + len(names)*layout.NameSize + len(exts)*layout.ExtSize + len(members)*8
+	out := make([]byte, tableEnd)
+	copy(out[0x00:], layout.Magic)
+	binary.LittleEndian.PutUint32(out[0x04:], layout.Unknown1)
+	binary.LittleEndian.PutUint32(out[0x08:], uint32(tableEnd))
+	copy(out[0x0C:0x1C], "JMWK")
+	binary.LittleEndian.PutUint16(out[0x1C:], uint16(len(names)))
+	binary.LittleEndian.PutUint16(out[0x1E:], uint16(len(exts)))
+
+	idx := 0x20
+	for _, name := range names {
+		copy(out[idx:idx+layout.NameSize], name)
+		idx += layout.NameSize
+	}
+	for _, ext := range exts {
+		copy(out[idx:idx+layout.ExtSize], ext)
+		idx += layout.ExtSize
+	}
+
+	offsets := make([]int, len(members))
+	for memberIdx, member := range members {
+		out = align(out, 0x20)
+		offsets[memberIdx] = len(out)
+		out = append(out, member.Data...)
+	}
+
+	archive := Archive{}
+	for memberIdx, member := range members {
+		binary.LittleEndian.PutUint16(out[idx:], uint16(entries[memberIdx].name))
+		binary.LittleEndian.PutUint16(out[idx+2:], uint16(entries[memberIdx].ext))
+		binary.LittleEndian.PutUint32(out[idx+4:], uint32(offsets[memberIdx]))
+		idx += 8
+
+		end := len(out)
+		if memberIdx+1 < len(members) {
+			end = offsets[memberIdx+1]
+		}
+		member.Data = out[offsets[memberIdx]:end]
+		archive.Members = append(archive.Members, member)
+	}
+
+	archive.Data = out
+	return archive
+}
@@ -0,0 +1,93 @@
+package testgen
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+)
+
+/*
+The image formats of KRT textures, by what the texture data holds.
+*/
+const (
+	KRTRGBA8    uint32 = 0x0F
+	KRTRGB5A3   uint32 = 0x10
+	KRTC8RGB565 uint32 = 0x11
+	KRTC8RGB5A3 uint32 = 0x12
+	KRTC4RGB565 uint32 = 0x13
+	KRTI4       uint32 = 0x16
+	KRTRGB565   uint32 = 0x17
+)
+
+const krtHeaderSize = 0xA0
+
+/*
+KRTFormats ...
+Every KRT image format
+*/
+var KRTFormats = []uint32{KRTRGBA8, KRTRGB5A3, KRTC8RGB565, KRTC8RGB5A3, KRTC4RGB565, KRTI4, KRTRGB565}
+
+var krtTilings = map[uint32]tiling{
+	KRTRGBA8:    {4, 4, 32},
+	KRTRGB5A3:   {4, 4, 16},
+	KRTC8RGB565: {8, 4, 8},
+	KRTC8RGB5A3: {8, 4, 8},
+	KRTC4RGB565: {8, 8, 4},
+	KRTI4:       {8, 8, 4},
+	KRTRGB565:   {4, 4, 16},
+}
+
+/*
+The I4 of KRT uses the intensity for the alpha too.
+*/
+var krtI4Texels = []texel{
+	{0x0, color.NRGBA{0x00, 0x00, 0x00, 0x00}},
+	{0xF, white},
+	{0x8, color.NRGBA{0x88, 0x88, 0x88, 0x88}},
+	{0x3, color.NRGBA{0x33, 0x33, 0x33, 0x33}},
+}
+
+/*
+KRT ...
+Build a KRT texture of an image format. Width and height have to be multiples of 8, KRT
+textures are always made of whole tiles. Palette formats get their palette at 0xA0, the image
+data follows aligned to 0x20.
+*/
+func KRT(format uint32, width int, height int) Texture {
+	var palData []byte
+	pick := pattern(map[uint32][]texel{
+		KRTRGBA8:  rgba32Texels,
+		KRTRGB5A3: rgb5a3Texels,
+		KRTI4:     krtI4Texels,
+		KRTRGB565: rgb565Texels,
+	}[format])
+	switch format {
+	case KRTC8RGB565, KRTC4RGB565:
+		palData, pick = palette(rgb565Texels, 8)
+	case KRTC8RGB5A3:
+		palData, pick = palette(rgb5a3Texels, 8)
+	}
+
+	layout := krtTilings[format]
+	data, img := tile(layout, width, height, pick)
+
+	out := make([]byte, krtHeaderSize)
+	binary.BigEndian.PutUint32(out[0x20:], uint32(width))
+	binary.BigEndian.PutUint32(out[0x24:], uint32(height))
+	binary.BigEndian.PutUint32(out[0x28:], format)
+	//The block size is the number of pixels of a tile, then the two flags that are almost always 1.
+	binary.BigEndian.PutUint16(out[0x2C:], uint16(layout.blockWidth*layout.blockHeight))
+	out[0x2E], out[0x2F] = 1, 1
+	out[0x34] = 0xFF
+	binary.BigEndian.PutUint32(out[0x38:], uint32(width*height))
+
+	if palData != nil {
+		binary.BigEndian.PutUint32(out[0x6C:], uint32(len(out)))
+		out = align(append(out, palData...), 0x20)
+	}
+	binary.BigEndian.PutUint32(out[0x70:], uint32(len(out)))
+	out = append(out, data...)
+	binary.BigEndian.PutUint32(out[0x74:], uint32(len(out)))
+
+	return Texture{Data: out, Images: []*image.NRGBA{img}}
+}
@@ -0,0 +1,192 @@
+package testgen
+
+/*
+Builders of small valid files of every format, with known contents, for the tests of the
+format packages. Game files can't be committed, these stand in for them.
+
+Files are put together byte by byte from the format descriptions, without the format
+packages, so a bug in a package can't hide in the fixtures its tests use. Textures come with
+the pixels they have to decode to, taken from tables of raw texel values and the colours
+they stand for.
+*/
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+)
+
+/*
+Texture ...
+A generated texture file and the pixels each of its images has to decode to
+*/
+type Texture struct {
+	Data   []byte
+	Images []*image.NRGBA
+}
+
+/*
+texel ...
+A raw texel value of an image format and the colour it decodes to. raw is a nibble, a byte,
+a 16 bit value or, for RGBA32, ARGB in 32 bits.
+*/
+type texel struct {
+	raw   uint32
+	color color.NRGBA
+}
+
+var (
+	white = color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	black = color.NRGBA{0x00, 0x00, 0x00, 0xFF}
+
+	i4Texels = []texel{
+		{0x0, black},
+		{0xF, white},
+		{0x8, color.NRGBA{0x88, 0x88, 0x88, 0xFF}},
+		{0x3, color.NRGBA{0x33, 0x33, 0x33, 0xFF}},
+	}
+	i8Texels = []texel{
+		{0x00, black},
+		{0xFF, white},
+		{0x80, color.NRGBA{0x80, 0x80, 0x80, 0xFF}},
+		{0x2A, color.NRGBA{0x2A, 0x2A, 0x2A, 0xFF}},
+	}
+	//Alpha in the high nibble, intensity in the low one.
+	ia4Texels = []texel{
+		{0xF0, black},
+		{0x0F, color.NRGBA{0xFF, 0xFF, 0xFF, 0x00}},
+		{0x8C, color.NRGBA{0xCC, 0xCC, 0xCC, 0x88}},
+		{0xFF, white},
+	}
+	//Alpha in the first byte, intensity in the second.
+	ia8Texels = []texel{
+		{0xFF00, black},
+		{0x00FF, color.NRGBA{0xFF, 0xFF, 0xFF, 0x00}},
+		{0x8040, color.NRGBA{0x40, 0x40, 0x40, 0x80}},
+		{0xFFFF, white},
+	}
+	rgb565Texels = []texel{
+		{0xF800, color.NRGBA{0xFF, 0x00, 0x00, 0xFF}},
+		{0x07E0, color.NRGBA{0x00, 0xFF, 0x00, 0xFF}},
+		{0x001F, color.NRGBA{0x00, 0x00, 0xFF, 0xFF}},
+		{0x8410, color.NRGBA{0x84, 0x82, 0x84, 0xFF}},
+	}
+	//The top bit picks opaque RGB555 or RGB444 with three bits of alpha.
+	rgb5a3Texels = []texel{
+		{0xFC00, color.NRGBA{0xFF, 0x00, 0x00, 0xFF}},
+		{0x83E0, color.NRGBA{0x00, 0xFF, 0x00, 0xFF}},
+		{0x0F00, color.NRGBA{0xFF, 0x00, 0x00, 0x00}},
+		{0x4F0F, color.NRGBA{0xFF, 0x00, 0xFF, 0x92}},
+		{0x7FFF, white},
+	}
+	rgba32Texels = []texel{
+		{0x78123456, color.NRGBA{0x12, 0x34, 0x56, 0x78}},
+		{0xFFFF0000, color.NRGBA{0xFF, 0x00, 0x00, 0xFF}},
+		{0x8000FF00, color.NRGBA{0x00, 0xFF, 0x00, 0x80}},
+		{0x000000FF, color.NRGBA{0x00, 0x00, 0xFF, 0x00}},
+	}
+)
+
+/*
+tiling ...
+How an image format lays out its pixels: tiles of blockWidth by blockHeight pixels, left to
+right and top to bottom, bitsPerPixel bits for each pixel of a tile in row order.
+*/
+type tiling struct {
+	blockWidth   int
+	blockHeight  int
+	bitsPerPixel int
+}
+
+/*
+tile ...
+Lay out a width by height image in tiles. pick gives the texel of every pixel, the decoded
+image gets its colour and the data its raw value. Pixels past the edge pad the tiles with zero.
+*/
+func tile(layout tiling, width int, height int, pick func(x int, y int) texel) ([]byte, *image.NRGBA) {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	blockBytes := layout.blockWidth * layout.blockHeight * layout.bitsPerPixel / 8
+
+	var data []byte
+	for blockY := 0; blockY < height; blockY += layout.blockHeight {
+		for blockX := 0; blockX < width; blockX += layout.blockWidth {
+			block := make([]byte, blockBytes)
+
+			for y := 0; y < layout.blockHeight; y++ {
+				for x := 0; x < layout.blockWidth; x++ {
+					if blockX+x >= width || blockY+y >= height {
+						continue
+					}
+					texel := pick(blockX+x, blockY+y)
+					img.SetNRGBA(blockX+x, blockY+y, texel.color)
+
+					pixel := y*layout.blockWidth + x
+					switch layout.bitsPerPixel {
+					case 4:
+						if pixel%2 == 0 {
+							block[pixel/2] |= uint8(texel.raw) << 4
+						} else {
+							block[pixel/2] |= uint8(texel.raw)
+						}
+					case 8:
+						block[pixel] = uint8(texel.raw)
+					case 16:
+						binary.BigEndian.PutUint16(block[pixel*2:], uint16(texel.raw))
+					case 32:
+						//The first 32 bytes of a tile hold the AR pairs of its pixels, the last 32 the GB pairs.
+						binary.BigEndian.PutUint16(block[pixel*2:], uint16(texel.raw>>16))
+						binary.BigEndian.PutUint16(block[32+pixel*2:], uint16(texel.raw))
+					}
+				}
+			}
+
+			data = append(data, block...)
+		}
+	}
+
+	return data, img
+}
+
+/*
+pattern ...
+The texel of pixel x, y out of texels. Neighbouring pixels and rows differ, so a tile that is
+laid out wrong decodes to other colours.
+*/
+func pattern(texels []texel) func(x int, y int) texel {
+	return func(x int, y int) texel {
+		return texels[patternIndex(x, y, len(texels))]
+	}
+}
+
+func patternIndex(x int, y int, count int) int {
+	return (x + y*3 + x/4) % count
+}
+
+/*
+palette ...
+The raw palette data of texels and a pattern of indices into it, every index decodes to the
+colour of its entry. The indices of 16 bit formats get their two unused top bits set on every
+other pixel, they have to be ignored.
+*/
+func palette(texels []texel, bitsPerPixel int) ([]byte, func(x int, y int) texel) {
+	data := make([]byte, len(texels)*2)
+	for idx, texel := range texels {
+		binary.BigEndian.PutUint16(data[idx*2:], uint16(texel.raw))
+	}
+
+	return data, func(x int, y int) texel {
+		entry := patternIndex(x, y, len(texels))
+		index := uint32(entry)
+		if bitsPerPixel == 16 && (x+y)%2 == 1 {
+			index |= 0xC000
+		}
+		return texel{index, texels[entry].color}
+	}
+}
+
+func align(data []byte, alignment int) []byte {
+	for len(data)%alignment != 0 {
+		data = append(data, 0x00)
+	}
+	return data
+}
@@ -0,0 +1,46 @@
+package testgen
+
+import "encoding/binary"
+
+/*
+TGAImage ...
+The header values and data of a generated TGA file. Data is the image data as it is stored:
+raw pixels, colour map indices or run-length packets, in the row order Descriptor says.
+*/
+type TGAImage struct {
+	Type          uint8
+	Width         uint16
+	Height        uint16
+	PixelDepth    uint8
+	Descriptor    uint8
+	ColorMapFirst uint16
+	ColorMapDepth uint8
+	//ColorMap holds ColorMapDepth bit entries, the file has a colour map when it isn't empty.
+	ColorMap []byte
+	ID       string
+	Data     []byte
+}
+
+/*
+TGA ...
+Build a TGA file from its header values, id, colour map and image data
+*/
+func TGA(img TGAImage) []byte {
+	out := make([]byte, 0x12)
+	out[0x00] = uint8(len(img.ID))
+	out[0x02] = img.Type
+	if len(img.ColorMap) > 0 {
+		out[0x01] = 1
+		binary.LittleEndian.PutUint16(out[0x03:], img.ColorMapFirst)
+		binary.LittleEndian.PutUint16(out[0x05:], uint16(len(img.ColorMap)/((int(img.ColorMapDepth)+7)/8)))
+		out[0x07] = img.ColorMapDepth
+	}
+	binary.LittleEndian.PutUint16(out[0x0C:], img.Width)
+	binary.LittleEndian.PutUint16(out[0x0E:], img.Height)
+	out[0x10] = img.PixelDepth
+	out[0x11] = img.Descriptor
+
+	out = append(out, img.ID...)
+	out = append(out, img.ColorMap...)
+	return append(out, img.Data...)
+}
@@ -0,0 +1,216 @@
+package testgen
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+)
+
+/*
+The GX image formats, as TPL image headers store them.
+*/
+const (
+	I4     uint32 = 0x00
+	I8     uint32 = 0x01
+	IA4    uint32 = 0x02
+	IA8    uint32 = 0x03
+	RGB565 uint32 = 0x04
+	RGB5A3 uint32 = 0x05
+	RGBA32 uint32 = 0x06
+	C4     uint32 = 0x08
+	C8     uint32 = 0x09
+	C14X2  uint32 = 0x0A
+	CMPR   uint32 = 0x0E
+)
+
+/*
+TPLFormats ...
+Every GX image format
+*/
+var TPLFormats = []uint32{I4, I8, IA4, IA8, RGB565, RGB5A3, RGBA32, C4, C8, C14X2, CMPR}
+
+var gxTilings = map[uint32]tiling{
+	I4:     {8, 8, 4},
+	I8:     {8, 4, 8},
+	IA4:    {8, 4, 8},
+	IA8:    {4, 4, 16},
+	RGB565: {4, 4, 16},
+	RGB5A3: {4, 4, 16},
+	RGBA32: {4, 4, 32},
+	C4:     {8, 8, 4},
+	C8:     {8, 4, 8},
+	C14X2:  {4, 4, 16},
+}
+
+/*
+TPLImage ...
+One image of a generated TPL file. Width and height don't have to be multiples of the tile
+size of the format, the tiles at the edges are padded.
+*/
+type TPLImage struct {
+	Format uint32
+	Width  int
+	Height int
+}
+
+/*
+TPL ...
+Build a TPL file holding images, in their order. Palette formats get a palette of their own:
+C4 an RGB565 one, C8 an RGB5A3 one and C14X2 an IA8 one. The headers follow the offset table,
+every palette and image data is aligned to 0x20 after them, and the file ends with the last
+image data.
+*/
+func TPL(images ...TPLImage) Texture {
+	texture := Texture{}
+
+	type part struct {
+		header    int
+		palHeader int
+		palData   []byte
+		palFormat uint32
+		data      []byte
+	}
+	parts := make([]part, len(images))
+
+	headerEnd := 0x0C + len(images)*8
+	for idx, spec := range images {
+		p := part{}
+		var img *image.NRGBA
+		p.data, p.palData, p.palFormat, img = gxImage(spec)
+		texture.Images = append(texture.Images, img)
+
+		if p.palData != nil {
+			p.palHeader = headerEnd
+			headerEnd += 0x0C
+		}
+		p.header = headerEnd
+		headerEnd += 0x24
+		parts[idx] = p
+	}
+
+	out := make([]byte, headerEnd)
+	binary.BigEndian.PutUint32(out[0x00:], 0x0020AF30)
+	binary.BigEndian.PutUint32(out[0x04:], uint32(len(images)))
+	binary.BigEndian.PutUint32(out[0x08:], 0x0C)
+
+	for idx, p := range parts {
+		binary.BigEndian.PutUint32(out[0x0C+idx*8:], uint32(p.header))
+		binary.BigEndian.PutUint32(out[0x10+idx*8:], uint32(p.palHeader))
+
+		if p.palData != nil {
+			out = align(out, 0x20)
+			palHeader := out[p.palHeader:]
+			binary.BigEndian.PutUint16(palHeader[0x00:], uint16(len(p.palData)/2))
+			binary.BigEndian.PutUint32(palHeader[0x04:], p.palFormat)
+			binary.BigEndian.PutUint32(palHeader[0x08:], uint32(len(out)))
+			out = append(out, p.palData...)
+		}
+
+		out = align(out, 0x20)
+		header := out[p.header:]
+		binary.BigEndian.PutUint16(header[0x00:], uint16(images[idx].Height))
+		binary.BigEndian.PutUint16(header[0x02:], uint16(images[idx].Width))
+		binary.BigEndian.PutUint32(header[0x04:], images[idx].Format)
+		binary.BigEndian.PutUint32(header[0x08:], uint32(len(out)))
+		//Clamp S, repeat T and linear filtering, the rest stays zero: no LOD bias and no mipmaps.
+		binary.BigEndian.PutUint32(header[0x10:], 1)
+		binary.BigEndian.PutUint32(header[0x14:], 1)
+		binary.BigEndian.PutUint32(header[0x18:], 1)
+		out = append(out, p.data...)
+	}
+
+	texture.Data = out
+	return texture
+}
+
+/*
+gxImage ...
+The image data of a GX image, its palette data and palette format for palette formats, and
+the pixels it decodes to
+*/
+func gxImage(spec TPLImage) ([]byte, []byte, uint32, *image.NRGBA) {
+	width, height := spec.Width, spec.Height
+
+	switch spec.Format {
+	case CMPR:
+		data, img := cmpr(width, height)
+		return data, nil, 0, img
+	case C4:
+		palData, pick := palette(rgb565Texels, 4)
+		data, img := tile(gxTilings[C4], width, height, pick)
+		return data, palData, 0x01, img
+	case C8:
+		palData, pick := palette(rgb5a3Texels, 8)
+		data, img := tile(gxTilings[C8], width, height, pick)
+		return data, palData, 0x02, img
+	case C14X2:
+		palData, pick := palette(ia8Texels, 16)
+		data, img := tile(gxTilings[C14X2], width, height, pick)
+		return data, palData, 0x00, img
+	}
+
+	texels := map[uint32][]texel{
+		I4:     i4Texels,
+		I8:     i8Texels,
+		IA4:    ia4Texels,
+		IA8:    ia8Texels,
+		RGB565: rgb565Texels,
+		RGB5A3: rgb5a3Texels,
+		RGBA32: rgba32Texels,
+	}[spec.Format]
+	data, img := tile(gxTilings[spec.Format], width, height, pattern(texels))
+	return data, nil, 0, img
+}
+
+/*
+cmprBlocks ...
+The two kinds of DXT1 block CMPR has. color0 above color1 gives four colours, two of them
+mixed a third and two thirds of the way, otherwise three colours, one mixed half way, and
+transparent.
+*/
+var cmprBlocks = []struct {
+	color0 uint16
+	color1 uint16
+	colors [4]color.NRGBA
+}{
+	{0xFFFF, 0x0000, [4]color.NRGBA{white, black, {0xAA, 0xAA, 0xAA, 0xFF}, {0x55, 0x55, 0x55, 0xFF}}},
+	{0x0000, 0xF800, [4]color.NRGBA{black, {0xFF, 0x00, 0x00, 0xFF}, {0x7F, 0x00, 0x00, 0xFF}, {}}},
+}
+
+/*
+cmpr ...
+Lay out a CMPR image: 8x8 tiles of four 4x4 blocks, top left, top right, bottom left and
+bottom right, the blocks on the left of a tile of the four colour kind and on the right of
+the three colour kind
+*/
+func cmpr(width int, height int) ([]byte, *image.NRGBA) {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	var data []byte
+	for tileY := 0; tileY < height; tileY += 8 {
+		for tileX := 0; tileX < width; tileX += 8 {
+			for sub := 0; sub < 4; sub++ {
+				kind := cmprBlocks[sub%2]
+				block := make([]byte, 8)
+				binary.BigEndian.PutUint16(block[0:], kind.color0)
+				binary.BigEndian.PutUint16(block[2:], kind.color1)
+
+				subX, subY := tileX+(sub%2)*4, tileY+(sub/2)*4
+				for y := 0; y < 4; y++ {
+					for x := 0; x < 4; x++ {
+						if subX+x >= width || subY+y >= height {
+							continue
+						}
+						index := patternIndex(subX+x, subY+y, 4)
+						img.SetNRGBA(subX+x, subY+y, kind.colors[index])
+						block[4+y] |= uint8(index) << uint(6-x*2)
+					}
+				}
+
+				data = append(data, block...)
+			}
+		}
+	}
+
+	return data, img
+}
@@ -0,0 +1,49 @@
+package testgen
+
+import "encoding/binary"
+
+/*
+Yaz0 ...
+Compress data to a Yaz0 file the simplest way there is: a run of 3 or more equal bytes after
+the first one becomes a back reference to the byte before it, the 2 byte kind up to 0x11 bytes
+and the 3 byte kind up to 0x111, everything else is literals. That is enough to cover both
+kinds of back reference and ones that overlap what they write.
+*/
+func Yaz0(data []byte) []byte {
+	out := make([]byte, 0x10)
+	copy(out, "Yaz0")
+	binary.BigEndian.PutUint32(out[4:], uint32(len(data)))
+
+	codeIndex, bit := 0, 8
+	chunk := func(code bool, bytes ...byte) {
+		if bit == 8 {
+			codeIndex, bit = len(out), 0
+			out = append(out, 0x00)
+		}
+		if code {
+			out[codeIndex] |= 0x80 >> uint(bit)
+		}
+		bit++
+		out = append(out, bytes...)
+	}
+
+	for pos := 0; pos < len(data); {
+		run := 0
+		for pos > 0 && pos+run < len(data) && run < 0x111 && data[pos+run] == data[pos-1] {
+			run++
+		}
+
+		switch {
+		case run >= 0x12:
+			chunk(false, 0x00, 0x00, byte(run-0x12))
+		case run >= 3:
+			chunk(false, byte(run-2)<<4, 0x00)
+		default:
+			chunk(true, data[pos])
+			run = 1
+		}
+		pos += run
+	}
+
+	return out
+}
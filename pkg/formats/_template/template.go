@@ -0,0 +1,34 @@
+package template
+
+/*
+NAME:
+EXTENSION:
+DESCRIPTION:
+
+The File and Work convention every format package follows, kept in a directory the go tool
+skips so it isn't compiled. Copy it to start a format: File is the file as it is stored and
+Work the form that is meant to be worked with. Register a format{} in init to make it known
+to the registry, see formats.Format.
+*/
+
+import (
+	"fmt"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+type File struct{}
+type Work struct{}
+
+func Read(data []byte) (*File, error) {
+	return nil, fmt.Errorf("%w, reading", formats.ErrNotSupported)
+}
+func Write(data *File) ([]byte, error) {
+	return nil, fmt.Errorf("%w, writing", formats.ErrNotSupported)
+}
+func Decode(data *File) (*Work, error) {
+	return nil, fmt.Errorf("%w, decoding", formats.ErrNotSupported)
+}
+func Encode(data *Work) (*File, error) {
+	return nil, fmt.Errorf("%w, encoding", formats.ErrNotSupported)
+}
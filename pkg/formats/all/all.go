@@ -0,0 +1,20 @@
+package all
+
+/*
+Importing this package registers every format of the module with the formats registry,
+for tools that work on any file:
+
+	import _ "github.com/ProfElements/go-files/pkg/formats/all"
+*/
+
+import (
+	_ "github.com/ProfElements/go-files/pkg/formats/bmvg"
+	_ "github.com/ProfElements/go-files/pkg/formats/bmvg/gka"
+	_ "github.com/ProfElements/go-files/pkg/formats/bmvg/gmd"
+	_ "github.com/ProfElements/go-files/pkg/formats/bmvg/jam"
+	_ "github.com/ProfElements/go-files/pkg/formats/cftkk/crt"
+	_ "github.com/ProfElements/go-files/pkg/formats/cftkk/fetm"
+	_ "github.com/ProfElements/go-files/pkg/formats/nintendo/tpl"
+	_ "github.com/ProfElements/go-files/pkg/formats/nintendo/yaz0"
+	_ "github.com/ProfElements/go-files/pkg/formats/truevision/tga"
+)
@@ -0,0 +1,106 @@
+package all
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+FuzzDetect ...
+Feed any data through what the tools do with a file they don't know: detect it, then read,
+decode and unpack it as every format it could be, unwrapping the wrappers.
+*/
+func FuzzDetect(f *testing.F) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for idx := 0; idx < 64; idx++ {
+		img.SetNRGBA(idx%8, idx/8, color.NRGBA{uint8(idx * 4), 0x80, uint8(255 - idx*4), 0xFF})
+	}
+
+	//A file of every format that can be encoded from an image or from bytes, and each of them wrapped.
+	var seeds [][]byte
+	for _, format := range formats.Formats() {
+		for _, work := range []interface{}{img, []byte("payload")} {
+			file, err := format.Encode(work)
+			if err != nil {
+				continue
+			}
+			if data, err := format.Write(file); err == nil {
+				seeds = append(seeds, data)
+			}
+		}
+		if packer, ok := format.(formats.Packer); ok {
+			if data, err := packer.Pack([]formats.Member{{Name: "A.TXT", Data: []byte("member")}}); err == nil {
+				seeds = append(seeds, data)
+			}
+		}
+	}
+	//Generated files of what can't be encoded: every TPL and KRT image format, a level and an archive holding a texture.
+	var images []testgen.TPLImage
+	for _, format := range testgen.TPLFormats {
+		images = append(images, testgen.TPLImage{Format: format, Width: 8, Height: 8})
+	}
+	tpl := testgen.TPL(images...).Data
+	seeds = append(seeds, tpl, testgen.FETM().Data, testgen.JAM("JAM2", testgen.Member{Name: "LEVEL", Ext: "TPL", Data: tpl}).Data)
+	for _, format := range testgen.KRTFormats {
+		seeds = append(seeds, testgen.KRT(format, 8, 8).Data)
+	}
+
+	for _, format := range formats.Formats() {
+		if _, ok := format.(formats.Wrapper); !ok {
+			continue
+		}
+		for _, seed := range seeds {
+			if file, err := format.Encode(seed); err == nil {
+				if data, err := format.Write(file); err == nil {
+					f.Add(data)
+				}
+			}
+		}
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, detection := range formats.DetectAll(data) {
+			handle(detection, data)
+		}
+	})
+}
+
+func handle(detection formats.Detection, data []byte) {
+	format := detection.Format
+	if wrapper, ok := format.(formats.Wrapper); ok {
+		inner, err := wrapper.Unwrap(data)
+		if err == nil && detection.Inner != nil {
+			handle(*detection.Inner, inner)
+		}
+	}
+
+	file, err := format.Read(data)
+	if err != nil {
+		return
+	}
+	format.Write(file)
+	format.Decode(file)
+
+	if inspector, ok := format.(formats.Inspector); ok {
+		if inspection, err := inspector.Inspect(file); err == nil {
+			inspection.WriteText(ioutil.Discard, "")
+			if _, err := json.Marshal(inspection); err != nil {
+				panic(fmt.Sprintf("the %v inspection doesn't encode to JSON, %v", format.Name(), err))
+			}
+		}
+	}
+
+	if container, ok := format.(formats.Container); ok {
+		container.Unpack(data)
+	}
+}
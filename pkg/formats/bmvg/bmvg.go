@@ -0,0 +1,196 @@
+package bmvg
+
+/*
+Name: High Voltage members
+Description: The formats stored in the .jam archives of the High Voltage games, in the
+             packages under this one. Every one of them starts with the same big endian
+             header: a few values of unknown meaning and the size of the whole file.
+
+BINARY STRUCTURE:
+
+             Unknowns;  uint32 each, three of them, five in GSL
+             FileSize;  uint32, size of the whole file including this header
+             body;      FileSize - the header bytes
+
+             GMD (models) and GKA (animations) have a body of tables described in their
+             packages. That layout is provisional: it hasn't been checked against files from
+             the games yet, a file that doesn't follow it fails to read with a formats error.
+
+             Nothing is known yet about the bodies of GGG, GMS and GSL. File reads the header of
+             any of the formats and keeps the body as raw data, which is written back unchanged.
+*/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+Unknowns ...
+The number of unknown header values before the file size of every format, by extension
+*/
+var Unknowns = map[string]int{
+	"GGG": 3,
+	"GKA": 3,
+	"GMD": 3,
+	"GMS": 3,
+	"GSL": 5,
+}
+
+/*
+ReadHeader ...
+Read the header of a file of format, lowercase like "gmd", that has unknowns values before
+its file size. Returns the unknown values, the file size and the body up to the file size, or
+error.
+*/
+func ReadHeader(format string, data []byte, unknowns int) ([]uint32, uint32, []byte, error) {
+	headerSize := 4*unknowns + 4
+	if len(data) < headerSize {
+		return nil, 0, nil, &formats.FormatError{Format: format, Offset: 0, Field: "header", Err: fmt.Errorf("%w, it is 0x%X bytes and there are 0x%X", formats.ErrTruncated, headerSize, len(data))}
+	}
+
+	values := make([]uint32, unknowns)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint32(data[4*i : 4*i+4])
+	}
+	fileSize := binary.BigEndian.Uint32(data[headerSize-4 : headerSize])
+
+	if fileSize < uint32(headerSize) {
+		return nil, 0, nil, &formats.FormatError{Format: format, Offset: headerSize - 4, Field: "file size", Err: fmt.Errorf("%w, 0x%X is smaller than the header", formats.ErrInvalid, fileSize)}
+	}
+	if uint64(fileSize) > uint64(len(data)) {
+		return nil, 0, nil, &formats.FormatError{Format: format, Offset: headerSize - 4, Field: "file size", Err: fmt.Errorf("%w, the file is 0x%X bytes and there are 0x%X", formats.ErrTruncated, fileSize, len(data))}
+	}
+
+	return values, fileSize, data[headerSize:fileSize], nil
+}
+
+/*
+WriteHeader ...
+Write the header values and the body after them, the file size is worked out from the body
+*/
+func WriteHeader(unknowns []uint32, body []byte) []byte {
+	buffer := &bytes.Buffer{}
+	headerSize := 4*len(unknowns) + 4
+
+	for _, value := range unknowns {
+		binary.Write(buffer, binary.BigEndian, value)
+	}
+	binary.Write(buffer, binary.BigEndian, uint32(headerSize+len(body)))
+	buffer.Write(body)
+
+	return buffer.Bytes()
+}
+
+/*
+MemberSize ...
+Return the size of the High Voltage file of extension ext at the start of data, or error.
+ok is false for extensions that aren't High Voltage formats.
+*/
+func MemberSize(ext string, data []byte) (uint32, bool, error) {
+	unknowns, ok := Unknowns[ext]
+	if !ok {
+		return 0, false, nil
+	}
+
+	_, fileSize, _, err := ReadHeader(strings.ToLower(ext), data, unknowns)
+	return fileSize, true, err
+}
+
+/*
+Span ...
+A table or a block of data in the body of a High Voltage file: Count entries of Size bytes at
+Offset, counted from the start of the file. Field names it in errors.
+*/
+type Span struct {
+	Field  string
+	Offset uint32
+	Count  uint32
+	Size   uint32
+}
+
+/*
+CheckSpans ...
+Check that every span with entries starts after the first start bytes of the file, ends inside
+fileSize and overlaps no other span, or error. The GMD and GKA layouts are provisional, a file
+whose tables don't hold to this doesn't follow them and is refused instead of read as garbage.
+*/
+func CheckSpans(format string, start uint32, fileSize uint32, spans ...Span) error {
+	var placed []Span
+	for _, span := range spans {
+		if span.Count == 0 {
+			continue
+		}
+		if span.Offset < start {
+			return &formats.FormatError{Format: format, Offset: int(span.Offset), Field: span.Field,
+				Err: fmt.Errorf("%w, it starts at 0x%X, inside the 0x%X bytes of the header", formats.ErrInvalid, span.Offset, start)}
+		}
+		end := uint64(span.Offset) + uint64(span.Count)*uint64(span.Size)
+		if end > uint64(fileSize) {
+			return &formats.FormatError{Format: format, Offset: int(span.Offset), Field: span.Field,
+				Err: fmt.Errorf("%w, 0x%X entries of 0x%X bytes end at 0x%X of 0x%X bytes", formats.ErrTruncated, span.Count, span.Size, end, fileSize)}
+		}
+		placed = append(placed, span)
+	}
+
+	sort.SliceStable(placed, func(i, j int) bool { return placed[i].Offset < placed[j].Offset })
+	for i := 1; i < len(placed); i++ {
+		before := placed[i-1]
+		if uint64(before.Offset)+uint64(before.Count)*uint64(before.Size) > uint64(placed[i].Offset) {
+			return &formats.FormatError{Format: format, Offset: int(placed[i].Offset), Field: placed[i].Field,
+				Err: fmt.Errorf("%w, it overlaps the %v at 0x%X", formats.ErrInvalid, before.Field, before.Offset)}
+		}
+	}
+	return nil
+}
+
+/*
+File ...
+A High Voltage file read as its header and a raw body. Format is the lowercase extension, the
+number of Unknowns is the one of the format in Unknowns.
+*/
+type File struct {
+	Format   string
+	Unknowns []uint32
+	FileSize uint32
+	Data     []byte
+}
+
+/*
+Read ...
+Read a High Voltage file of format, lowercase like "gsl", from data and return a File structure
+pointer, or error
+*/
+func Read(format string, data []byte) (*File, error) {
+	unknowns, ok := Unknowns[strings.ToUpper(format)]
+	if !ok {
+		return nil, fmt.Errorf("%w, %v isn't a High Voltage format", formats.ErrUnsupportedFormat, format)
+	}
+
+	values, fileSize, body, err := ReadHeader(format, data, unknowns)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Format: format, Unknowns: values, FileSize: fileSize, Data: body}, nil
+}
+
+/*
+Write ...
+Write a File structure to a file of its format, or error
+*/
+func Write(data *File) ([]byte, error) {
+	unknowns, ok := Unknowns[strings.ToUpper(data.Format)]
+	if !ok {
+		return nil, fmt.Errorf("%w, %v isn't a High Voltage format", formats.ErrUnsupportedFormat, data.Format)
+	}
+	if len(data.Unknowns) != unknowns {
+		return nil, &formats.FormatError{Format: data.Format, Offset: 0, Field: "header", Err: fmt.Errorf("%w, there are %v unknown values and the format has %v", formats.ErrInvalid, len(data.Unknowns), unknowns)}
+	}
+
+	return WriteHeader(data.Unknowns, data.Data), nil
+}
@@ -0,0 +1,123 @@
+package bmvg
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+func TestReadHeader(t *testing.T) {
+	tooLarge := testgen.HV([]uint32{1, 2, 3}, []byte("body"))
+	tooLarge[0x0F]++
+	tooSmall := testgen.HV([]uint32{1, 2, 3}, nil)
+	tooSmall[0x0F] = 0x0C
+
+	tests := []struct {
+		name   string
+		data   []byte
+		offset int
+		err    error
+	}{
+		{name: "short header", data: make([]byte, 0x0F), offset: 0, err: formats.ErrTruncated},
+		{name: "file size past the end", data: tooLarge, offset: 0x0C, err: formats.ErrTruncated},
+		{name: "file size inside the header", data: tooSmall, offset: 0x0C, err: formats.ErrInvalid},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, _, err := ReadHeader("gmd", test.data, 3)
+			var formatErr *formats.FormatError
+			if !errors.As(err, &formatErr) || formatErr.Offset != test.offset || !errors.Is(err, test.err) {
+				t.Fatalf("reading gave %v, it has to be %v at 0x%X", err, test.err, test.offset)
+			}
+		})
+	}
+}
+
+func TestMemberSize(t *testing.T) {
+	//The file is followed by the next member, only its own bytes belong to it.
+	gsl := append(testgen.HV([]uint32{1, 2, 3, 4, 5}, []byte("level")), "next member"...)
+
+	size, ok, err := MemberSize("GSL", gsl)
+	if err != nil || !ok || size != 0x18+5 {
+		t.Fatalf("the gsl is 0x%X bytes, ok %v and error %v", size, ok, err)
+	}
+	if _, ok, _ := MemberSize("TPL", gsl); ok {
+		t.Fatalf("TPL isn't a High Voltage format")
+	}
+}
+
+func FuzzRead(f *testing.F) {
+	f.Add(testgen.HV([]uint32{1, 2, 3, 4, 5}, []byte("file data")))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, format := range []string{"ggg", "gms", "gsl"} {
+			file, err := Read(format, data)
+			if err != nil {
+				continue
+			}
+			Write(file)
+		}
+	})
+}
+
+func TestFile(t *testing.T) {
+	tests := []struct {
+		format   string
+		unknowns []uint32
+		body     string
+	}{
+		{"ggg", []uint32{0x11, 0x22, 0x33}, "ggg data"},
+		{"gms", []uint32{0x44, 0x55, 0x66}, "gms data"},
+		{"gsl", []uint32{0x11, 0x22, 0x33, 0x44, 0x55}, "gsl data"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			//The next member follows the file, the file size ends the body.
+			data := testgen.HV(test.unknowns, []byte(test.body))
+			file, err := Read(test.format, append(append([]byte{}, data...), "next"...))
+			if err != nil {
+				t.Fatalf("reading: %v", err)
+			}
+			if file.Format != test.format || !reflect.DeepEqual(file.Unknowns, test.unknowns) {
+				t.Fatalf("read the header %v %X, not %X", file.Format, file.Unknowns, test.unknowns)
+			}
+			if file.FileSize != uint32(len(data)) || string(file.Data) != test.body {
+				t.Fatalf("read 0x%X bytes of data %q, the file is 0x%X bytes", file.FileSize, file.Data, len(data))
+			}
+
+			written, err := Write(file)
+			if err != nil || !bytes.Equal(written, data) {
+				t.Fatalf("writing gave %v, % X became % X", err, data, written)
+			}
+
+			//The format is registered under its extension and inspects to the header values.
+			registered := formats.ByExtension(test.format)
+			if len(registered) != 1 || registered[0].Name() != test.format {
+				t.Fatalf("the formats of .%v are %v", test.format, registered)
+			}
+			inspection, err := registered[0].(formats.Inspector).Inspect(file)
+			if err != nil || len(inspection.Sections[0].Fields) != len(test.unknowns)+1 || inspection.Sections[1].Fields[0].Value != formats.Hex(len(test.body)) {
+				t.Fatalf("inspecting gave %v, %+v", err, inspection)
+			}
+
+			file.Unknowns = file.Unknowns[1:]
+			if _, err := Write(file); !errors.Is(err, formats.ErrInvalid) {
+				t.Fatalf("writing %v unknown values has to fail, it gave %v", len(file.Unknowns), err)
+			}
+		})
+	}
+
+	//With three unknowns the fourth value of a GSL header is read as the file size.
+	if _, err := Read("gms", testgen.HV([]uint32{1, 2, 3, 4, 5}, nil)); !errors.Is(err, formats.ErrInvalid) {
+		t.Fatalf("reading a gsl header as gms has to fail, it gave %v", err)
+	}
+	if _, err := Read("tpl", testgen.HV([]uint32{1, 2, 3}, nil)); !formats.Unsupported(err) {
+		t.Fatalf("reading a format that isn't High Voltage has to be unsupported, it gave %v", err)
+	}
+}
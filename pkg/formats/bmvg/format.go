@@ -0,0 +1,57 @@
+package bmvg
+
+import (
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+func init() {
+	//GMD and GKA have packages of their own that register them.
+	for _, name := range []string{"ggg", "gms", "gsl"} {
+		formats.Register(format{name})
+	}
+}
+
+/*
+format ...
+One of the High Voltage formats whose body isn't known yet in the formats registry, files are
+*File with the raw body. Nothing decodes them. It is a formats.Inspector.
+*/
+type format struct {
+	name string
+}
+
+func (hv format) Name() string         { return hv.name }
+func (hv format) Extensions() []string { return []string{"." + hv.name} }
+
+func (format) Probe(data []byte) bool {
+	//The High Voltage formats have no magic and all share one header, only the extension tells them apart.
+	return false
+}
+
+func (hv format) Read(data []byte) (interface{}, error) {
+	return Read(hv.name, data)
+}
+
+func (hv format) Write(file interface{}) ([]byte, error) {
+	hvFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType(hv.name, "*bmvg.File", file)
+	}
+	return Write(hvFile)
+}
+
+func (format) Decode(file interface{}) (interface{}, error) {
+	return nil, formats.ErrNotSupported
+}
+
+func (format) Encode(work interface{}) (interface{}, error) {
+	return nil, formats.ErrNotSupported
+}
+
+func (hv format) Inspect(file interface{}) (*formats.Inspection, error) {
+	hvFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType(hv.name, "*bmvg.File", file)
+	}
+	return Inspect(hvFile), nil
+}
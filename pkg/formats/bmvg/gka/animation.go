@@ -0,0 +1,215 @@
+package gka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+	"github.com/ProfElements/go-files/pkg/formats/bmvg"
+	"github.com/ProfElements/go-files/pkg/formats/khronos/gltf"
+)
+
+type Path uint8
+
+const (
+	Translation Path = 0x00
+	Rotation    Path = 0x01
+	Scale       Path = 0x02
+)
+
+type Interpolation uint8
+
+const (
+	Linear      Interpolation = 0x00
+	Step        Interpolation = 0x01
+	CubicSpline Interpolation = 0x02
+)
+
+/*
+Keyframe ...
+One key of a track. Translation and scale use the first three components of Value, rotation
+is a quaternion in x, y, z, w order. The tangents are only used by CubicSpline tracks.
+*/
+type Keyframe struct {
+	Time       float32
+	Value      [4]float32
+	InTangent  [4]float32
+	OutTangent [4]float32
+}
+
+/*
+Track ...
+The keyframes animating one property of one bone, Bone is the bone name of the model skeleton.
+*/
+type Track struct {
+	Bone          string
+	Path          Path
+	Interpolation Interpolation
+	Keyframes     []Keyframe
+}
+
+type Animation struct {
+	Name   string
+	Tracks []Track
+}
+
+/*
+Decode ...
+Decode the bone tracks of a GKA file with their keyframes, or error
+*/
+func Decode(data *File) (*Animation, error) {
+	animation := &Animation{Name: strings.TrimRight(data.Sections.Name, "\x00")}
+
+	for i, entry := range data.Tracks {
+		entryOffset := int(data.Sections.TrackOffset) + i*0x30
+
+		if _, _, ok := trackPath(Path(entry.Path)); !ok {
+			return nil, &formats.FormatError{Format: "gka", Offset: entryOffset + 0x20, Field: fmt.Sprintf("track %v path", i),
+				Err: fmt.Errorf("%w, path %v isn't known", formats.ErrUnsupportedFormat, entry.Path)}
+		}
+		if Interpolation(entry.Interpolation) > CubicSpline {
+			return nil, &formats.FormatError{Format: "gka", Offset: entryOffset + 0x21, Field: fmt.Sprintf("track %v interpolation", i),
+				Err: fmt.Errorf("%w, interpolation %v isn't known", formats.ErrUnsupportedFormat, entry.Interpolation)}
+		}
+
+		//Cubic spline keys have an in and an out tangent after their value.
+		stride := 0x14
+		if Interpolation(entry.Interpolation) == CubicSpline {
+			stride = 0x34
+		}
+
+		//Tracks may share keys, only the track table can't hold them.
+		keySpan := bmvg.Span{Field: fmt.Sprintf("track %v keys", i), Offset: entry.KeyOffset, Count: entry.KeyCount, Size: uint32(stride)}
+		err := bmvg.CheckSpans("gka", sectionsEnd, uint32(headerSize+len(data.Data)), data.trackTable(), keySpan)
+		if err != nil {
+			return nil, err
+		}
+		var keys []byte
+		if entry.KeyCount != 0 {
+			keys = data.Data[entry.KeyOffset-headerSize : uint64(entry.KeyOffset-headerSize)+uint64(entry.KeyCount)*uint64(stride)]
+		}
+
+		track := Track{
+			Bone:          strings.TrimRight(entry.Bone, "\x00"),
+			Path:          Path(entry.Path),
+			Interpolation: Interpolation(entry.Interpolation),
+			Keyframes:     make([]Keyframe, entry.KeyCount),
+		}
+		for k := range track.Keyframes {
+			key := keys[k*stride : (k+1)*stride]
+			keyframe := Keyframe{Time: float(key, 0), Value: vector(key[4:])}
+			if stride == 0x34 {
+				keyframe.InTangent = vector(key[0x14:])
+				keyframe.OutTangent = vector(key[0x24:])
+			}
+			track.Keyframes[k] = keyframe
+		}
+
+		animation.Tracks = append(animation.Tracks, track)
+	}
+
+	return animation, nil
+}
+
+func float(data []byte, offset int) float32 {
+	return math.Float32frombits(binary.BigEndian.Uint32(data[offset:]))
+}
+
+func vector(data []byte) [4]float32 {
+	return [4]float32{float(data, 0), float(data, 4), float(data, 8), float(data, 12)}
+}
+
+/*
+AttachGLTF ...
+Add an animation to a glTF document exported from the matching model. Tracks find their bone
+by node name, an error is returned when a bone is missing so mismatched skeletons are noticed.
+*/
+func AttachGLTF(doc *gltf.Document, animation *Animation) error {
+	gltfAnimation := gltf.Animation{Name: animation.Name}
+
+	//Check every bone first, so a mismatched skeleton leaves the document untouched.
+	for _, track := range animation.Tracks {
+		if _, ok := doc.FindNode(track.Bone); !ok {
+			return fmt.Errorf("animation %v animates bone %v, which the model doesn't have", animation.Name, track.Bone)
+		}
+	}
+
+	for _, track := range animation.Tracks {
+		node, _ := doc.FindNode(track.Bone)
+
+		path, components, ok := trackPath(track.Path)
+		if !ok {
+			return fmt.Errorf("bone %v has a track with unknown path %v", track.Bone, track.Path)
+		}
+
+		interpolation, ok := map[Interpolation]string{
+			Linear:      gltf.InterpolationLinear,
+			Step:        gltf.InterpolationStep,
+			CubicSpline: gltf.InterpolationCubicSpline,
+		}[track.Interpolation]
+		if !ok {
+			return fmt.Errorf("bone %v has a track with unknown interpolation %v", track.Bone, track.Interpolation)
+		}
+
+		times := make([]float32, len(track.Keyframes))
+		var values []float32
+		for i, keyframe := range track.Keyframes {
+			if i > 0 && keyframe.Time <= times[i-1] {
+				return fmt.Errorf("keyframe %v of bone %v isn't after the one before it", i, track.Bone)
+			}
+			times[i] = keyframe.Time
+
+			//Cubic spline outputs hold in tangent, value and out tangent for every key.
+			if track.Interpolation == CubicSpline {
+				values = append(values, keyframe.InTangent[:components]...)
+				values = append(values, keyframe.Value[:components]...)
+				values = append(values, keyframe.OutTangent[:components]...)
+			} else {
+				values = append(values, keyframe.Value[:components]...)
+			}
+		}
+
+		input, err := doc.AddFloatAccessor(times, 1, 0)
+		if err != nil {
+			return err
+		}
+		output, err := doc.AddFloatAccessor(values, components, 0)
+		if err != nil {
+			return err
+		}
+
+		gltfAnimation.Samplers = append(gltfAnimation.Samplers, gltf.AnimationSampler{
+			Input:         input,
+			Interpolation: interpolation,
+			Output:        output,
+		})
+
+		target := node
+		gltfAnimation.Channels = append(gltfAnimation.Channels, gltf.AnimationChannel{
+			Sampler: len(gltfAnimation.Samplers) - 1,
+			Target:  gltf.AnimationTarget{Node: &target, Path: path},
+		})
+	}
+
+	if len(gltfAnimation.Channels) == 0 {
+		return fmt.Errorf("animation %v has no tracks", animation.Name)
+	}
+
+	doc.Animations = append(doc.Animations, gltfAnimation)
+
+	return nil
+}
+
+func trackPath(path Path) (string, int, bool) {
+	switch path {
+	case Translation:
+		return gltf.PathTranslation, 3, true
+	case Rotation:
+		return gltf.PathRotation, 4, true
+	case Scale:
+		return gltf.PathScale, 3, true
+	}
+	return "", 0, false
+}
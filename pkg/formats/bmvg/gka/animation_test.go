@@ -0,0 +1,131 @@
+package gka
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+	"github.com/ProfElements/go-files/pkg/formats"
+	"github.com/ProfElements/go-files/pkg/formats/bmvg/gmd"
+)
+
+func decode(t *testing.T, data []byte) (*Animation, error) {
+	t.Helper()
+
+	file, err := Read(data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	return Decode(file)
+}
+
+func TestDecode(t *testing.T) {
+	animation, err := decode(t, testgen.GKAOf(testAnimation))
+	if err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+
+	want := &Animation{Name: "wave", Tracks: []Track{
+		{Bone: "arm", Path: Rotation, Interpolation: Linear, Keyframes: []Keyframe{
+			{Time: 0, Value: [4]float32{0, 0, 0, 1}},
+			{Time: 0.5, Value: [4]float32{0, 0, 0.70710677, 0.70710677}},
+		}},
+		{Bone: "root", Path: Translation, Interpolation: Step, Keyframes: []Keyframe{
+			{Time: 0, Value: [4]float32{0, 1, 0}},
+			{Time: 1, Value: [4]float32{0, 2, 0}},
+		}},
+		{Bone: "arm", Path: Scale, Interpolation: CubicSpline, Keyframes: []Keyframe{
+			{Time: 0, Value: [4]float32{1, 1, 1}, OutTangent: [4]float32{0.5, 0, 0}},
+			{Time: 1, Value: [4]float32{2, 1, 1}, InTangent: [4]float32{0.5, 0, 0}},
+		}},
+	}}
+	if !reflect.DeepEqual(animation, want) {
+		t.Fatalf("decoded %+v, it has to be %+v", animation, want)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	//The first track entry is at 0x38, its keys at 0xC8.
+	tests := []struct {
+		name   string
+		patch  func(data []byte)
+		offset int
+		field  string
+		err    error
+	}{
+		{"path", func(data []byte) { data[0x38+0x20] = 3 }, 0x38 + 0x20, "track 0 path", formats.ErrUnsupportedFormat},
+		{"interpolation", func(data []byte) { data[0x38+0x21] = 3 }, 0x38 + 0x21, "track 0 interpolation", formats.ErrUnsupportedFormat},
+		{"keys", func(data []byte) { binary.BigEndian.PutUint32(data[0x38+0x24:], 0x1000) }, 0xC8, "track 0 keys", formats.ErrTruncated},
+		{"keys in the header", func(data []byte) { binary.BigEndian.PutUint32(data[0x38+0x28:], 0x04) }, 0x04, "track 0 keys", formats.ErrInvalid},
+		{"keys over the track table", func(data []byte) { binary.BigEndian.PutUint32(data[0x38+0x28:], 0x38+0x30) }, 0x38 + 0x30, "track 0 keys", formats.ErrInvalid},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := testgen.GKAOf(testAnimation)
+			test.patch(data)
+
+			_, err := decode(t, data)
+			var formatErr *formats.FormatError
+			if !errors.As(err, &formatErr) || formatErr.Offset != test.offset || formatErr.Field != test.field || !errors.Is(err, test.err) {
+				t.Fatalf("decoding gave %v, it has to be %v at 0x%X in %v", err, test.err, test.offset, test.field)
+			}
+		})
+	}
+}
+
+func TestAttachGLTF(t *testing.T) {
+	animation, err := decode(t, testgen.GKAOf(testAnimation))
+	if err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+
+	model := &gmd.Model{Bones: []gmd.Bone{
+		{Name: "root", Parent: -1, Rotation: [4]float32{0, 0, 0, 1}, Scale: [3]float32{1, 1, 1}},
+		{Name: "arm", Parent: 0, Rotation: [4]float32{0, 0, 0, 1}, Scale: [3]float32{1, 1, 1}},
+	}}
+	doc, err := gmd.ExportGLTF(model, nil)
+	if err != nil {
+		t.Fatalf("exporting the model: %v", err)
+	}
+
+	err = AttachGLTF(doc, animation)
+	if err != nil {
+		t.Fatalf("attaching: %v", err)
+	}
+	if len(doc.Animations) != 1 || doc.Animations[0].Name != "wave" || len(doc.Animations[0].Channels) != 3 {
+		t.Fatalf("attached %+v", doc.Animations)
+	}
+
+	//Every channel targets the bone node of its track, cubic spline outputs hold three values a key.
+	wants := []struct {
+		node          int
+		path          string
+		interpolation string
+		outputs       int
+		outputType    string
+	}{
+		{1, "rotation", "LINEAR", 2, "VEC4"},
+		{0, "translation", "STEP", 2, "VEC3"},
+		{1, "scale", "CUBICSPLINE", 6, "VEC3"},
+	}
+	gltfAnimation := doc.Animations[0]
+	for i, want := range wants {
+		channel := gltfAnimation.Channels[i]
+		sampler := gltfAnimation.Samplers[channel.Sampler]
+		input, output := doc.Accessors[sampler.Input], doc.Accessors[sampler.Output]
+		if *channel.Target.Node != want.node || channel.Target.Path != want.path || sampler.Interpolation != want.interpolation ||
+			input.Count != 2 || output.Count != want.outputs || output.Type != want.outputType {
+			t.Fatalf("channel %v targets %+v with sampler %+v, input %+v and output %+v", i, channel.Target, sampler, input, output)
+		}
+	}
+
+	//An animation of a bone the model doesn't have leaves the document as it is.
+	other := &Animation{Name: "other", Tracks: []Track{animation.Tracks[0], {Bone: "leg", Path: Rotation, Keyframes: animation.Tracks[0].Keyframes}}}
+	accessors := len(doc.Accessors)
+	if err := AttachGLTF(doc, other); err == nil || len(doc.Animations) != 1 || len(doc.Accessors) != accessors {
+		t.Fatalf("attaching to a model without the bone gave %v and %v animations", err, len(doc.Animations))
+	}
+}
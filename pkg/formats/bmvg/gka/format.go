@@ -0,0 +1,56 @@
+package gka
+
+import (
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+func init() {
+	formats.Register(format{})
+}
+
+/*
+format ...
+GKA in the formats registry, files are *File and works are *Animation.
+It is a formats.Inspector.
+*/
+type format struct{}
+
+func (format) Name() string         { return "gka" }
+func (format) Extensions() []string { return []string{".gka"} }
+
+func (format) Probe(data []byte) bool {
+	//GKA has no magic and its header is the one every High Voltage format has, only the extension tells them apart.
+	return false
+}
+
+func (format) Read(data []byte) (interface{}, error) {
+	return Read(data)
+}
+
+func (format) Write(file interface{}) ([]byte, error) {
+	gkaFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("gka", "*gka.File", file)
+	}
+	return Write(gkaFile)
+}
+
+func (format) Decode(file interface{}) (interface{}, error) {
+	gkaFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("gka", "*gka.File", file)
+	}
+	return Decode(gkaFile)
+}
+
+func (format) Encode(work interface{}) (interface{}, error) {
+	return nil, formats.ErrNotSupported
+}
+
+func (format) Inspect(file interface{}) (*formats.Inspection, error) {
+	gkaFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("gka", "*gka.File", file)
+	}
+	return Inspect(gkaFile), nil
+}
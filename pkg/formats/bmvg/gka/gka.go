@@ -0,0 +1,162 @@
+package gka
+
+/*
+Name: GKA
+Extension: .gka
+Description: The High Voltage animation format stored in .jam archives. It starts with the header
+             all High Voltage files share, see the bmvg package, the body holds the name of the
+             animation and a table of bone tracks. This layout is provisional, it hasn't been
+             checked against animations from the games yet. A file whose track table or keys
+             start inside the sections, run past the file size or overlap the track table fails
+             to read with a formats error.
+BINARY STRUCTURE:
+
+             Unknown1;        uint32, meaning unknown
+             Unknown2;        uint32, meaning unknown
+             Unknown3;        uint32, meaning unknown
+             FileSize;        uint32, size of the whole file including this header
+             Name;            32 bytes, zero padded
+             TrackCount;      uint32
+             TrackOffset;     uint32, from the start of the file
+
+             TRACK: (0x30 bytes)
+             Bone;            32 bytes, name of the animated bone, zero padded
+             Path;            uint8, 0 translation, 1 rotation, 2 scale
+             Interpolation;   uint8, 0 linear, 1 step, 2 cubic spline
+             padding;         2 bytes
+             KeyCount;        uint32
+             KeyOffset;       uint32, from the start of the file
+             padding;         4 bytes
+
+             KEY: (0x14 bytes, 0x34 in cubic spline tracks)
+             Time;            float32, seconds
+             Value;           4 float32
+             InTangent;       4 float32, cubic spline tracks only
+             OutTangent;      4 float32, cubic spline tracks only
+*/
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ProfElements/go-files/internal/binstruct"
+	"github.com/ProfElements/go-files/pkg/formats/bmvg"
+)
+
+const headerSize = 0x10
+
+/*
+sectionsEnd ...
+The track table and the keys start after the header and the sections
+*/
+const sectionsEnd = headerSize + 0x28
+
+type Header struct {
+	Unknown1 uint32
+	Unknown2 uint32
+	Unknown3 uint32
+	FileSize uint32
+}
+
+/*
+Sections ...
+The name of the animation and where its track table is, right after the header
+*/
+type Sections struct {
+	Name        string `bin:"size=32"`
+	TrackCount  uint32
+	TrackOffset uint32
+}
+
+type TrackEntry struct {
+	Bone          string `bin:"size=32"`
+	Path          uint8
+	Interpolation uint8
+	_             [2]byte
+	KeyCount      uint32
+	KeyOffset     uint32
+	_             [4]byte
+}
+
+/*
+File ...
+A read GKA file. Data is the whole body after the header, tables and keys included, the
+entries are read from it. Write writes Data back with the entries over their place in it.
+*/
+type File struct {
+	Header   Header
+	Sections Sections
+	Tracks   []TrackEntry
+	Data     []byte
+}
+
+/*
+Read ...
+Read a .gka file from data and return a File structure pointer, or error
+*/
+func Read(data []byte) (*File, error) {
+	values, fileSize, body, err := bmvg.ReadHeader("gka", data, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{
+		Header: Header{values[0], values[1], values[2], fileSize},
+		Data:   body,
+	}
+	//Offsets count from the start of the file, the tables are read from all of it.
+	raw := data[:fileSize]
+
+	err = binstruct.Read(raw, headerSize, binary.BigEndian, &file.Sections)
+	if err != nil {
+		return nil, binstruct.Wrap(err, "gka", "sections")
+	}
+
+	err = bmvg.CheckSpans("gka", sectionsEnd, fileSize, file.trackTable())
+	if err != nil {
+		return nil, err
+	}
+
+	file.Tracks = make([]TrackEntry, file.Sections.TrackCount)
+	for i := range file.Tracks {
+		err = binstruct.Read(raw, int(file.Sections.TrackOffset)+i*0x30, binary.BigEndian, &file.Tracks[i])
+		if err != nil {
+			return nil, binstruct.Wrap(err, "gka", fmt.Sprintf("track %v", i))
+		}
+	}
+
+	return file, nil
+}
+
+/*
+trackTable ...
+The track table the sections point at
+*/
+func (data *File) trackTable() bmvg.Span {
+	return bmvg.Span{Field: "track table", Offset: data.Sections.TrackOffset, Count: data.Sections.TrackCount, Size: 0x30}
+}
+
+/*
+Write ...
+Write a File structure to a .gka file, or error
+*/
+func Write(data *File) ([]byte, error) {
+	if len(data.Tracks) != int(data.Sections.TrackCount) {
+		return nil, fmt.Errorf("the gka has %v tracks, its sections count %v", len(data.Tracks), data.Sections.TrackCount)
+	}
+
+	out := bmvg.WriteHeader([]uint32{data.Header.Unknown1, data.Header.Unknown2, data.Header.Unknown3}, data.Data)
+
+	err := binstruct.Write(out, headerSize, binary.BigEndian, data.Sections)
+	if err != nil {
+		return nil, binstruct.Wrap(err, "gka", "sections")
+	}
+	for i, entry := range data.Tracks {
+		err = binstruct.Write(out, int(data.Sections.TrackOffset)+i*0x30, binary.BigEndian, entry)
+		if err != nil {
+			return nil, binstruct.Wrap(err, "gka", fmt.Sprintf("track %v", i))
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,131 @@
+package gka
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+testAnimation ...
+Swing the arm of the gmd test model and move its root in steps
+*/
+var testAnimation = testgen.GKAAnimation{
+	Name: "wave",
+	Tracks: []testgen.GKATrack{
+		{Bone: "arm", Path: 1, Interpolation: 0, Keys: []testgen.GKAKey{
+			{Time: 0, Value: [4]float32{0, 0, 0, 1}},
+			{Time: 0.5, Value: [4]float32{0, 0, 0.70710677, 0.70710677}},
+		}},
+		{Bone: "root", Path: 0, Interpolation: 1, Keys: []testgen.GKAKey{
+			{Time: 0, Value: [4]float32{0, 1, 0}},
+			{Time: 1, Value: [4]float32{0, 2, 0}},
+		}},
+		{Bone: "arm", Path: 2, Interpolation: 2, Keys: []testgen.GKAKey{
+			{Time: 0, Value: [4]float32{1, 1, 1}, OutTangent: [4]float32{0.5, 0, 0}},
+			{Time: 1, Value: [4]float32{2, 1, 1}, InTangent: [4]float32{0.5, 0, 0}},
+		}},
+	},
+}
+
+func FuzzRead(f *testing.F) {
+	f.Add(testgen.GKAOf(testAnimation))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := Read(data)
+		if err != nil {
+			return
+		}
+		Write(file)
+		Decode(file)
+	})
+}
+
+func TestReadWrite(t *testing.T) {
+	data := testgen.GKAOf(testAnimation)
+
+	file, err := Read(data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	if file.Header != (Header{1, 2, 3, uint32(len(data))}) {
+		t.Fatalf("read header %+v", file.Header)
+	}
+	if strings.TrimRight(file.Sections.Name, "\x00") != "wave" || file.Sections.TrackCount != 3 || file.Sections.TrackOffset != 0x38 {
+		t.Fatalf("read sections %+v", file.Sections)
+	}
+
+	//Linear and step keys are 0x14 bytes, cubic spline keys 0x34.
+	want := []TrackEntry{
+		{Bone: "arm", Path: 1, Interpolation: 0, KeyCount: 2, KeyOffset: 0xC8},
+		{Bone: "root", Path: 0, Interpolation: 1, KeyCount: 2, KeyOffset: 0xF0},
+		{Bone: "arm", Path: 2, Interpolation: 2, KeyCount: 2, KeyOffset: 0x118},
+	}
+	for i, track := range file.Tracks {
+		track.Bone = strings.TrimRight(track.Bone, "\x00")
+		if track != want[i] {
+			t.Fatalf("read track %v as %+v, it has to be %+v", i, track, want[i])
+		}
+	}
+
+	written, err := Write(file)
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	if !bytes.Equal(written, data) {
+		t.Fatalf("writing changed the file")
+	}
+}
+
+/*
+TestReadBadTables ...
+The sections of the test animation are at 0x10: the name, the track count at 0x30 and the
+track offset at 0x34. The track table follows them at 0x38.
+*/
+func TestReadBadTables(t *testing.T) {
+	tests := []struct {
+		name   string
+		patch  func(data []byte)
+		offset int
+		err    error
+	}{
+		{"more tracks than fit", func(data []byte) { data[0x30] = 0x40 }, 0x38, formats.ErrTruncated},
+		{"table inside the sections", func(data []byte) { data[0x37] = 0x30 }, 0x30, formats.ErrInvalid},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := testgen.GKAOf(testAnimation)
+			test.patch(data)
+
+			_, err := Read(data)
+			var formatErr *formats.FormatError
+			if !errors.As(err, &formatErr) || formatErr.Offset != test.offset || formatErr.Field != "track table" || !errors.Is(err, test.err) {
+				t.Fatalf("reading gave %v, it has to be %v at 0x%X in the track table", err, test.err, test.offset)
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	file, err := Read(testgen.GKAOf(testAnimation))
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+
+	inspection, err := formats.ByExtension(".gka")[0].(formats.Inspector).Inspect(file)
+	if err != nil {
+		t.Fatalf("inspecting: %v", err)
+	}
+	if len(inspection.Sections) != 3 || inspection.Sections[1].Fields[0].Value != "wave" {
+		t.Fatalf("inspected %+v", inspection.Sections)
+	}
+	tracks := inspection.Sections[2].Rows
+	if len(tracks) != 3 || tracks[0][1] != "arm" || tracks[0][2] != "rotation (0x1)" || tracks[2][3] != "cubic spline (0x2)" || tracks[1][4] != uint32(2) {
+		t.Fatalf("inspected tracks %v", tracks)
+	}
+}
@@ -0,0 +1,52 @@
+package gka
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+var (
+	pathNames          = []string{"translation", "rotation", "scale"}
+	interpolationNames = []string{"linear", "step", "cubic spline"}
+)
+
+/*
+Inspect ...
+Show the header of a GKA file, the unknown values included, the name of the animation, where
+its track table is and every track entry of it
+*/
+func Inspect(data *File) *formats.Inspection {
+	inspection := formats.NewInspection("gka")
+
+	inspection.Section("header").
+		Field("unknown 1", formats.Hex(data.Header.Unknown1)).
+		Field("unknown 2", formats.Hex(data.Header.Unknown2)).
+		Field("unknown 3", formats.Hex(data.Header.Unknown3)).
+		Field("file size", formats.Hex(data.Header.FileSize))
+
+	inspection.Section("sections").
+		Field("name", strings.TrimRight(data.Sections.Name, "\x00")).
+		Field("tracks", data.Sections.TrackCount).
+		Field("track offset", formats.Hex(data.Sections.TrackOffset))
+
+	tracks := inspection.Table("tracks", "track", "bone", "path", "interpolation", "keys", "key offset")
+	for idx, entry := range data.Tracks {
+		tracks.Row(idx, strings.TrimRight(entry.Bone, "\x00"), named(pathNames, entry.Path), named(interpolationNames, entry.Interpolation),
+			entry.KeyCount, formats.Hex(entry.KeyOffset))
+	}
+
+	return inspection
+}
+
+/*
+named ...
+A value as its name from names and the raw value, or only the raw value when it has no name
+*/
+func named(names []string, value uint8) string {
+	if int(value) >= len(names) {
+		return fmt.Sprintf("0x%X", value)
+	}
+	return fmt.Sprintf("%v (0x%X)", names[value], value)
+}
@@ -0,0 +1,56 @@
+package gmd
+
+import (
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+func init() {
+	formats.Register(format{})
+}
+
+/*
+format ...
+GMD in the formats registry, files are *File and works are *Model.
+It is a formats.Inspector.
+*/
+type format struct{}
+
+func (format) Name() string         { return "gmd" }
+func (format) Extensions() []string { return []string{".gmd"} }
+
+func (format) Probe(data []byte) bool {
+	//GMD has no magic and its header is the one every High Voltage format has, only the extension tells them apart.
+	return false
+}
+
+func (format) Read(data []byte) (interface{}, error) {
+	return Read(data)
+}
+
+func (format) Write(file interface{}) ([]byte, error) {
+	gmdFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("gmd", "*gmd.File", file)
+	}
+	return Write(gmdFile)
+}
+
+func (format) Decode(file interface{}) (interface{}, error) {
+	gmdFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("gmd", "*gmd.File", file)
+	}
+	return Decode(gmdFile)
+}
+
+func (format) Encode(work interface{}) (interface{}, error) {
+	return nil, formats.ErrNotSupported
+}
+
+func (format) Inspect(file interface{}) (*formats.Inspection, error) {
+	gmdFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("gmd", "*gmd.File", file)
+	}
+	return Inspect(gmdFile), nil
+}
@@ -0,0 +1,242 @@
+package gmd
+
+/*
+Name: GMD
+Extension: .gmd
+Description: The High Voltage model format stored in .jam archives. It starts with the header all
+             High Voltage files share, see the bmvg package, the body holds three tables. This
+             layout is provisional, it hasn't been checked against models from the games yet.
+             A file whose tables or vertex data start inside the sections, run past the file
+             size or overlap a table fails to read with a formats error.
+BINARY STRUCTURE:
+
+             Unknown1;        uint32, meaning unknown
+             Unknown2;        uint32, meaning unknown
+             Unknown3;        uint32, meaning unknown
+             FileSize;        uint32, size of the whole file including this header
+             MaterialCount;   uint32
+             MaterialOffset;  uint32, from the start of the file
+             BoneCount;       uint32
+             BoneOffset;      uint32, from the start of the file
+             MeshCount;       uint32
+             MeshOffset;      uint32, from the start of the file
+
+             MATERIAL: (0x40 bytes)
+             Name;            32 bytes, zero padded
+             Texture;         32 bytes, name of the TPL member in the same archive
+
+             BONE: (0x50 bytes)
+             Name;            32 bytes, zero padded
+             Parent;          int32, index of the parent bone, -1 for root bones
+             Translation;     3 float32
+             Rotation;        4 float32, quaternion in x, y, z, w order
+             Scale;           3 float32
+             padding;         4 bytes
+
+             MESH: (0x40 bytes)
+             Name;            32 bytes, zero padded
+             Material;        int32, index of the material, -1 for none
+             Flags;           uint32, which vertex attributes follow the position
+             VertexCount;     uint32
+             VertexOffset;    uint32, from the start of the file
+             IndexCount;      uint32
+             IndexOffset;     uint32, from the start of the file, uint16 indices
+             padding;         8 bytes
+
+             VERTEX:
+             Position;        3 float32
+             Normal;          3 float32, with MeshNormals
+             UV;              2 float32, with MeshUVs
+             Joints;          4 uint8, bone indices, with MeshSkin
+             Weights;         4 float32, with MeshSkin
+*/
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/ProfElements/go-files/internal/binstruct"
+	"github.com/ProfElements/go-files/pkg/formats/bmvg"
+)
+
+const headerSize = 0x10
+
+/*
+sectionsEnd ...
+The tables and the vertex data start after the header and the sections
+*/
+const sectionsEnd = headerSize + 0x18
+
+const (
+	MeshNormals uint32 = 0x01
+	MeshUVs     uint32 = 0x02
+	MeshSkin    uint32 = 0x04
+)
+
+type Header struct {
+	Unknown1 uint32
+	Unknown2 uint32
+	Unknown3 uint32
+	FileSize uint32
+}
+
+/*
+Sections ...
+Where the tables of the body are, right after the header
+*/
+type Sections struct {
+	MaterialCount  uint32
+	MaterialOffset uint32
+	BoneCount      uint32
+	BoneOffset     uint32
+	MeshCount      uint32
+	MeshOffset     uint32
+}
+
+type MaterialEntry struct {
+	Name    string `bin:"size=32"`
+	Texture string `bin:"size=32"`
+}
+
+type BoneEntry struct {
+	Name        string `bin:"size=32"`
+	Parent      int32
+	Translation [3]float32
+	Rotation    [4]float32
+	Scale       [3]float32
+	_           [4]byte
+}
+
+type MeshEntry struct {
+	Name         string `bin:"size=32"`
+	Material     int32
+	Flags        uint32
+	VertexCount  uint32
+	VertexOffset uint32
+	IndexCount   uint32
+	IndexOffset  uint32
+	_            [8]byte
+}
+
+/*
+File ...
+A read GMD file. Data is the whole body after the header, tables and vertex data included, the
+entries are read from it. Write writes Data back with the entries over their place in it.
+*/
+type File struct {
+	Header    Header
+	Sections  Sections
+	Materials []MaterialEntry
+	Bones     []BoneEntry
+	Meshes    []MeshEntry
+	Data      []byte
+}
+
+/*
+Read ...
+Read a .gmd file from data and return a File structure pointer, or error
+*/
+func Read(data []byte) (*File, error) {
+	values, fileSize, body, err := bmvg.ReadHeader("gmd", data, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{
+		Header: Header{values[0], values[1], values[2], fileSize},
+		Data:   body,
+	}
+	//Offsets count from the start of the file, the tables are read from all of it.
+	raw := data[:fileSize]
+
+	err = binstruct.Read(raw, headerSize, binary.BigEndian, &file.Sections)
+	if err != nil {
+		return nil, binstruct.Wrap(err, "gmd", "sections")
+	}
+
+	err = bmvg.CheckSpans("gmd", sectionsEnd, fileSize, file.tables()...)
+	if err != nil {
+		return nil, err
+	}
+
+	file.Materials = make([]MaterialEntry, file.Sections.MaterialCount)
+	for i := range file.Materials {
+		err = binstruct.Read(raw, int(file.Sections.MaterialOffset)+i*0x40, binary.BigEndian, &file.Materials[i])
+		if err != nil {
+			return nil, binstruct.Wrap(err, "gmd", fmt.Sprintf("material %v", i))
+		}
+	}
+
+	file.Bones = make([]BoneEntry, file.Sections.BoneCount)
+	for i := range file.Bones {
+		err = binstruct.Read(raw, int(file.Sections.BoneOffset)+i*0x50, binary.BigEndian, &file.Bones[i])
+		if err != nil {
+			return nil, binstruct.Wrap(err, "gmd", fmt.Sprintf("bone %v", i))
+		}
+	}
+
+	file.Meshes = make([]MeshEntry, file.Sections.MeshCount)
+	for i := range file.Meshes {
+		err = binstruct.Read(raw, int(file.Sections.MeshOffset)+i*0x40, binary.BigEndian, &file.Meshes[i])
+		if err != nil {
+			return nil, binstruct.Wrap(err, "gmd", fmt.Sprintf("mesh %v", i))
+		}
+	}
+
+	return file, nil
+}
+
+/*
+tables ...
+The material, bone and mesh tables the sections point at
+*/
+func (data *File) tables() []bmvg.Span {
+	return []bmvg.Span{
+		{Field: "material table", Offset: data.Sections.MaterialOffset, Count: data.Sections.MaterialCount, Size: 0x40},
+		{Field: "bone table", Offset: data.Sections.BoneOffset, Count: data.Sections.BoneCount, Size: 0x50},
+		{Field: "mesh table", Offset: data.Sections.MeshOffset, Count: data.Sections.MeshCount, Size: 0x40},
+	}
+}
+
+/*
+Write ...
+Write a File structure to a .gmd file, or error
+*/
+func Write(data *File) ([]byte, error) {
+	if len(data.Materials) != int(data.Sections.MaterialCount) || len(data.Bones) != int(data.Sections.BoneCount) || len(data.Meshes) != int(data.Sections.MeshCount) {
+		return nil, fmt.Errorf("the gmd has %v materials, %v bones and %v meshes, its sections count %v, %v and %v", len(data.Materials), len(data.Bones), len(data.Meshes),
+			data.Sections.MaterialCount, data.Sections.BoneCount, data.Sections.MeshCount)
+	}
+
+	out := bmvg.WriteHeader([]uint32{data.Header.Unknown1, data.Header.Unknown2, data.Header.Unknown3}, data.Data)
+
+	err := binstruct.Write(out, headerSize, binary.BigEndian, data.Sections)
+	if err != nil {
+		return nil, binstruct.Wrap(err, "gmd", "sections")
+	}
+	for i, entry := range data.Materials {
+		err = binstruct.Write(out, int(data.Sections.MaterialOffset)+i*0x40, binary.BigEndian, entry)
+		if err != nil {
+			return nil, binstruct.Wrap(err, "gmd", fmt.Sprintf("material %v", i))
+		}
+	}
+	for i, entry := range data.Bones {
+		err = binstruct.Write(out, int(data.Sections.BoneOffset)+i*0x50, binary.BigEndian, entry)
+		if err != nil {
+			return nil, binstruct.Wrap(err, "gmd", fmt.Sprintf("bone %v", i))
+		}
+	}
+	for i, entry := range data.Meshes {
+		err = binstruct.Write(out, int(data.Sections.MeshOffset)+i*0x40, binary.BigEndian, entry)
+		if err != nil {
+			return nil, binstruct.Wrap(err, "gmd", fmt.Sprintf("mesh %v", i))
+		}
+	}
+
+	return out, nil
+}
+
+func trimName(name string) string {
+	return strings.TrimRight(name, "\x00")
+}
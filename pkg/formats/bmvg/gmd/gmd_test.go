@@ -0,0 +1,166 @@
+package gmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+// testModel is a textured triangle skinned to a two bone arm, and an untextured quad.
+var testModel = testgen.GMDModel{
+	Materials: []testgen.GMDMaterial{
+		{Name: "skin", Texture: "BODY"},
+		{Name: "plain"},
+	},
+	Bones: []testgen.GMDBone{
+		{Name: "root", Parent: -1, Translation: [3]float32{0, 1, 0}, Rotation: [4]float32{0, 0, 0, 1}, Scale: [3]float32{1, 1, 1}},
+		{Name: "arm", Parent: 0, Translation: [3]float32{1, 0, 0}, Rotation: [4]float32{0, 0, 0.70710677, 0.70710677}, Scale: [3]float32{1, 1, 1}},
+	},
+	Meshes: []testgen.GMDMesh{
+		{
+			Name:      "body",
+			Material:  0,
+			Positions: [][3]float32{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+			Normals:   [][3]float32{{0, 0, 1}, {0, 0, 1}, {0, 0, 1}},
+			UVs:       [][2]float32{{0, 0}, {1, 0}, {0, 1}},
+			Joints:    [][4]uint8{{0, 0, 0, 0}, {1, 0, 0, 0}, {0, 1, 0, 0}},
+			Weights:   [][4]float32{{1, 0, 0, 0}, {1, 0, 0, 0}, {0.5, 0.5, 0, 0}},
+			Indices:   []uint16{0, 1, 2},
+		},
+		{
+			Name:      "floor",
+			Material:  -1,
+			Positions: [][3]float32{{-1, 0, -1}, {1, 0, -1}, {1, 0, 1}, {-1, 0, 1}},
+			Indices:   []uint16{0, 1, 2, 0, 2, 3},
+		},
+	},
+}
+
+func FuzzRead(f *testing.F) {
+	f.Add(testgen.GMDOf(testModel))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := Read(data)
+		if err != nil {
+			return
+		}
+		Write(file)
+		Decode(file)
+	})
+}
+
+func TestReadWrite(t *testing.T) {
+	data := testgen.GMDOf(testModel)
+
+	file, err := Read(data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	if file.Header != (Header{1, 2, 3, uint32(len(data))}) {
+		t.Fatalf("read header %+v", file.Header)
+	}
+	if file.Sections.MaterialCount != 2 || file.Sections.MaterialOffset != 0x28 || file.Sections.BoneCount != 2 || file.Sections.BoneOffset != 0xA8 ||
+		file.Sections.MeshCount != 2 || file.Sections.MeshOffset != 0x148 {
+		t.Fatalf("read sections %+v", file.Sections)
+	}
+	if trimName(file.Materials[0].Name) != "skin" || trimName(file.Materials[0].Texture) != "BODY" || trimName(file.Materials[1].Texture) != "" {
+		t.Fatalf("read materials %q", file.Materials)
+	}
+	arm := file.Bones[1]
+	if trimName(arm.Name) != "arm" || arm.Parent != 0 || arm.Translation != [3]float32{1, 0, 0} || arm.Rotation != testModel.Bones[1].Rotation || file.Bones[0].Parent != -1 {
+		t.Fatalf("read bones %+v", file.Bones)
+	}
+	//The body has positions, normals, uvs and skin: 12+12+8+4+16 bytes a vertex.
+	body, floor := file.Meshes[0], file.Meshes[1]
+	if trimName(body.Name) != "body" || body.Flags != MeshNormals|MeshUVs|MeshSkin || body.VertexCount != 3 || body.VertexOffset != 0x1C8 ||
+		body.IndexCount != 3 || body.IndexOffset != 0x1C8+3*52 {
+		t.Fatalf("read mesh %+v", body)
+	}
+	if floor.Material != -1 || floor.Flags != 0 || floor.VertexCount != 4 || floor.IndexCount != 6 {
+		t.Fatalf("read mesh %+v", floor)
+	}
+
+	written, err := Write(file)
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	if !bytes.Equal(written, data) {
+		t.Fatalf("writing changed the file")
+	}
+
+	//Entries are written over their place in the data.
+	file.Materials[1].Texture = "FLOOR"
+	written, err = Write(file)
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	reread, err := Read(written)
+	if err != nil || trimName(reread.Materials[1].Texture) != "FLOOR" {
+		t.Fatalf("the changed material was read back as %+v, %v", reread.Materials, err)
+	}
+}
+
+/*
+TestReadBadTables ...
+The sections of the test model are at 0x10: the material count and offset, the bone count and
+offset, the mesh count and offset. The tables follow them at 0x28, 0xA8 and 0x148.
+*/
+func TestReadBadTables(t *testing.T) {
+	tests := []struct {
+		name   string
+		patch  func(data []byte)
+		offset int
+		field  string
+		err    error
+	}{
+		{"more meshes than fit", func(data []byte) { data[0x20] = 0x40 }, 0x148, "mesh table", formats.ErrTruncated},
+		{"table inside the sections", func(data []byte) { data[0x17] = 0x20 }, 0x20, "material table", formats.ErrInvalid},
+		{"overlapping tables", func(data []byte) { data[0x1F] = 0x68 }, 0x68, "bone table", formats.ErrInvalid},
+		{"empty table anywhere", func(data []byte) { binary.BigEndian.PutUint32(data[0x10:], 0); data[0x17] = 0 }, 0, "", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := testgen.GMDOf(testModel)
+			test.patch(data)
+
+			_, err := Read(data)
+			if test.err == nil {
+				if err != nil {
+					t.Fatalf("reading: %v", err)
+				}
+				return
+			}
+			var formatErr *formats.FormatError
+			if !errors.As(err, &formatErr) || formatErr.Offset != test.offset || formatErr.Field != test.field || !errors.Is(err, test.err) {
+				t.Fatalf("reading gave %v, it has to be %v at 0x%X in %v", err, test.err, test.offset, test.field)
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	file, err := Read(testgen.GMDOf(testModel))
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+
+	inspection, err := formats.ByExtension(".gmd")[0].(formats.Inspector).Inspect(file)
+	if err != nil {
+		t.Fatalf("inspecting: %v", err)
+	}
+	if len(inspection.Sections) != 5 || inspection.Sections[1].Fields[1].Value != formats.Hex(0x28) {
+		t.Fatalf("inspected %+v", inspection.Sections)
+	}
+	materials, bones, meshes := inspection.Sections[2], inspection.Sections[3], inspection.Sections[4]
+	if len(materials.Rows) != 2 || materials.Rows[0][2] != "BODY" || len(bones.Rows) != 2 || bones.Rows[1][2] != int32(0) {
+		t.Fatalf("inspected materials %v and bones %v", materials.Rows, bones.Rows)
+	}
+	if len(meshes.Rows) != 2 || meshes.Rows[1][1] != "floor" || meshes.Rows[1][6] != uint32(6) {
+		t.Fatalf("inspected meshes %v", meshes.Rows)
+	}
+}
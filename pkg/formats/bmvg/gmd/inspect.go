@@ -0,0 +1,46 @@
+package gmd
+
+import (
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+Inspect ...
+Show the header of a GMD file, the unknown values included, where its tables are and every
+material, bone and mesh entry of them
+*/
+func Inspect(data *File) *formats.Inspection {
+	inspection := formats.NewInspection("gmd")
+
+	inspection.Section("header").
+		Field("unknown 1", formats.Hex(data.Header.Unknown1)).
+		Field("unknown 2", formats.Hex(data.Header.Unknown2)).
+		Field("unknown 3", formats.Hex(data.Header.Unknown3)).
+		Field("file size", formats.Hex(data.Header.FileSize))
+
+	inspection.Section("sections").
+		Field("materials", data.Sections.MaterialCount).
+		Field("material offset", formats.Hex(data.Sections.MaterialOffset)).
+		Field("bones", data.Sections.BoneCount).
+		Field("bone offset", formats.Hex(data.Sections.BoneOffset)).
+		Field("meshes", data.Sections.MeshCount).
+		Field("mesh offset", formats.Hex(data.Sections.MeshOffset))
+
+	materials := inspection.Table("materials", "material", "name", "texture")
+	for idx, entry := range data.Materials {
+		materials.Row(idx, trimName(entry.Name), trimName(entry.Texture))
+	}
+
+	bones := inspection.Table("bones", "bone", "name", "parent", "translation", "rotation", "scale")
+	for idx, entry := range data.Bones {
+		bones.Row(idx, trimName(entry.Name), entry.Parent, entry.Translation, entry.Rotation, entry.Scale)
+	}
+
+	meshes := inspection.Table("meshes", "mesh", "name", "material", "flags", "vertices", "vertex offset", "indices", "index offset")
+	for idx, entry := range data.Meshes {
+		meshes.Row(idx, trimName(entry.Name), entry.Material, formats.Hex(entry.Flags),
+			entry.VertexCount, formats.Hex(entry.VertexOffset), entry.IndexCount, formats.Hex(entry.IndexOffset))
+	}
+
+	return inspection
+}
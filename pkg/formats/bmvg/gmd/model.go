@@ -0,0 +1,342 @@
+package gmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"strings"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+	"github.com/ProfElements/go-files/pkg/formats/bmvg"
+	"github.com/ProfElements/go-files/pkg/formats/khronos/gltf"
+)
+
+/*
+Model ...
+The geometry and materials of a GMD model, in the shape the glTF exporter takes them.
+Positions, normals and uvs are per vertex, every three indices make a triangle.
+*/
+type Model struct {
+	Meshes    []Mesh
+	Materials []Material
+	Bones     []Bone
+}
+
+/*
+Bone ...
+One joint of the model skeleton in its rest pose, relative to its parent.
+Parent indexes Model.Bones and is -1 for root bones, Rotation is a quaternion in x, y, z, w order.
+*/
+type Bone struct {
+	Name        string
+	Parent      int
+	Translation [3]float32
+	Rotation    [4]float32
+	Scale       [3]float32
+}
+
+type Mesh struct {
+	Name      string
+	Positions [][3]float32
+	Normals   [][3]float32
+	UVs       [][2]float32
+	Indices   []uint32
+	//Joints index Model.Bones, every vertex is weighted to up to four bones. Both are empty for unskinned meshes.
+	Joints  [][4]uint16
+	Weights [][4]float32
+	//Material indexes Model.Materials, -1 for none.
+	Material int
+}
+
+type Material struct {
+	Name string
+	//Texture is the name of the TPL member in the same archive, without extension.
+	Texture string
+}
+
+/*
+Decode ...
+Decode the materials, bones and meshes of a GMD file, or error. Every index has to point into
+the table it names: mesh materials, bone parents, vertex joints and triangle indices.
+*/
+func Decode(data *File) (*Model, error) {
+	model := &Model{}
+
+	for _, entry := range data.Materials {
+		model.Materials = append(model.Materials, Material{Name: trimName(entry.Name), Texture: trimName(entry.Texture)})
+	}
+
+	for i, entry := range data.Bones {
+		if entry.Parent < -1 || int(entry.Parent) >= len(data.Bones) || int(entry.Parent) == i {
+			return nil, &formats.FormatError{Format: "gmd", Offset: int(data.Sections.BoneOffset) + i*0x50 + 0x20, Field: fmt.Sprintf("bone %v parent", i),
+				Err: fmt.Errorf("%w, %v isn't another of the %v bones", formats.ErrInvalid, entry.Parent, len(data.Bones))}
+		}
+		model.Bones = append(model.Bones, Bone{
+			Name:        trimName(entry.Name),
+			Parent:      int(entry.Parent),
+			Translation: entry.Translation,
+			Rotation:    entry.Rotation,
+			Scale:       entry.Scale,
+		})
+	}
+
+	for i, entry := range data.Meshes {
+		mesh, err := decodeMesh(data, i, entry)
+		if err != nil {
+			return nil, err
+		}
+		model.Meshes = append(model.Meshes, mesh)
+	}
+
+	return model, nil
+}
+
+/*
+decodeMesh ...
+Decode the vertices and indices of mesh i, entry is its entry of the mesh table
+*/
+func decodeMesh(data *File, i int, entry MeshEntry) (Mesh, error) {
+	entryOffset := int(data.Sections.MeshOffset) + i*0x40
+	mesh := Mesh{Name: trimName(entry.Name), Material: int(entry.Material)}
+
+	if entry.Material < -1 || int(entry.Material) >= len(data.Materials) {
+		return mesh, &formats.FormatError{Format: "gmd", Offset: entryOffset + 0x20, Field: fmt.Sprintf("mesh %v material", i),
+			Err: fmt.Errorf("%w, %v isn't one of the %v materials", formats.ErrInvalid, entry.Material, len(data.Materials))}
+	}
+	if entry.Flags&^(MeshNormals|MeshUVs|MeshSkin) != 0 {
+		return mesh, &formats.FormatError{Format: "gmd", Offset: entryOffset + 0x24, Field: fmt.Sprintf("mesh %v flags", i),
+			Err: fmt.Errorf("%w, flags 0x%X aren't known", formats.ErrUnsupportedFormat, entry.Flags)}
+	}
+
+	stride := 12
+	if entry.Flags&MeshNormals != 0 {
+		stride += 12
+	}
+	if entry.Flags&MeshUVs != 0 {
+		stride += 8
+	}
+	if entry.Flags&MeshSkin != 0 {
+		stride += 20
+	}
+
+	vertices, err := section(data, bmvg.Span{Field: fmt.Sprintf("mesh %v vertices", i), Offset: entry.VertexOffset, Count: entry.VertexCount, Size: uint32(stride)})
+	if err != nil {
+		return mesh, err
+	}
+	indices, err := section(data, bmvg.Span{Field: fmt.Sprintf("mesh %v indices", i), Offset: entry.IndexOffset, Count: entry.IndexCount, Size: 2})
+	if err != nil {
+		return mesh, err
+	}
+
+	for v := 0; v < int(entry.VertexCount); v++ {
+		vertex := vertices[v*stride : (v+1)*stride]
+
+		mesh.Positions = append(mesh.Positions, [3]float32{float(vertex, 0), float(vertex, 4), float(vertex, 8)})
+		vertex = vertex[12:]
+		if entry.Flags&MeshNormals != 0 {
+			mesh.Normals = append(mesh.Normals, [3]float32{float(vertex, 0), float(vertex, 4), float(vertex, 8)})
+			vertex = vertex[12:]
+		}
+		if entry.Flags&MeshUVs != 0 {
+			mesh.UVs = append(mesh.UVs, [2]float32{float(vertex, 0), float(vertex, 4)})
+			vertex = vertex[8:]
+		}
+		if entry.Flags&MeshSkin != 0 {
+			var joints [4]uint16
+			for j := range joints {
+				joints[j] = uint16(vertex[j])
+				if int(joints[j]) >= len(data.Bones) {
+					return mesh, &formats.FormatError{Format: "gmd", Offset: int(entry.VertexOffset) + (v+1)*stride - 20 + j, Field: fmt.Sprintf("mesh %v vertex %v joint", i, v),
+						Err: fmt.Errorf("%w, %v isn't one of the %v bones", formats.ErrInvalid, joints[j], len(data.Bones))}
+				}
+			}
+			mesh.Joints = append(mesh.Joints, joints)
+			mesh.Weights = append(mesh.Weights, [4]float32{float(vertex, 4), float(vertex, 8), float(vertex, 12), float(vertex, 16)})
+		}
+	}
+
+	if entry.IndexCount%3 != 0 {
+		return mesh, &formats.FormatError{Format: "gmd", Offset: entryOffset + 0x30, Field: fmt.Sprintf("mesh %v index count", i),
+			Err: fmt.Errorf("%w, %v indices don't make whole triangles", formats.ErrInvalid, entry.IndexCount)}
+	}
+	for n := 0; n < int(entry.IndexCount); n++ {
+		index := uint32(binary.BigEndian.Uint16(indices[n*2:]))
+		if index >= entry.VertexCount {
+			return mesh, &formats.FormatError{Format: "gmd", Offset: int(entry.IndexOffset) + n*2, Field: fmt.Sprintf("mesh %v index %v", i, n),
+				Err: fmt.Errorf("%w, %v isn't one of the %v vertices", formats.ErrInvalid, index, entry.VertexCount)}
+		}
+		mesh.Indices = append(mesh.Indices, index)
+	}
+
+	return mesh, nil
+}
+
+/*
+section ...
+Return the bytes of span, or error. It has to lie after the sections and inside the file, and
+can't overlap the tables. Meshes may share vertex data, spans of data aren't checked against
+each other.
+*/
+func section(data *File, span bmvg.Span) ([]byte, error) {
+	if span.Count == 0 {
+		return nil, nil
+	}
+	err := bmvg.CheckSpans("gmd", sectionsEnd, uint32(headerSize+len(data.Data)), append(data.tables(), span)...)
+	if err != nil {
+		return nil, err
+	}
+	start := uint64(span.Offset) - headerSize
+	return data.Data[start : start+uint64(span.Count)*uint64(span.Size)], nil
+}
+
+func float(data []byte, offset int) float32 {
+	return math.Float32frombits(binary.BigEndian.Uint32(data[offset:]))
+}
+
+/*
+ExportGLTF ...
+Build a glTF document from a model, textures holds the decoded TPL members of the archive by name.
+Materials whose texture isn't in textures are exported without one.
+*/
+func ExportGLTF(model *Model, textures map[string]image.Image) (*gltf.Document, error) {
+	doc := gltf.NewDocument()
+
+	textureIndices := make(map[string]int)
+	for _, material := range model.Materials {
+		gltfMaterial := gltf.Material{
+			Name:                 material.Name,
+			PBRMetallicRoughness: &gltf.PBRMetallicRoughness{MetallicFactor: 0, RoughnessFactor: 1},
+		}
+
+		name := strings.TrimRight(material.Texture, "\x00")
+		if img, ok := textures[name]; ok {
+			index, ok := textureIndices[name]
+			if !ok {
+				raw := &bytes.Buffer{}
+				err := png.Encode(raw, img)
+				if err != nil {
+					return nil, fmt.Errorf("error while encoding texture %v %v", name, err)
+				}
+
+				doc.Textures = append(doc.Textures, gltf.Texture{Source: doc.AddImage(name, raw.Bytes())})
+				index = len(doc.Textures) - 1
+				textureIndices[name] = index
+			}
+
+			gltfMaterial.PBRMetallicRoughness.BaseColorTexture = &gltf.TextureInfo{Index: index}
+			gltfMaterial.AlphaMode = "MASK"
+		}
+
+		doc.Materials = append(doc.Materials, gltfMaterial)
+	}
+
+	scene := gltf.Scene{}
+
+	var skin *int
+	index, err := exportSkeleton(doc, model.Bones, &scene)
+	if err == nil {
+		skin = &index
+	} else if !errors.Is(err, errNoSkeleton) {
+		return nil, err
+	}
+
+	for i, mesh := range model.Meshes {
+		primitive := gltf.Primitive{Attributes: map[string]int{}, Mode: gltf.ModeTriangles}
+
+		positions, err := doc.AddFloatAccessor(flatten3(mesh.Positions), 3, gltf.TargetArrayBuffer)
+		if err != nil {
+			return nil, err
+		}
+		primitive.Attributes["POSITION"] = positions
+
+		if len(mesh.Normals) > 0 {
+			if len(mesh.Normals) != len(mesh.Positions) {
+				return nil, fmt.Errorf("mesh %v has %v normals for %v positions", i, len(mesh.Normals), len(mesh.Positions))
+			}
+			normals, err := doc.AddFloatAccessor(flatten3(mesh.Normals), 3, gltf.TargetArrayBuffer)
+			if err != nil {
+				return nil, err
+			}
+			primitive.Attributes["NORMAL"] = normals
+		}
+
+		if len(mesh.UVs) > 0 {
+			if len(mesh.UVs) != len(mesh.Positions) {
+				return nil, fmt.Errorf("mesh %v has %v uvs for %v positions", i, len(mesh.UVs), len(mesh.Positions))
+			}
+			uvs, err := doc.AddFloatAccessor(flatten2(mesh.UVs), 2, gltf.TargetArrayBuffer)
+			if err != nil {
+				return nil, err
+			}
+			primitive.Attributes["TEXCOORD_0"] = uvs
+		}
+
+		skinned := len(mesh.Joints) > 0 && skin != nil
+		if skinned {
+			if len(mesh.Joints) != len(mesh.Positions) || len(mesh.Weights) != len(mesh.Positions) {
+				return nil, fmt.Errorf("mesh %v has %v joints and %v weights for %v positions", i, len(mesh.Joints), len(mesh.Weights), len(mesh.Positions))
+			}
+			primitive.Attributes["JOINTS_0"] = doc.AddJointAccessor(mesh.Joints)
+
+			weights, err := doc.AddFloatAccessor(flatten4(mesh.Weights), 4, gltf.TargetArrayBuffer)
+			if err != nil {
+				return nil, err
+			}
+			primitive.Attributes["WEIGHTS_0"] = weights
+		}
+
+		if len(mesh.Indices) > 0 {
+			indices := doc.AddIndexAccessor(mesh.Indices)
+			primitive.Indices = &indices
+		}
+
+		if mesh.Material >= 0 && mesh.Material < len(doc.Materials) {
+			material := mesh.Material
+			primitive.Material = &material
+		}
+
+		doc.Meshes = append(doc.Meshes, gltf.Mesh{Name: mesh.Name, Primitives: []gltf.Primitive{primitive}})
+
+		meshIndex := len(doc.Meshes) - 1
+		node := gltf.Node{Name: mesh.Name, Mesh: &meshIndex}
+		if skinned {
+			node.Skin = skin
+		}
+		doc.Nodes = append(doc.Nodes, node)
+		scene.Nodes = append(scene.Nodes, len(doc.Nodes)-1)
+	}
+
+	doc.Scenes = []gltf.Scene{scene}
+	sceneIndex := 0
+	doc.Scene = &sceneIndex
+
+	return doc, nil
+}
+
+func flatten3(values [][3]float32) []float32 {
+	flat := make([]float32, 0, len(values)*3)
+	for _, value := range values {
+		flat = append(flat, value[0], value[1], value[2])
+	}
+	return flat
+}
+
+func flatten2(values [][2]float32) []float32 {
+	flat := make([]float32, 0, len(values)*2)
+	for _, value := range values {
+		flat = append(flat, value[0], value[1])
+	}
+	return flat
+}
+
+func flatten4(values [][4]float32) []float32 {
+	flat := make([]float32, 0, len(values)*4)
+	for _, value := range values {
+		flat = append(flat, value[0], value[1], value[2], value[3])
+	}
+	return flat
+}
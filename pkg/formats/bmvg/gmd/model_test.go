@@ -0,0 +1,163 @@
+package gmd
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+	"github.com/ProfElements/go-files/pkg/formats"
+	"github.com/ProfElements/go-files/pkg/formats/khronos/gltf"
+)
+
+func decode(t *testing.T, data []byte) (*Model, error) {
+	t.Helper()
+
+	file, err := Read(data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	return Decode(file)
+}
+
+func TestDecode(t *testing.T) {
+	model, err := decode(t, testgen.GMDOf(testModel))
+	if err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+
+	if !reflect.DeepEqual(model.Materials, []Material{{Name: "skin", Texture: "BODY"}, {Name: "plain"}}) {
+		t.Fatalf("decoded materials %+v", model.Materials)
+	}
+	if len(model.Bones) != 2 || model.Bones[0].Name != "root" || model.Bones[0].Parent != -1 || model.Bones[1].Parent != 0 || model.Bones[1].Rotation != testModel.Bones[1].Rotation {
+		t.Fatalf("decoded bones %+v", model.Bones)
+	}
+
+	body := model.Meshes[0]
+	want := testModel.Meshes[0]
+	if body.Name != "body" || body.Material != 0 || !reflect.DeepEqual(body.Positions, want.Positions) || !reflect.DeepEqual(body.Normals, want.Normals) ||
+		!reflect.DeepEqual(body.UVs, want.UVs) || !reflect.DeepEqual(body.Weights, want.Weights) {
+		t.Fatalf("decoded mesh %+v", body)
+	}
+	if !reflect.DeepEqual(body.Joints, [][4]uint16{{0, 0, 0, 0}, {1, 0, 0, 0}, {0, 1, 0, 0}}) || !reflect.DeepEqual(body.Indices, []uint32{0, 1, 2}) {
+		t.Fatalf("decoded joints %v and indices %v", body.Joints, body.Indices)
+	}
+
+	floor := model.Meshes[1]
+	if floor.Material != -1 || len(floor.Positions) != 4 || floor.Normals != nil || floor.UVs != nil || floor.Joints != nil || !reflect.DeepEqual(floor.Indices, []uint32{0, 1, 2, 0, 2, 3}) {
+		t.Fatalf("decoded mesh %+v", floor)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	//Offsets of the test model: the body mesh entry is at 0x148, its vertices at 0x1C8 and its indices after the 3 vertices of 52 bytes.
+	const bodyEntry, bodyVertices, bodyIndices = 0x148, 0x1C8, 0x1C8 + 3*52
+
+	tests := []struct {
+		name   string
+		patch  func(data []byte)
+		offset int
+		field  string
+		err    error
+	}{
+		{"material", func(data []byte) { binary.BigEndian.PutUint32(data[bodyEntry+0x20:], 2) }, bodyEntry + 0x20, "mesh 0 material", formats.ErrInvalid},
+		{"flags", func(data []byte) { data[bodyEntry+0x27] |= 0x80 }, bodyEntry + 0x24, "mesh 0 flags", formats.ErrUnsupportedFormat},
+		{"vertices", func(data []byte) { binary.BigEndian.PutUint32(data[bodyEntry+0x28:], 0x10000) }, bodyVertices, "mesh 0 vertices", formats.ErrTruncated},
+		{"vertices in the sections", func(data []byte) { binary.BigEndian.PutUint32(data[bodyEntry+0x2C:], 0x20) }, 0x20, "mesh 0 vertices", formats.ErrInvalid},
+		{"indices over the mesh table", func(data []byte) { binary.BigEndian.PutUint32(data[bodyEntry+0x34:], bodyEntry+0x40) }, bodyEntry + 0x40, "mesh 0 indices", formats.ErrInvalid},
+		{"joint", func(data []byte) { data[bodyVertices+52+32] = 2 }, bodyVertices + 52 + 32, "mesh 0 vertex 1 joint", formats.ErrInvalid},
+		{"index", func(data []byte) { binary.BigEndian.PutUint16(data[bodyIndices+2:], 3) }, bodyIndices + 2, "mesh 0 index 1", formats.ErrInvalid},
+		{"parent", func(data []byte) { binary.BigEndian.PutUint32(data[0xA8+0x50+0x20:], 1) }, 0xA8 + 0x50 + 0x20, "bone 1 parent", formats.ErrInvalid},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := testgen.GMDOf(testModel)
+			test.patch(data)
+
+			_, err := decode(t, data)
+			var formatErr *formats.FormatError
+			if !errors.As(err, &formatErr) || formatErr.Offset != test.offset || formatErr.Field != test.field || !errors.Is(err, test.err) {
+				t.Fatalf("decoding gave %v, it has to be %v at 0x%X in %v", err, test.err, test.offset, test.field)
+			}
+		})
+	}
+}
+
+func TestExportGLTF(t *testing.T) {
+	model, err := decode(t, testgen.GMDOf(testModel))
+	if err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+	textures := map[string]image.Image{"BODY": image.NewNRGBA(image.Rect(0, 0, 4, 4)), "UNUSED": image.NewNRGBA(image.Rect(0, 0, 2, 2))}
+
+	doc, err := ExportGLTF(model, textures)
+	if err != nil {
+		t.Fatalf("exporting: %v", err)
+	}
+
+	//Only the texture a material uses is embedded.
+	if len(doc.Textures) != 1 || len(doc.Images) != 1 || doc.Images[0].Name != "BODY" {
+		t.Fatalf("exported textures %+v and images %+v", doc.Textures, doc.Images)
+	}
+	if doc.Materials[0].PBRMetallicRoughness.BaseColorTexture == nil || doc.Materials[1].PBRMetallicRoughness.BaseColorTexture != nil {
+		t.Fatalf("exported materials %+v", doc.Materials)
+	}
+
+	//The bones come first, then a node for every mesh.
+	names := []string{}
+	for _, node := range doc.Nodes {
+		names = append(names, node.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"root", "arm", "body", "floor"}) || !reflect.DeepEqual(doc.Nodes[0].Children, []int{1}) {
+		t.Fatalf("exported nodes %+v", doc.Nodes)
+	}
+	if len(doc.Skins) != 1 || !reflect.DeepEqual(doc.Skins[0].Joints, []int{0, 1}) || doc.Nodes[2].Skin == nil || doc.Nodes[3].Skin != nil {
+		t.Fatalf("exported skins %+v, the body has skin %v and the floor %v", doc.Skins, doc.Nodes[2].Skin, doc.Nodes[3].Skin)
+	}
+	if !reflect.DeepEqual(doc.Scenes[0].Nodes, []int{0, 2, 3}) {
+		t.Fatalf("the scene holds nodes %v", doc.Scenes[0].Nodes)
+	}
+
+	body, floor := doc.Meshes[0].Primitives[0], doc.Meshes[1].Primitives[0]
+	for _, attribute := range []string{"POSITION", "NORMAL", "TEXCOORD_0", "JOINTS_0", "WEIGHTS_0"} {
+		if index, ok := body.Attributes[attribute]; !ok || doc.Accessors[index].Count != 3 {
+			t.Fatalf("the body has attributes %v, %v has to be one of 3 vertices", body.Attributes, attribute)
+		}
+	}
+	if len(floor.Attributes) != 1 || doc.Accessors[floor.Attributes["POSITION"]].Count != 4 || doc.Accessors[*floor.Indices].Count != 6 || floor.Material != nil {
+		t.Fatalf("the floor is %+v", floor)
+	}
+	if *body.Material != 0 || doc.Accessors[*body.Indices].Count != 3 {
+		t.Fatalf("the body is %+v", body)
+	}
+
+	//The exported document is what the glb holds.
+	glb, err := doc.WriteGLB()
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	var written gltf.Document
+	err = json.Unmarshal(glb[20:20+binary.LittleEndian.Uint32(glb[12:])], &written)
+	if err != nil || len(written.Meshes) != 2 || len(written.Nodes) != 4 || written.Buffers[0].ByteLength == 0 {
+		t.Fatalf("the glb holds %+v, %v", written, err)
+	}
+}
+
+func TestExportWithoutBones(t *testing.T) {
+	model, err := decode(t, testgen.GMDOf(testgen.GMDModel{Meshes: testModel.Meshes[1:]}))
+	if err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+
+	doc, err := ExportGLTF(model, nil)
+	if err != nil {
+		t.Fatalf("exporting: %v", err)
+	}
+	if len(doc.Skins) != 0 || len(doc.Nodes) != 1 || doc.Nodes[0].Skin != nil {
+		t.Fatalf("a model without bones exported skins %+v and nodes %+v", doc.Skins, doc.Nodes)
+	}
+}
@@ -0,0 +1,167 @@
+package gmd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ProfElements/go-files/pkg/formats/khronos/gltf"
+)
+
+/*
+errNoSkeleton ...
+Returned by exportSkeleton for models without bones, they are exported without a skin
+*/
+var errNoSkeleton = errors.New("the model has no bones")
+
+/*
+exportSkeleton ...
+Add a node for every bone, named after the bone so animations can find them, and a skin
+joining them. Root bones are added to scene. Returns errNoSkeleton when the model has no bones.
+*/
+func exportSkeleton(doc *gltf.Document, bones []Bone, scene *gltf.Scene) (int, error) {
+	if len(bones) == 0 {
+		return 0, errNoSkeleton
+	}
+
+	first := len(doc.Nodes)
+	joints := make([]int, len(bones))
+	for i, bone := range bones {
+		joints[i] = first + i
+		doc.Nodes = append(doc.Nodes, gltf.Node{
+			Name:        bone.Name,
+			Translation: bone.Translation[:],
+			Rotation:    bone.Rotation[:],
+			Scale:       bone.Scale[:],
+		})
+	}
+
+	//Global rest matrices, parents have to be resolved before their children.
+	globals := make([][16]float32, len(bones))
+	resolved := make([]bool, len(bones))
+	var resolve func(i int, depth int) error
+	resolve = func(i int, depth int) error {
+		if resolved[i] {
+			return nil
+		}
+		if depth > len(bones) {
+			return fmt.Errorf("bone %v is part of a parent loop", bones[i].Name)
+		}
+
+		local := composeTRS(bones[i].Translation, bones[i].Rotation, bones[i].Scale)
+		parent := bones[i].Parent
+		if parent < 0 {
+			globals[i] = local
+		} else {
+			if parent >= len(bones) {
+				return fmt.Errorf("bone %v has parent %v, there are only %v bones", bones[i].Name, parent, len(bones))
+			}
+			err := resolve(parent, depth+1)
+			if err != nil {
+				return err
+			}
+			globals[i] = multiply(globals[parent], local)
+		}
+		resolved[i] = true
+
+		return nil
+	}
+
+	inverseBinds := make([]float32, 0, len(bones)*16)
+	for i, bone := range bones {
+		err := resolve(i, 0)
+		if err != nil {
+			return 0, err
+		}
+
+		if bone.Parent < 0 {
+			scene.Nodes = append(scene.Nodes, first+i)
+		} else {
+			doc.Nodes[first+bone.Parent].Children = append(doc.Nodes[first+bone.Parent].Children, first+i)
+		}
+
+		inverse, ok := invert(globals[i])
+		if !ok {
+			return 0, fmt.Errorf("the rest pose of bone %v can't be inverted", bone.Name)
+		}
+		inverseBinds = append(inverseBinds, inverse[:]...)
+	}
+
+	accessor, err := doc.AddFloatAccessor(inverseBinds, 16, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	doc.Skins = append(doc.Skins, gltf.Skin{InverseBindMatrices: &accessor, Joints: joints})
+	return len(doc.Skins) - 1, nil
+}
+
+/*
+composeTRS ...
+Build a column major matrix, like glTF uses, from a translation, rotation quaternion and scale
+*/
+func composeTRS(t [3]float32, r [4]float32, s [3]float32) [16]float32 {
+	x, y, z, w := float64(r[0]), float64(r[1]), float64(r[2]), float64(r[3])
+	sx, sy, sz := float64(s[0]), float64(s[1]), float64(s[2])
+
+	return [16]float32{
+		float32((1 - 2*(y*y+z*z)) * sx), float32((2 * (x*y + z*w)) * sx), float32((2 * (x*z - y*w)) * sx), 0,
+		float32((2 * (x*y - z*w)) * sy), float32((1 - 2*(x*x+z*z)) * sy), float32((2 * (y*z + x*w)) * sy), 0,
+		float32((2 * (x*z + y*w)) * sz), float32((2 * (y*z - x*w)) * sz), float32((1 - 2*(x*x+y*y)) * sz), 0,
+		t[0], t[1], t[2], 1,
+	}
+}
+
+func multiply(a [16]float32, b [16]float32) [16]float32 {
+	var m [16]float32
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			var sum float32
+			for k := 0; k < 4; k++ {
+				sum += a[k*4+row] * b[col*4+k]
+			}
+			m[col*4+row] = sum
+		}
+	}
+	return m
+}
+
+/*
+invert ...
+Invert a 4x4 matrix by cofactors, ok is false when it is singular
+*/
+func invert(m [16]float32) ([16]float32, bool) {
+	var a [16]float64
+	for i := range m {
+		a[i] = float64(m[i])
+	}
+
+	var inv [16]float64
+	inv[0] = a[5]*a[10]*a[15] - a[5]*a[11]*a[14] - a[9]*a[6]*a[15] + a[9]*a[7]*a[14] + a[13]*a[6]*a[11] - a[13]*a[7]*a[10]
+	inv[4] = -a[4]*a[10]*a[15] + a[4]*a[11]*a[14] + a[8]*a[6]*a[15] - a[8]*a[7]*a[14] - a[12]*a[6]*a[11] + a[12]*a[7]*a[10]
+	inv[8] = a[4]*a[9]*a[15] - a[4]*a[11]*a[13] - a[8]*a[5]*a[15] + a[8]*a[7]*a[13] + a[12]*a[5]*a[11] - a[12]*a[7]*a[9]
+	inv[12] = -a[4]*a[9]*a[14] + a[4]*a[10]*a[13] + a[8]*a[5]*a[14] - a[8]*a[6]*a[13] - a[12]*a[5]*a[10] + a[12]*a[6]*a[9]
+	inv[1] = -a[1]*a[10]*a[15] + a[1]*a[11]*a[14] + a[9]*a[2]*a[15] - a[9]*a[3]*a[14] - a[13]*a[2]*a[11] + a[13]*a[3]*a[10]
+	inv[5] = a[0]*a[10]*a[15] - a[0]*a[11]*a[14] - a[8]*a[2]*a[15] + a[8]*a[3]*a[14] + a[12]*a[2]*a[11] - a[12]*a[3]*a[10]
+	inv[9] = -a[0]*a[9]*a[15] + a[0]*a[11]*a[13] + a[8]*a[1]*a[15] - a[8]*a[3]*a[13] - a[12]*a[1]*a[11] + a[12]*a[3]*a[9]
+	inv[13] = a[0]*a[9]*a[14] - a[0]*a[10]*a[13] - a[8]*a[1]*a[14] + a[8]*a[2]*a[13] + a[12]*a[1]*a[10] - a[12]*a[2]*a[9]
+	inv[2] = a[1]*a[6]*a[15] - a[1]*a[7]*a[14] - a[5]*a[2]*a[15] + a[5]*a[3]*a[14] + a[13]*a[2]*a[7] - a[13]*a[3]*a[6]
+	inv[6] = -a[0]*a[6]*a[15] + a[0]*a[7]*a[14] + a[4]*a[2]*a[15] - a[4]*a[3]*a[14] - a[12]*a[2]*a[7] + a[12]*a[3]*a[6]
+	inv[10] = a[0]*a[5]*a[15] - a[0]*a[7]*a[13] - a[4]*a[1]*a[15] + a[4]*a[3]*a[13] + a[12]*a[1]*a[7] - a[12]*a[3]*a[5]
+	inv[14] = -a[0]*a[5]*a[14] + a[0]*a[6]*a[13] + a[4]*a[1]*a[14] - a[4]*a[2]*a[13] - a[12]*a[1]*a[6] + a[12]*a[2]*a[5]
+	inv[3] = -a[1]*a[6]*a[11] + a[1]*a[7]*a[10] + a[5]*a[2]*a[11] - a[5]*a[3]*a[10] - a[9]*a[2]*a[7] + a[9]*a[3]*a[6]
+	inv[7] = a[0]*a[6]*a[11] - a[0]*a[7]*a[10] - a[4]*a[2]*a[11] + a[4]*a[3]*a[10] + a[8]*a[2]*a[7] - a[8]*a[3]*a[6]
+	inv[11] = -a[0]*a[5]*a[11] + a[0]*a[7]*a[9] + a[4]*a[1]*a[11] - a[4]*a[3]*a[9] - a[8]*a[1]*a[7] + a[8]*a[3]*a[5]
+	inv[15] = a[0]*a[5]*a[10] - a[0]*a[6]*a[9] - a[4]*a[1]*a[10] + a[4]*a[2]*a[9] + a[8]*a[1]*a[6] - a[8]*a[2]*a[5]
+
+	det := a[0]*inv[0] + a[1]*inv[4] + a[2]*inv[8] + a[3]*inv[12]
+	if math.Abs(det) < 1e-12 {
+		return [16]float32{}, false
+	}
+
+	var out [16]float32
+	for i := range inv {
+		out[i] = float32(inv[i] / det)
+	}
+	return out, true
+}
@@ -0,0 +1,73 @@
+package gmd
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ProfElements/go-files/pkg/formats/khronos/gltf"
+)
+
+func TestExportSkeleton(t *testing.T) {
+	bones := []Bone{
+		{Name: "root", Parent: -1, Translation: [3]float32{0, 1, 0}, Rotation: [4]float32{0, 0, 0, 1}, Scale: [3]float32{1, 1, 1}},
+		{Name: "arm", Parent: 0, Translation: [3]float32{1, 0, 0}, Rotation: [4]float32{0, 0, 0.70710677, 0.70710677}, Scale: [3]float32{1, 1, 1}},
+	}
+
+	doc := gltf.NewDocument()
+	scene := gltf.Scene{}
+	skin, err := exportSkeleton(doc, bones, &scene)
+	if err != nil {
+		t.Fatalf("exporting: %v", err)
+	}
+	if len(scene.Nodes) != 1 || scene.Nodes[0] != 0 || len(doc.Nodes[0].Children) != 1 || doc.Nodes[0].Children[0] != 1 {
+		t.Fatalf("the scene holds %v, the root has children %v", scene.Nodes, doc.Nodes[0].Children)
+	}
+
+	_, bin, err := doc.WriteGLTF("skeleton.bin")
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	accessor := doc.Accessors[*doc.Skins[skin].InverseBindMatrices]
+	if accessor.Count != 2 || accessor.Type != "MAT4" {
+		t.Fatalf("the inverse bind matrices are %+v", accessor)
+	}
+	matrices := bin[doc.BufferViews[accessor.BufferView].ByteOffset:]
+	value := func(matrix int, i int) float32 {
+		return math.Float32frombits(binary.LittleEndian.Uint32(matrices[matrix*64+i*4:]))
+	}
+
+	//The root sits at (0, 1, 0), the arm at (1, 1, 0) turned a quarter around z, the inverses move them back.
+	want := [][16]float32{
+		{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, -1, 0, 1},
+		{0, -1, 0, 0, 1, 0, 0, 0, 0, 0, 1, 0, -1, 1, 0, 1},
+	}
+	for matrix := range want {
+		for i := range want[matrix] {
+			if math.Abs(float64(value(matrix, i)-want[matrix][i])) > 1e-5 {
+				t.Fatalf("value %v of inverse bind matrix %v is %v, it has to be %v", i, matrix, value(matrix, i), want[matrix][i])
+			}
+		}
+	}
+}
+
+func TestExportSkeletonErrors(t *testing.T) {
+	_, err := exportSkeleton(gltf.NewDocument(), nil, &gltf.Scene{})
+	if !errors.Is(err, errNoSkeleton) {
+		t.Fatalf("exporting no bones gave %v", err)
+	}
+
+	loop := []Bone{
+		{Name: "a", Parent: 1, Rotation: [4]float32{0, 0, 0, 1}, Scale: [3]float32{1, 1, 1}},
+		{Name: "b", Parent: 0, Rotation: [4]float32{0, 0, 0, 1}, Scale: [3]float32{1, 1, 1}},
+	}
+	if _, err := ExportGLTF(&Model{Bones: loop}, nil); err == nil {
+		t.Fatalf("bones that are each other's parent have to fail")
+	}
+
+	flat := []Bone{{Name: "flat", Parent: -1, Rotation: [4]float32{0, 0, 0, 1}}}
+	if _, err := ExportGLTF(&Model{Bones: flat}, nil); err == nil || errors.Is(err, errNoSkeleton) {
+		t.Fatalf("a bone scaled to nothing can't be inverted, exporting gave %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package bmvg
+
+import (
+	"fmt"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+Inspect ...
+Show the header of a High Voltage file, its unknown values and file size, and how big the raw
+body after it is
+*/
+func Inspect(data *File) *formats.Inspection {
+	inspection := formats.NewInspection(data.Format)
+
+	header := inspection.Section("header")
+	for idx, value := range data.Unknowns {
+		header.Field(fmt.Sprintf("unknown %v", idx+1), formats.Hex(value))
+	}
+	header.Field("file size", formats.Hex(data.FileSize))
+
+	inspection.Section("body").
+		Field("size", formats.Hex(len(data.Data)))
+
+	return inspection
+}
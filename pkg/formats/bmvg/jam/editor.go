@@ -0,0 +1,353 @@
+package jam
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+Editor ...
+Editor changes single members of an existing JAM archive without a full Decode/Encode cycle.
+Everything it is not asked to touch is written back as it was read: the header values, the
+name and extension tables, the order of the file table and the order of the member data.
+*/
+type Editor struct {
+	header  Header
+	names   []string
+	exts    []string
+	lead    []byte
+	entries []editEntry
+	align   uint32
+	added   int
+	//tableEnd and size are those of the archive as it was read, offsets outside of its data are relocated from them.
+	tableEnd uint32
+	size     uint32
+}
+
+type editEntry struct {
+	entry fileEntry
+	data  []byte
+
+	//stored is set when the offset points into the data after the file table, data is what is there.
+	stored bool
+	//member is set for stored entries whose name and extension are in the tables, only those are members.
+	member bool
+	//changed is set once data no longer is the original bytes at entry.FileOffset.
+	changed bool
+	//slot is the original position of the data, so members are laid out in the same order.
+	slot uint64
+}
+
+/*
+NewEditor ...
+Open a read JAM archive for editing, or error
+*/
+func NewEditor(file *File) (*Editor, error) {
+	if file == nil {
+		return nil, fmt.Errorf("there is no jam file to edit")
+	}
+
+	tableEnd := file.Header.fileTableEndOffset
+	if int(tableEnd) > len(file.Data) {
+		return nil, formatError(8, "file table end offset", fmt.Errorf("%w, 0x%X is outside of the archive", formats.ErrInvalid, tableEnd))
+	}
+
+	editor := &Editor{
+		header:   file.Header,
+		names:    append([]string{}, file.fileNameTable...),
+		exts:     append([]string{}, file.fileExtTable...),
+		entries:  make([]editEntry, len(file.FileTable)),
+		tableEnd: tableEnd,
+		size:     uint32(len(file.Data)),
+	}
+
+	//Members have no stored size, a member runs until the next member starts or the archive ends.
+	var offsets []uint32
+	for _, entry := range file.FileTable {
+		if entry.FileOffset >= tableEnd && int(entry.FileOffset) < len(file.Data) {
+			offsets = append(offsets, entry.FileOffset)
+		}
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	firstOffset := uint32(len(file.Data))
+	if len(offsets) > 0 {
+		firstOffset = offsets[0]
+	}
+	editor.lead = file.Data[tableEnd:firstOffset]
+	editor.align = detectAlignment(offsets)
+
+	for i, entry := range file.FileTable {
+		editEntry := editEntry{entry: entry}
+
+		//Entries with a name or extension outside of the tables aren't members, their data is still kept.
+		inTables := int(entry.fileNameIdx) < len(editor.names) && int(entry.fileExtIdx) < len(editor.exts)
+		if entry.FileOffset >= tableEnd && int(entry.FileOffset) < len(file.Data) {
+			next := sort.Search(len(offsets), func(j int) bool { return offsets[j] > entry.FileOffset })
+			end := uint32(len(file.Data))
+			if next < len(offsets) {
+				end = offsets[next]
+			}
+
+			editEntry.stored = true
+			editEntry.member = inTables
+			editEntry.data = file.Data[entry.FileOffset:end]
+			editEntry.slot = uint64(entry.FileOffset)
+		}
+
+		editor.entries[i] = editEntry
+	}
+
+	return editor, nil
+}
+
+/*
+Members ...
+Return the members of the archive in file table order
+*/
+func (editor *Editor) Members() []WorkFile {
+	var members []WorkFile
+
+	for _, entry := range editor.entries {
+		if !entry.member {
+			continue
+		}
+		members = append(members, WorkFile{
+			FileName: editor.names[entry.entry.fileNameIdx],
+			FileExt:  editor.exts[entry.entry.fileExtIdx],
+			Data:     entry.data,
+		})
+	}
+
+	return members
+}
+
+/*
+Lookup ...
+Return the data of the member with the given name and extension
+*/
+func (editor *Editor) Lookup(name string, ext string) ([]byte, bool) {
+	idx := editor.find(name, ext)
+	if idx < 0 {
+		return nil, false
+	}
+
+	return editor.entries[idx].data, true
+}
+
+/*
+Replace ...
+Replace the data of the member with the given name and extension, or error
+*/
+func (editor *Editor) Replace(name string, ext string, data []byte) error {
+	idx := editor.find(name, ext)
+	if idx < 0 {
+		return fmt.Errorf("there is no member %v.%v in the archive", name, ext)
+	}
+
+	editor.entries[idx].data = data
+	editor.entries[idx].changed = true
+
+	return nil
+}
+
+/*
+ReplaceMember ...
+Replace the data of member index, counted in the order Members returns them, or error.
+Unlike Replace it also works when two members have the same name.
+*/
+func (editor *Editor) ReplaceMember(index int, data []byte) error {
+	member := 0
+	for idx, entry := range editor.entries {
+		if !entry.member {
+			continue
+		}
+		if member == index {
+			editor.entries[idx].data = data
+			editor.entries[idx].changed = true
+			return nil
+		}
+		member++
+	}
+
+	return fmt.Errorf("there is no member %v, the archive has %v", index, member)
+}
+
+/*
+Remove ...
+Remove the member with the given name and extension from the archive, or error. Its name and
+extension stay in their tables.
+*/
+func (editor *Editor) Remove(name string, ext string) error {
+	idx := editor.find(name, ext)
+	if idx < 0 {
+		return fmt.Errorf("there is no member %v.%v in the archive", name, ext)
+	}
+
+	//Renumbering the tables would also move the indices of entries that aren't members.
+	editor.entries = append(editor.entries[:idx], editor.entries[idx+1:]...)
+	return nil
+}
+
+/*
+Add ...
+Add a new member to the end of the archive, or error
+*/
+func (editor *Editor) Add(name string, ext string, data []byte) error {
+	layout := editor.header.Layout.orDefault()
+	if len(name) > layout.NameSize || len(ext) > layout.ExtSize {
+		return fmt.Errorf("%v.%v doesn't fit, names are at most %v bytes and extensions %v bytes", name, ext, layout.NameSize, layout.ExtSize)
+	}
+
+	if editor.find(name, ext) >= 0 {
+		return fmt.Errorf("there already is a member %v.%v in the archive", name, ext)
+	}
+
+	nameIdx := indexOfName(editor.names, name)
+	if nameIdx < 0 {
+		editor.names = append(editor.names, name)
+		nameIdx = len(editor.names) - 1
+	}
+
+	extIdx := indexOfName(editor.exts, ext)
+	if extIdx < 0 {
+		editor.exts = append(editor.exts, ext)
+		extIdx = len(editor.exts) - 1
+	}
+
+	editor.entries = append(editor.entries, editEntry{
+		entry: fileEntry{
+			fileNameIdx: uint16(nameIdx),
+			fileExtIdx:  uint16(extIdx),
+		},
+		data:    data,
+		stored:  true,
+		member:  true,
+		changed: true,
+		slot:    uint64(1)<<32 + uint64(editor.added),
+	})
+	editor.added++
+
+	return nil
+}
+
+/*
+Write ...
+Write the edited archive to a new JAM file or error
+*/
+func (editor *Editor) Write() ([]byte, error) {
+	file := &File{
+		Header:        editor.header,
+		fileNameTable: editor.names,
+		fileExtTable:  editor.exts,
+		FileTable:     make([]fileEntry, len(editor.entries)),
+	}
+
+	file.Header.fileNameCount = uint16(len(editor.names))
+	file.Header.fileExtCount = uint16(len(editor.exts))
+
+	layout := editor.header.Layout.orDefault()
+	tableEnd := uint32(32 + len(editor.names)*layout.NameSize + len(editor.exts)*layout.ExtSize + len(editor.entries)*8)
+	file.Header.fileTableEndOffset = tableEnd
+
+	var files []byte
+	if tableEnd == editor.header.fileTableEndOffset {
+		files = append(files, editor.lead...)
+	}
+
+	order := make([]int, 0, len(editor.entries))
+	for i, entry := range editor.entries {
+		file.FileTable[i] = entry.entry
+		if entry.stored {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return editor.entries[order[i]].slot < editor.entries[order[j]].slot
+	})
+
+	//Untouched entries that shared their data keep sharing it.
+	written := make(map[uint32]uint32)
+
+	for _, i := range order {
+		entry := editor.entries[i]
+
+		if !entry.changed {
+			if offset, ok := written[entry.entry.FileOffset]; ok {
+				file.FileTable[i].FileOffset = offset
+				continue
+			}
+		}
+
+		for (int(tableEnd)+len(files))%int(editor.align) != 0 {
+			files = append(files, 0x00)
+		}
+
+		offset := tableEnd + uint32(len(files))
+		if !entry.changed {
+			written[entry.entry.FileOffset] = offset
+		}
+
+		file.FileTable[i].FileOffset = offset
+		files = append(files, entry.data...)
+	}
+
+	//The rest point into the header and tables, which still start at 0, or past the end of the archive.
+	end := tableEnd + uint32(len(files))
+	for i, entry := range editor.entries {
+		if !entry.stored && entry.entry.FileOffset >= editor.tableEnd {
+			file.FileTable[i].FileOffset = end + entry.entry.FileOffset - editor.size
+		}
+	}
+
+	file.Files = files
+
+	return Write(file)
+}
+
+func (editor *Editor) find(name string, ext string) int {
+	for i, entry := range editor.entries {
+		if !entry.member {
+			continue
+		}
+		if trimName(editor.names[entry.entry.fileNameIdx]) == name && trimName(editor.exts[entry.entry.fileExtIdx]) == ext {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexOfName(table []string, name string) int {
+	for i := 0; i < len(table); i++ {
+		if trimName(table[i]) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func trimName(name string) string {
+	return strings.TrimRight(name, "\x00")
+}
+
+/*
+detectAlignment ...
+Find the largest power of two, up to 0x800, that every member offset is aligned to.
+An archive without members gets 0x20.
+*/
+func detectAlignment(offsets []uint32) uint32 {
+	if len(offsets) == 0 {
+		return 0x20
+	}
+
+	align := uint32(0x800)
+	for _, offset := range offsets {
+		for align > 1 && offset%align != 0 {
+			align /= 2
+		}
+	}
+	return align
+}
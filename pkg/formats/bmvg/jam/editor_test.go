@@ -0,0 +1,226 @@
+package jam
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+)
+
+var editMembers = []testgen.Member{
+	{Name: "LEVEL", Ext: "TPL", Data: []byte("level texture")},
+	{Name: "LEVEL", Ext: "GMD", Data: []byte("level model")},
+	{Name: "PLAYER", Ext: "GMD", Data: []byte("player model")},
+}
+
+/*
+edit ...
+Open the archive, edit it, write it and read the written archive again
+*/
+func edit(t *testing.T, data []byte, change func(editor *Editor) error) (*File, *Editor) {
+	t.Helper()
+
+	file, err := Read(data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	editor, err := NewEditor(file)
+	if err != nil {
+		t.Fatalf("opening: %v", err)
+	}
+	err = change(editor)
+	if err != nil {
+		t.Fatalf("editing: %v", err)
+	}
+
+	written, err := editor.Write()
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	edited, err := Read(written)
+	if err != nil {
+		t.Fatalf("reading the written archive: %v", err)
+	}
+	reopened, err := NewEditor(edited)
+	if err != nil {
+		t.Fatalf("opening the written archive: %v", err)
+	}
+
+	return edited, reopened
+}
+
+/*
+checkMembers ...
+The members of editor have to be want in order, their data starts with the wanted data and
+only padding follows it
+*/
+func checkMembers(t *testing.T, editor *Editor, want []testgen.Member) {
+	t.Helper()
+
+	members := editor.Members()
+	if len(members) != len(want) {
+		t.Fatalf("the archive has %v members, it has to have %v", len(members), len(want))
+	}
+	for idx, member := range members {
+		name, ext := trimName(member.FileName), trimName(member.FileExt)
+		if name != want[idx].Name || ext != want[idx].Ext {
+			t.Fatalf("member %v is %v.%v, it has to be %v.%v", idx, name, ext, want[idx].Name, want[idx].Ext)
+		}
+		if !bytes.HasPrefix(member.Data, want[idx].Data) || len(bytes.Trim(member.Data[len(want[idx].Data):], "\x00")) != 0 {
+			t.Fatalf("%v.%v holds %q, it has to be %q", name, ext, member.Data, want[idx].Data)
+		}
+	}
+}
+
+func TestEditor(t *testing.T) {
+	archive := testgen.JAM("JAM2", editMembers...)
+
+	tests := []struct {
+		name   string
+		change func(editor *Editor) error
+		want   []testgen.Member
+		names  int
+		exts   int
+	}{
+		{
+			name:   "replace with more data",
+			change: func(editor *Editor) error { return editor.Replace("LEVEL", "GMD", bytes.Repeat([]byte("grown"), 20)) },
+			want:   []testgen.Member{editMembers[0], {Name: "LEVEL", Ext: "GMD", Data: bytes.Repeat([]byte("grown"), 20)}, editMembers[2]},
+			names:  2,
+			exts:   2,
+		},
+		{
+			name:   "replace with less data",
+			change: func(editor *Editor) error { return editor.ReplaceMember(0, []byte("t")) },
+			want:   []testgen.Member{{Name: "LEVEL", Ext: "TPL", Data: []byte("t")}, editMembers[1], editMembers[2]},
+			names:  2,
+			exts:   2,
+		},
+		{
+			name:   "add grows the name and extension tables",
+			change: func(editor *Editor) error { return editor.Add("README", "TXT", []byte("hello")) },
+			want:   append(append([]testgen.Member{}, editMembers...), testgen.Member{Name: "README", Ext: "TXT", Data: []byte("hello")}),
+			names:  3,
+			exts:   3,
+		},
+		{
+			name:   "remove keeps names other members use",
+			change: func(editor *Editor) error { return editor.Remove("LEVEL", "GMD") },
+			want:   []testgen.Member{editMembers[0], editMembers[2]},
+			names:  2,
+			exts:   2,
+		},
+		{
+			name:   "remove keeps names no member uses",
+			change: func(editor *Editor) error { return editor.Remove("LEVEL", "TPL") },
+			want:   editMembers[1:],
+			names:  2,
+			exts:   2,
+		},
+		{
+			name: "remove and add",
+			change: func(editor *Editor) error {
+				err := editor.Remove("PLAYER", "GMD")
+				if err != nil {
+					return err
+				}
+				return editor.Add("ENEMY", "GKA", []byte("enemy animation"))
+			},
+			want:  []testgen.Member{editMembers[0], editMembers[1], {Name: "ENEMY", Ext: "GKA", Data: []byte("enemy animation")}},
+			names: 3,
+			exts:  3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			edited, reopened := edit(t, archive.Data, test.change)
+			checkMembers(t, reopened, test.want)
+
+			if len(edited.fileNameTable) != test.names || len(edited.fileExtTable) != test.exts {
+				t.Fatalf("the tables hold %v names and %v extensions, they have to hold %v and %v", len(edited.fileNameTable), len(edited.fileExtTable), test.names, test.exts)
+			}
+			if edited.Header.Variant != JAM2 || trimName(edited.Header.ArchiveNote) != "JMWK" {
+				t.Fatalf("the header became %+v", edited.Header)
+			}
+		})
+	}
+}
+
+func TestEditorErrors(t *testing.T) {
+	file, err := Read(testgen.JAM("FSTA", editMembers...).Data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	editor, err := NewEditor(file)
+	if err != nil {
+		t.Fatalf("opening: %v", err)
+	}
+
+	if editor.Replace("ENEMY", "GMD", nil) == nil || editor.Remove("LEVEL", "GKA") == nil || editor.ReplaceMember(3, nil) == nil {
+		t.Fatalf("editing a member that isn't there has to fail")
+	}
+	if editor.Add("PLAYER", "GMD", nil) == nil {
+		t.Fatalf("adding a member that is there has to fail")
+	}
+	if editor.Add("LONGERNAME", "GMD", nil) == nil || editor.Add("NAME", "LONGER", nil) == nil {
+		t.Fatalf("adding a name longer than the table allows has to fail")
+	}
+}
+
+/*
+TestEditorRelocates ...
+Entries that aren't members still have to point at the same bytes once the tables grow or
+shrink: one with an extension outside of the table and one pointing at the end of the archive.
+*/
+func TestEditorRelocates(t *testing.T) {
+	archive := testgen.JAM("JAM2", editMembers...)
+	data := append([]byte{}, archive.Data...)
+
+	//The file table follows two names and two extensions, its entries are 8 bytes each.
+	table := 0x20 + 2*8 + 2*4
+	binary.LittleEndian.PutUint16(data[table+2:], 0xFF)
+	binary.LittleEndian.PutUint32(data[table+8+4:], uint32(len(data)))
+	//With the second entry moved to the end, the data of the first runs up to the third.
+	first := data[binary.LittleEndian.Uint32(data[table+4:]):binary.LittleEndian.Uint32(data[table+16+4:])]
+
+	for _, change := range []func(editor *Editor) error{
+		func(editor *Editor) error { return editor.Add("README", "TXT", []byte("hello")) },
+		func(editor *Editor) error { return editor.Remove("PLAYER", "GMD") },
+	} {
+		edited, reopened := edit(t, data, change)
+
+		if !reopened.entries[0].stored || reopened.entries[0].member || !bytes.Equal(reopened.entries[0].data, first) {
+			t.Fatalf("the entry outside of the extension table points at %q, it has to keep %q", reopened.entries[0].data, first)
+		}
+		if edited.FileTable[1].FileOffset != uint32(len(edited.Data)) {
+			t.Fatalf("the entry at the end points at 0x%X, the archive ends at 0x%X", edited.FileTable[1].FileOffset, len(edited.Data))
+		}
+	}
+}
+
+/*
+TestEditorRemoveKeepsTables ...
+Removing the only member with a name can't turn an entry with a name index just outside of the
+table into a member, the tables keep their bytes.
+*/
+func TestEditorRemoveKeepsTables(t *testing.T) {
+	archive := testgen.JAM("JAM2", editMembers...)
+	data := append([]byte{}, archive.Data...)
+
+	table := 0x20 + 2*8 + 2*4
+	binary.LittleEndian.PutUint16(data[table:], 2)
+
+	edited, reopened := edit(t, data, func(editor *Editor) error { return editor.Remove("PLAYER", "GMD") })
+
+	if reopened.entries[0].member || edited.FileTable[0].fileNameIdx != 2 {
+		t.Fatalf("the entry outside of the name table has the name index %v, member %v", edited.FileTable[0].fileNameIdx, reopened.entries[0].member)
+	}
+	if !bytes.Equal(edited.Data[0x20:table], data[0x20:table]) {
+		t.Fatalf("the tables became % X, they have to stay % X", edited.Data[0x20:table], data[0x20:table])
+	}
+	if len(reopened.Members()) != 1 {
+		t.Fatalf("the archive holds %v members, only LEVEL.GMD is left", len(reopened.Members()))
+	}
+}
@@ -0,0 +1,150 @@
+package jam
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+func init() {
+	formats.Register(format{})
+}
+
+/*
+format ...
+JAM in the formats registry, files are *File and works are *Work.
+It is a formats.Container, Packer, Repacker and Inspector, member names are NAME.EXT.
+*/
+type format struct{}
+
+func (format) Name() string         { return "jam" }
+func (format) Extensions() []string { return []string{".jam"} }
+
+func (format) Probe(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	_, ok := variantFromMagic(data[:4])
+	return ok
+}
+
+func (format) Read(data []byte) (interface{}, error) {
+	return Read(data)
+}
+
+func (format) Write(file interface{}) ([]byte, error) {
+	jamFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("jam", "*jam.File", file)
+	}
+	return Write(jamFile)
+}
+
+func (format) Decode(file interface{}) (interface{}, error) {
+	jamFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("jam", "*jam.File", file)
+	}
+	return Decode(jamFile)
+}
+
+func (format) Encode(work interface{}) (interface{}, error) {
+	jamWork, ok := work.(*Work)
+	if !ok {
+		return nil, formats.WrongType("jam", "*jam.Work", work)
+	}
+	return Encode(jamWork)
+}
+
+func (format) Inspect(file interface{}) (*formats.Inspection, error) {
+	jamFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("jam", "*jam.File", file)
+	}
+	return Inspect(jamFile), nil
+}
+
+func (format) Unpack(data []byte) ([]formats.Member, error) {
+	file, err := Read(data)
+	if err != nil {
+		return nil, err
+	}
+	editor, err := NewEditor(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return unpacked(editor), nil
+}
+
+/*
+unpacked ...
+The members of an archive as Unpack returns them. A member of a format that knows its own size
+is cut to it, as Decode does, without the padding up to the next member. Any other member, or
+one that can't be read, keeps every byte up to the next member. Repack keeps the padding of the
+members it isn't given new data for.
+*/
+func unpacked(editor *Editor) []formats.Member {
+	var members []formats.Member
+	for _, member := range editor.Members() {
+		data := member.Data
+		if sized, ok, err := sizedData(trimName(member.FileExt), data); ok && err == nil {
+			data = sized
+		}
+		members = append(members, formats.Member{
+			Name: trimName(member.FileName) + "." + trimName(member.FileExt),
+			Data: data,
+		})
+	}
+	return members
+}
+
+func (format) Pack(members []formats.Member) ([]byte, error) {
+	work := &Work{Variant: JAM2}
+	for _, member := range members {
+		dot := strings.LastIndex(member.Name, ".")
+		if dot < 0 {
+			return nil, fmt.Errorf("jam members need an extension, %v has none", member.Name)
+		}
+		work.Files = append(work.Files, WorkFile{
+			FileName: member.Name[:dot],
+			FileExt:  member.Name[dot+1:],
+			Data:     member.Data,
+		})
+	}
+
+	file, err := Encode(work)
+	if err != nil {
+		return nil, err
+	}
+	return Write(file)
+}
+
+func (format) Repack(original []byte, members []formats.Member) ([]byte, error) {
+	file, err := Read(original)
+	if err != nil {
+		return nil, err
+	}
+	editor, err := NewEditor(file)
+	if err != nil {
+		return nil, err
+	}
+
+	originals := unpacked(editor)
+	if len(members) != len(originals) {
+		return nil, fmt.Errorf("the archive has %v members, %v were given", len(originals), len(members))
+	}
+	for idx, member := range members {
+		if bytes.Equal(member.Data, originals[idx].Data) {
+			continue
+		}
+		err = editor.ReplaceMember(idx, member.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return editor.Write()
+}
@@ -0,0 +1,210 @@
+package jam
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+func TestGolden(t *testing.T) {
+	level := testgen.TPL(testgen.TPLImage{Format: testgen.RGB5A3, Width: 8, Height: 8}, testgen.TPLImage{Format: testgen.C4, Width: 16, Height: 8})
+	model := testgen.GMD([]byte("model data"))
+
+	//The TPL comes first so its extension has index 0.
+	members := []testgen.Member{
+		{Name: "LEVEL", Ext: "TPL", Data: level.Data},
+		{Name: "LEVEL", Ext: "GMD", Data: model},
+		{Name: "PLAYER", Ext: "GMD", Data: model},
+		{Name: "README", Ext: "TXT", Data: []byte("hello")},
+	}
+	decoded := map[string][]byte{"TPL": level.Data, "GMD": model}
+
+	//Archives differ in their magic, the size of their table entries and the unknown value after the magic.
+	layouts := []struct {
+		variant Variant
+		layout  testgen.JAMLayout
+	}{
+		{FSTA, testgen.JAMLayout{Magic: "FSTA", NameSize: 8, ExtSize: 4}},
+		{JAM2, testgen.JAMLayout{Magic: "JAM2", NameSize: 8, ExtSize: 4}},
+		{FSTA, testgen.JAMLayout{Magic: "FSTA", NameSize: 16, ExtSize: 4, Unknown1: 0x01}},
+		{JAM2, testgen.JAMLayout{Magic: "JAM2", NameSize: 12, ExtSize: 4, Unknown1: 0x20}},
+		{JAM2, testgen.JAMLayout{Magic: "JAM2", NameSize: 32, ExtSize: 8, Unknown1: 0xDEADBEEF}},
+	}
+
+	for _, test := range layouts {
+		variant := test.variant
+		layout := Layout{NameSize: test.layout.NameSize, ExtSize: test.layout.ExtSize}
+		t.Run(fmt.Sprintf("%v %v/%v 0x%X", variant, layout.NameSize, layout.ExtSize, test.layout.Unknown1), func(t *testing.T) {
+			archive := testgen.JAMOf(test.layout, members...)
+
+			file, err := Read(archive.Data)
+			if err != nil {
+				t.Fatalf("reading: %v", err)
+			}
+			if file.Header.Variant != variant || file.Header.Layout != layout || file.Header.unk1 != test.layout.Unknown1 {
+				t.Fatalf("read variant %v, layout %+v and unknown 0x%X", file.Header.Variant, file.Header.Layout, file.Header.unk1)
+			}
+
+			written, err := Write(file)
+			if err != nil {
+				t.Fatalf("writing: %v", err)
+			}
+			if !bytes.Equal(written, archive.Data) {
+				t.Fatalf("writing changed the archive, 0x%X bytes became 0x%X", len(archive.Data), len(written))
+			}
+
+			unpacked, err := format{}.Unpack(archive.Data)
+			if err != nil {
+				t.Fatalf("unpacking: %v", err)
+			}
+			if len(unpacked) != len(archive.Members) {
+				t.Fatalf("unpacked %v members, the archive has %v", len(unpacked), len(archive.Members))
+			}
+			//Members that know their size are unpacked without the padding after them.
+			for idx, member := range archive.Members {
+				want := member.Data
+				if data, ok := decoded[member.Ext]; ok {
+					want = data
+				}
+				if unpacked[idx].Name != member.Name+"."+member.Ext || !bytes.Equal(unpacked[idx].Data, want) {
+					t.Fatalf("member %v is %v with 0x%X bytes, it has to be %v.%v with 0x%X", idx, unpacked[idx].Name, len(unpacked[idx].Data), member.Name, member.Ext, len(want))
+				}
+			}
+
+			repacked, err := format{}.Repack(archive.Data, unpacked)
+			if err != nil || !bytes.Equal(repacked, archive.Data) {
+				t.Fatalf("repacking the unpacked members changed the archive, %v", err)
+			}
+
+			files := Inspect(file).Sections[3]
+			for idx, member := range archive.Members {
+				if files.Rows[idx][5] != formats.Hex(len(member.Data)) {
+					t.Fatalf("inspected file %v as %v, its size has to be 0x%X", idx, files.Rows[idx], len(member.Data))
+				}
+			}
+
+			work, err := Decode(file)
+			if err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+			for idx, member := range members {
+				workFile := work.Files[idx]
+				if trimName(workFile.FileName) != member.Name || trimName(workFile.FileExt) != member.Ext {
+					t.Fatalf("member %v decoded as %q.%q, it has to be %v.%v", idx, workFile.FileName, workFile.FileExt, member.Name, member.Ext)
+				}
+				if want, ok := decoded[member.Ext]; ok && !bytes.Equal(workFile.Data, want) {
+					t.Fatalf("%v.%v decoded to 0x%X bytes, it has to be the 0x%X bytes of the member", member.Name, member.Ext, len(workFile.Data), len(want))
+				}
+			}
+
+			//Encoding the decoded work builds the same archive again.
+			encoded, err := Encode(work)
+			if err != nil {
+				t.Fatalf("encoding: %v", err)
+			}
+			written, err = Write(encoded)
+			if err != nil {
+				t.Fatalf("writing the encoded archive: %v", err)
+			}
+			if !bytes.Equal(written, archive.Data) {
+				t.Fatalf("encoding changed the archive, 0x%X bytes became 0x%X", len(archive.Data), len(written))
+			}
+		})
+	}
+}
+
+func TestDecodeBadMember(t *testing.T) {
+	level := testgen.TPL(testgen.TPLImage{Format: testgen.RGB5A3, Width: 8, Height: 8})
+	//The model says it is larger than the rest of the archive.
+	model := testgen.GMD([]byte("model data"))
+	model[0x0F] = 0xFF
+
+	file, err := Read(testgen.JAM("JAM2",
+		testgen.Member{Name: "BROKEN", Ext: "GMD", Data: model},
+		testgen.Member{Name: "LEVEL", Ext: "TPL", Data: level.Data},
+	).Data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+
+	work, err := Decode(file)
+	var formatErr *formats.FormatError
+	if !errors.As(err, &formatErr) || formatErr.Field != "member BROKEN.GMD" || !errors.Is(err, formats.ErrTruncated) {
+		t.Fatalf("decoding gave %v, it has to say the model is truncated", err)
+	}
+	if work == nil || len(work.Files) != 2 || work.Files[0].Data != nil || !bytes.Equal(work.Files[1].Data, level.Data) {
+		t.Fatalf("the texture has to be decoded next to the broken model, decoded %+v", work)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	file, err := Read(testgen.JAM("JAM2", testgen.Member{Name: "README", Ext: "TXT", Data: []byte("hello")}).Data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+
+	//The tables don't fit before the members anymore.
+	file.Header.fileTableEndOffset = 0x10
+	_, err = Write(file)
+	var formatErr *formats.FormatError
+	if !errors.As(err, &formatErr) || formatErr.Offset != 8 || !errors.Is(err, formats.ErrInvalid) {
+		t.Fatalf("writing gave %v, it has to say the file table end offset is invalid", err)
+	}
+
+	file.Header.Variant = Variant(9)
+	if _, err = Write(file); !formats.Unsupported(err) {
+		t.Fatalf("writing gave %v, it has to say the variant is unsupported", err)
+	}
+}
+
+/*
+TestUnpackSizes ...
+A 27 byte TGA is padded to 0x20 bytes in the archive. It has to be unpacked as the 27 bytes
+Decode returns, and repacking it or another member keeps the archive around it.
+*/
+func TestUnpackSizes(t *testing.T) {
+	image := testgen.TGA(testgen.TGAImage{Type: 3, Width: 3, Height: 3, PixelDepth: 8, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}})
+	archive := testgen.JAM("JAM2", testgen.Member{Name: "ICON", Ext: "TGA", Data: image}, testgen.Member{Name: "README", Ext: "TXT", Data: []byte("hello")})
+	if len(image) != 27 || len(archive.Members[0].Data) != 0x20 {
+		t.Fatalf("the tga is 0x%X bytes and 0x%X in the archive", len(image), len(archive.Members[0].Data))
+	}
+
+	members, err := format{}.Unpack(archive.Data)
+	if err != nil {
+		t.Fatalf("unpacking: %v", err)
+	}
+	if !bytes.Equal(members[0].Data, image) {
+		t.Fatalf("the tga is unpacked as 0x%X bytes, it is 0x%X", len(members[0].Data), len(image))
+	}
+	file, err := Read(archive.Data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	work, err := Decode(file)
+	if err != nil || !bytes.Equal(work.Files[0].Data, members[0].Data) {
+		t.Fatalf("decoding gave 0x%X bytes and %v, unpacking 0x%X", len(work.Files[0].Data), err, len(members[0].Data))
+	}
+
+	repacked, err := format{}.Repack(archive.Data, members)
+	if err != nil || !bytes.Equal(repacked, archive.Data) {
+		t.Fatalf("repacking the unpacked members changed the archive, %v", err)
+	}
+
+	members[1].Data = []byte("hello again")
+	repacked, err = format{}.Repack(archive.Data, members)
+	if err != nil {
+		t.Fatalf("repacking: %v", err)
+	}
+	again, err := format{}.Unpack(repacked)
+	if err != nil || !bytes.Equal(again[0].Data, image) || string(again[1].Data) != "hello again" {
+		t.Fatalf("the repacked archive unpacks to %q, %v", again, err)
+	}
+	readme := file.FileTable[1].FileOffset
+	if !bytes.Equal(repacked[:readme], archive.Data[:readme]) {
+		t.Fatalf("changing the readme changed the bytes before it")
+	}
+}
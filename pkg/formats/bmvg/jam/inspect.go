@@ -0,0 +1,61 @@
+package jam
+
+import (
+	"fmt"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+Inspect ...
+Show the header of a JAM archive, its name and extension tables with where each entry is, and
+its file table with the offset and size of every member. Members have no stored size, the size
+is up to the next member or the end of the archive, like Unpack cuts them.
+*/
+func Inspect(data *File) *formats.Inspection {
+	inspection := formats.NewInspection("jam")
+	layout := data.Header.Layout.orDefault()
+
+	inspection.Section("header").
+		Field("variant", data.Header.Variant).
+		Field("name size", layout.NameSize).
+		Field("extension size", layout.ExtSize).
+		Field("unknown 1", formats.Hex(data.Header.unk1)).
+		Field("file table end", formats.Hex(data.Header.fileTableEndOffset)).
+		Field("note", trimName(data.Header.ArchiveNote)).
+		Field("names", data.Header.fileNameCount).
+		Field("extensions", data.Header.fileExtCount)
+
+	offset := 32
+	names := inspection.Table("names", "index", "offset", "name")
+	for idx, name := range data.fileNameTable {
+		names.Row(idx, formats.Hex(offset), trimName(name))
+		offset += layout.NameSize
+	}
+	exts := inspection.Table("extensions", "index", "offset", "extension")
+	for idx, ext := range data.fileExtTable {
+		exts.Row(idx, formats.Hex(offset), trimName(ext))
+		offset += layout.ExtSize
+	}
+
+	//The editor finds where every member ends, a broken table end only leaves the sizes out.
+	editor, _ := NewEditor(data)
+	files := inspection.Table("files", "index", "entry", "name", "extension", "offset", "size")
+	for idx, entry := range data.FileTable {
+		name, ext, size := "?", "?", interface{}("?")
+		if int(entry.fileNameIdx) < len(data.fileNameTable) {
+			name = trimName(data.fileNameTable[entry.fileNameIdx])
+		}
+		if int(entry.fileExtIdx) < len(data.fileExtTable) {
+			ext = trimName(data.fileExtTable[entry.fileExtIdx])
+		}
+		if editor != nil && editor.entries[idx].member {
+			size = formats.Hex(len(editor.entries[idx].data))
+		}
+		files.Row(idx, formats.Hex(offset), fmt.Sprintf("%v (%v)", name, entry.fileNameIdx), fmt.Sprintf("%v (%v)", ext, entry.fileExtIdx),
+			formats.Hex(entry.FileOffset), size)
+		offset += 8
+	}
+
+	return inspection
+}
@@ -14,19 +14,123 @@ Description: An archive used by a few high voltage gamecube/wii games.
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/ProfElements/go-files/pkg/formats"
+	"github.com/ProfElements/go-files/pkg/formats/bmvg"
 	"github.com/ProfElements/go-files/pkg/formats/nintendo/tpl"
+	"github.com/ProfElements/go-files/pkg/formats/truevision/tga"
 )
 
+/*
+Variant ...
+The two flavours of JAM archive, told apart by their magic. The magic is all the variant
+decides, the sizes of the tables are the Layout of each archive.
+*/
+type Variant uint8
+
+const (
+	FSTA Variant = 0x00
+	JAM2 Variant = 0x01
+)
+
+var magics = map[Variant]string{
+	FSTA: "FSTA",
+	JAM2: "JAM2",
+}
+
+func (variant Variant) String() string {
+	magic, ok := magics[variant]
+	if !ok {
+		return fmt.Sprintf("Variant(%d)", uint8(variant))
+	}
+	return magic
+}
+
+func variantFromMagic(magic []byte) (Variant, bool) {
+	for variant, known := range magics {
+		if string(magic) == known {
+			return variant, true
+		}
+	}
+	return 0, false
+}
+
+/*
+Layout ...
+The size of the entries of the name and extension tables. The header doesn't store them, so
+Read finds the sizes that make the file table of an archive consistent and keeps them, the
+archive is written back with the same sizes. The zero Layout is DefaultLayout.
+*/
+type Layout struct {
+	NameSize int
+	ExtSize  int
+}
+
+/*
+DefaultLayout ...
+The table sizes of every archive seen so far, 8 byte names and 4 byte extensions
+*/
+var DefaultLayout = Layout{NameSize: 8, ExtSize: 4}
+
+//candidateLayouts are tried in order when reading, an archive that fits none gets DefaultLayout.
+var candidateLayouts = []Layout{DefaultLayout, {NameSize: 12, ExtSize: 4}, {NameSize: 16, ExtSize: 4}, {NameSize: 16, ExtSize: 8}, {NameSize: 32, ExtSize: 4}, {NameSize: 32, ExtSize: 8}}
+
+func (layout Layout) orDefault() Layout {
+	if layout.NameSize <= 0 || layout.ExtSize <= 0 {
+		return DefaultLayout
+	}
+	return layout
+}
+
+/*
+detectLayout ...
+Find the table sizes of an archive: the first candidate whose tables end before the file table
+and whose file table only names names and extensions that are in the tables and points into the
+data after it. Archives may hold entries outside of the tables, so a candidate whose file table
+only points into the data is taken too. DefaultLayout is tried before every other candidate.
+*/
+func detectLayout(data []byte, nameCount int, extCount int, tableEnd int) Layout {
+	for _, layout := range candidateLayouts {
+		for _, strict := range []bool{true, false} {
+			if fitsLayout(data, layout, nameCount, extCount, tableEnd, strict) {
+				return layout
+			}
+		}
+	}
+	return DefaultLayout
+}
+
+func fitsLayout(data []byte, layout Layout, nameCount int, extCount int, tableEnd int, strict bool) bool {
+	tablesEnd := 32 + nameCount*layout.NameSize + extCount*layout.ExtSize
+	if tablesEnd > tableEnd || tableEnd > len(data) || (tableEnd-tablesEnd)%8 != 0 {
+		return false
+	}
+
+	for idx := tablesEnd; idx < tableEnd; idx += 8 {
+		nameIdx := int(binary.LittleEndian.Uint16(data[idx : idx+2]))
+		extIdx := int(binary.LittleEndian.Uint16(data[idx+2 : idx+4]))
+		offset := int(binary.LittleEndian.Uint32(data[idx+4 : idx+8]))
+		if offset < tableEnd || offset > len(data) {
+			return false
+		}
+		if strict && (nameIdx >= nameCount || extIdx >= extCount) {
+			return false
+		}
+	}
+	return true
+}
+
 type fileEntry struct {
 	fileNameIdx uint16
 	fileExtIdx  uint16
 	FileOffset  uint32
 }
 type Header struct {
-	Magic              uint32
+	Variant            Variant
+	Layout             Layout
 	unk1               uint32
 	fileTableEndOffset uint32
 	ArchiveNote        string
@@ -50,27 +154,40 @@ type WorkFile struct {
 }
 
 type Work struct {
-	Files []WorkFile
+	Variant Variant
+	//Layout and Unknown1 are those of the archive the work was decoded from, new archives get them too.
+	Layout   Layout
+	Unknown1 uint32
+	Files    []WorkFile
 }
 
 func Read(data []byte) (*File, error) {
 	jam := &File{}
 
 	if len(data) < 32 {
-		return nil, fmt.Errorf("sata size is too small to be a .jam file")
+		return nil, formatError(0, "header", fmt.Errorf("%w, it is 0x20 bytes and there are 0x%X", formats.ErrTruncated, len(data)))
 	}
 
-	if !bytes.Equal(data[:4], []byte("FSTA")) && !bytes.Equal(data[:4], []byte("JAM2")) {
-		return nil, fmt.Errorf("wrong file magic, It isn't `FSTA` or `JAM2`")
+	variant, ok := variantFromMagic(data[:4])
+	if !ok {
+		return nil, formatError(0, "magic", fmt.Errorf("%w, %q isn't FSTA or JAM2", formats.ErrBadMagic, data[:4]))
 	}
-
-	jam.Header.Magic = binary.LittleEndian.Uint32(data[:4])
+	jam.Header.Variant = variant
+	//unk1 has no known meaning in either variant, it is kept as is so it is written back unchanged.
 	jam.Header.unk1 = binary.LittleEndian.Uint32(data[4:8])
 	jam.Header.fileTableEndOffset = binary.LittleEndian.Uint32(data[8:12])
 	jam.Header.ArchiveNote = string(data[12:28])
 	jam.Header.fileNameCount = binary.LittleEndian.Uint16(data[28:30])
 	jam.Header.fileExtCount = binary.LittleEndian.Uint16(data[30:32])
 
+	layout := detectLayout(data, int(jam.Header.fileNameCount), int(jam.Header.fileExtCount), int(jam.Header.fileTableEndOffset))
+	jam.Header.Layout = layout
+
+	tablesEnd := 32 + int(jam.Header.fileNameCount)*layout.NameSize + int(jam.Header.fileExtCount)*layout.ExtSize
+	if tablesEnd > len(data) {
+		return nil, formatError(32, "name and extension tables", fmt.Errorf("%w, they end at 0x%X of 0x%X bytes", formats.ErrTruncated, tablesEnd, len(data)))
+	}
+
 	var fileNames []string
 	var fileExts []string
 
@@ -79,21 +196,25 @@ func Read(data []byte) (*File, error) {
 
 	idx := 32
 	for i := 0; i < len(fileNames); i++ {
-		fileNames[i] = string(data[idx : idx+8])
-		idx += 8
+		fileNames[i] = string(data[idx : idx+layout.NameSize])
+		idx += layout.NameSize
 	}
 
 	for i := 0; i < len(fileExts); i++ {
-		fileExts[i] = string(data[idx : idx+4])
-		idx += 4
+		fileExts[i] = string(data[idx : idx+layout.ExtSize])
+		idx += layout.ExtSize
 	}
 
 	jam.fileNameTable = fileNames
 	jam.fileExtTable = fileExts
 
-	fileEntries := make([]fileEntry, ((int(jam.Header.fileTableEndOffset)-idx)/8)+1)
+	if int(jam.Header.fileTableEndOffset) < idx || int(jam.Header.fileTableEndOffset) > len(data) {
+		return nil, formatError(8, "file table end offset", fmt.Errorf("%w, 0x%X is outside of the archive", formats.ErrInvalid, jam.Header.fileTableEndOffset))
+	}
+
+	fileEntries := make([]fileEntry, (int(jam.Header.fileTableEndOffset)-idx)/8)
 
-	for i := 0; idx < int(jam.Header.fileTableEndOffset); i++ {
+	for i := 0; i < len(fileEntries); i++ {
 		fileEntry := fileEntry{
 			fileNameIdx: binary.LittleEndian.Uint16(data[idx : idx+2]),
 			fileExtIdx:  binary.LittleEndian.Uint16(data[idx+2 : idx+4]),
@@ -104,7 +225,7 @@ func Read(data []byte) (*File, error) {
 	}
 
 	jam.FileTable = fileEntries
-	jam.Files = data[idx:]
+	jam.Files = data[jam.Header.fileTableEndOffset:]
 	jam.Data = data
 
 	return jam, nil
@@ -113,27 +234,29 @@ func Read(data []byte) (*File, error) {
 
 func Write(data *File) ([]byte, error) {
 	buffer := &bytes.Buffer{}
-	_, err := buffer.WriteString("FSTA")
-	if err != nil {
-		return nil, err
+
+	magic, ok := magics[data.Header.Variant]
+	if !ok {
+		return nil, formatError(0, "magic", fmt.Errorf("%w, unknown jam variant %v", formats.ErrUnsupportedFormat, data.Header.Variant))
 	}
+	layout := data.Header.Layout.orDefault()
 
-	err = binary.Write(buffer, binary.LittleEndian, uint32(data.Header.unk1))
+	_, err := buffer.WriteString(magic)
 	if err != nil {
 		return nil, err
 	}
 
-	err = binary.Write(buffer, binary.LittleEndian, uint32(data.Header.fileTableEndOffset))
+	err = binary.Write(buffer, binary.LittleEndian, uint32(data.Header.unk1))
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = buffer.WriteString("JMWK")
+	err = binary.Write(buffer, binary.LittleEndian, uint32(data.Header.fileTableEndOffset))
 	if err != nil {
 		return nil, err
 	}
 
-	err = binary.Write(buffer, binary.LittleEndian, []byte("\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"))
+	_, err = buffer.Write(fixedString(data.Header.ArchiveNote, 16))
 	if err != nil {
 		return nil, err
 	}
@@ -149,20 +272,28 @@ func Write(data *File) ([]byte, error) {
 	}
 
 	for idx := 0; idx < int(data.Header.fileNameCount); idx++ {
-		err = binary.Write(buffer, binary.LittleEndian, binary.LittleEndian.Uint64([]byte(data.fileNameTable[idx])))
+		_, err = buffer.Write(fixedString(data.fileNameTable[idx], layout.NameSize))
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	for idx := 0; idx < int(data.Header.fileExtCount); idx++ {
-		err = binary.Write(buffer, binary.LittleEndian, binary.LittleEndian.Uint32([]byte(data.fileExtTable[idx])))
+		_, err = buffer.Write(fixedString(data.fileExtTable[idx], layout.ExtSize))
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	err = binary.Write(buffer, binary.LittleEndian, data.FileTable[:len(data.FileTable)-2])
+	err = binary.Write(buffer, binary.LittleEndian, data.FileTable)
+	if err != nil {
+		return nil, err
+	}
+
+	if buffer.Len() > int(data.Header.fileTableEndOffset) {
+		return nil, formatError(8, "file table end offset", fmt.Errorf("%w, the file table ends at 0x%X, past 0x%X", formats.ErrInvalid, buffer.Len(), data.Header.fileTableEndOffset))
+	}
+	_, err = buffer.Write(make([]byte, int(data.Header.fileTableEndOffset)-buffer.Len()))
 	if err != nil {
 		return nil, err
 	}
@@ -175,135 +306,139 @@ func Write(data *File) ([]byte, error) {
 	return buffer.Bytes(), nil
 
 }
+
+/*
+Decode ...
+Decode the members of a JAM archive in file table order. A member that can't be read doesn't
+stop the others: it is decoded without data and its *formats.FormatError is returned, every
+bad member's joined into one error, next to the work holding the good ones.
+*/
 func Decode(data *File) (*Work, error) {
-	work := &Work{}
+	work := &Work{Variant: data.Header.Variant, Layout: data.Header.Layout, Unknown1: data.Header.unk1}
 
 	workFiles := make([]WorkFile, len(data.FileTable))
+	var errs []error
 
 	for i := 0; i < len(workFiles); i++ {
-		if int(data.FileTable[i].fileNameIdx) > len(data.fileNameTable) || int(data.FileTable[i].fileExtIdx) > len(data.fileExtTable) || int(data.FileTable[i].fileExtIdx) == 0 || int(data.FileTable[i].FileOffset) < int(data.Header.fileTableEndOffset) || int(data.FileTable[i].FileOffset) > len(data.Data) {
+		if int(data.FileTable[i].fileNameIdx) >= len(data.fileNameTable) || int(data.FileTable[i].fileExtIdx) >= len(data.fileExtTable) || int(data.FileTable[i].FileOffset) < int(data.Header.fileTableEndOffset) || int(data.FileTable[i].FileOffset) > len(data.Data) {
 
 		} else {
+			tempName := data.fileNameTable[data.FileTable[i].fileNameIdx]
 			tempExt := data.fileExtTable[data.FileTable[i].fileExtIdx]
 			tempOffset := data.FileTable[i].FileOffset
+			tempData, err := getData(tempExt, tempOffset, data)
+			if err != nil {
+				errs = append(errs, formatError(int(tempOffset), "member "+trimName(tempName)+"."+trimName(tempExt), err))
+			}
 			workFile := WorkFile{
-				FileName: data.fileNameTable[data.FileTable[i].fileNameIdx],
-				FileExt:  data.fileExtTable[data.FileTable[i].fileExtIdx],
-				Data:     getData(tempExt, tempOffset, data),
+				FileName: tempName,
+				FileExt:  tempExt,
+				Data:     tempData,
 			}
 			workFiles[i] = workFile
 		}
 	}
 	work.Files = workFiles
-	return work, nil
+	return work, errors.Join(errs...)
 }
 
+/*
+Encode ...
+Build a JAM archive holding the files of data in their order, or error when a name doesn't fit
+*/
 func Encode(data *Work) (*File, error) {
-	file := &File{}
-	file.Header.Magic = binary.BigEndian.Uint32([]byte("JAM2"))
-	file.Header.unk1 = uint32(0) //Temporarily Set this to 0, it seems to work.
-	file.Header.ArchiveNote = "JMWK"
-
-	//Setup
-	fileNameCount := 0
-	fileExtCount := 0
-
-	tempNameTable := make([]string, len(data.Files))
-	tempExtTable := make([]string, len(data.Files))
-	tempEntryTable := make([]fileEntry, len(data.Files))
-
-	for i := 0; i < len(tempNameTable); i++ {
-		if arrayContains(tempNameTable, data.Files[i].FileName) {
-			continue
-		} else {
-			tempNameTable[fileNameCount] = data.Files[i].FileName
-			fileNameCount++
-		}
-
-		if tempNameTable[fileNameCount] == data.Files[i].FileName {
-			tempEntryTable[i].fileNameIdx = uint16(fileNameCount)
-		}
+	if _, ok := magics[data.Variant]; !ok {
+		return nil, formatError(0, "magic", fmt.Errorf("%w, unknown jam variant %v", formats.ErrUnsupportedFormat, data.Variant))
+	}
 
+	//A new archive is an edit of an empty one, so both lay out members the same way.
+	editor := &Editor{
+		header: Header{
+			Variant:     data.Variant,
+			unk1:        data.Unknown1,
+			ArchiveNote: "JMWK",
+			Layout:      data.Layout.orDefault(),
+		},
+		align: detectAlignment(nil),
 	}
-	file.Header.fileNameCount = uint16(fileNameCount)
-	file.fileNameTable = tempNameTable
 
-	for i := 0; i < len(tempExtTable); i++ {
-		if arrayContains(tempExtTable, data.Files[i].FileExt) {
-			continue
-		} else {
-			tempExtTable[fileExtCount] = data.Files[i].FileExt
-			fileExtCount++
+	for _, member := range data.Files {
+		err := editor.Add(trimName(member.FileName), trimName(member.FileExt), member.Data)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		if tempExtTable[fileExtCount] == data.Files[i].FileExt {
-			tempEntryTable[i].fileNameIdx = uint16(fileExtCount)
-		}
+	raw, err := editor.Write()
+	if err != nil {
+		return nil, err
 	}
-	file.Header.fileExtCount = uint16(fileExtCount)
-	file.fileExtTable = tempExtTable
 
-	//fileTableEndOffset uint32
+	return Read(raw)
+}
 
-	//FileTable     []fileEntry I just have to figure out the offsets,
-	//Files         []byte FileTable[i].fileOffset and writing of the files will need to be done in tandem
-	//Data          []byte hate myself for this using this stupid bullshit :(
+//----------//
+func getData(fileExt string, fileOffset uint32, data *File) ([]byte, error) {
 
-	return nil, fmt.Errorf("currently encoding is not working")
+	if member, ok, err := sizedData(strings.Trim(fileExt, "\x00"), data.Data[fileOffset:]); ok {
+		return member, err
+	}
+
+	idx := fileOffset
+	for int(idx) != len(data.Data) && data.Data[idx] != byte(0xFF) || int(fileOffset) > len(data.Data) {
+		idx++
+	}
+	fileData := data.Data[fileOffset:idx]
+	return fileData, nil
 }
 
-//----------//
-func getData(fileExt string, fileOffset uint32, data *File) []byte {
-
-	switch Extension := strings.Trim(fileExt, "\x00"); Extension {
-	case "GGG":
-		fileSize := binary.BigEndian.Uint32(data.Data[fileOffset+12 : fileOffset+16])
-		fileData := data.Data[fileOffset : fileOffset+fileSize]
-		return fileData
-	case "GKA":
-		fileSize := binary.BigEndian.Uint32(data.Data[fileOffset+12 : fileOffset+16])
-		fileData := data.Data[fileOffset : fileOffset+fileSize]
-		return fileData
-	case "GMD":
-		fileSize := binary.BigEndian.Uint32(data.Data[fileOffset+12 : fileOffset+16])
-		fileData := data.Data[fileOffset : fileOffset+fileSize]
-		return fileData
-	case "GMS":
-		fileSize := binary.BigEndian.Uint32(data.Data[fileOffset+12 : fileOffset+16])
-		fileData := data.Data[fileOffset : fileOffset+fileSize]
-		return fileData
-	case "GSL":
-		fileSize := binary.BigEndian.Uint32(data.Data[fileOffset+20 : fileOffset+24])
-		fileData := data.Data[fileOffset : fileOffset+fileSize]
-		return fileData
-	case "TGA":
-		return nil
-	case "TPL":
-		tpl, err := tpl.Read(data.Data[fileOffset:])
+/*
+sizedData ...
+Return the member of extension ext at the start of data, cut to the size it has itself, for the
+formats that know it: the High Voltage formats by the file size in their header, TGA and TPL by
+their contents. ok is false for every other extension.
+*/
+func sizedData(ext string, data []byte) ([]byte, bool, error) {
+	//The High Voltage formats are cut by the file size in their header, their bodies aren't checked here.
+	if size, ok, err := bmvg.MemberSize(ext, data); ok {
 		if err != nil {
-			fmt.Printf("SOmething went wrong")
+			return nil, true, err
 		}
-		if tpl == nil {
-			return []byte{}
+		return data[:size], true, nil
+	}
+
+	switch ext {
+	case "TGA":
+		file, err := tga.Read(data)
+		if err != nil {
+			return nil, true, err
 		}
-		return tpl.Data
-		//return nil
-	default:
-		idx := fileOffset
-		for int(idx) != len(data.Data) && data.Data[idx] != byte(0xFF) || int(fileOffset) > len(data.Data) {
-			idx++
+		return file.Data, true, nil
+	case "TPL":
+		file, err := tpl.Read(data)
+		if err != nil {
+			return nil, true, err
 		}
-		fileData := data.Data[fileOffset:idx]
-		return fileData
+		return file.Data, true, nil
 	}
 
+	return nil, false, nil
 }
 
-func arrayContains(array []string, item string) bool {
-	for i := 0; i < len(array); i++ {
-		if array[i] == item && array[i] != "" {
-			return true
-		}
-	}
-	return false
+/*
+formatError ...
+A problem of the archive at offset, err is one of the formats errors or wraps one
+*/
+func formatError(offset int, field string, err error) error {
+	return &formats.FormatError{Format: "jam", Offset: offset, Field: field, Err: err}
+}
+
+/*
+fixedString ...
+Pad or cut str to exactly size bytes, like the name and extension tables expect.
+*/
+func fixedString(str string, size int) []byte {
+	buf := make([]byte, size)
+	copy(buf, str)
+	return buf
 }
@@ -0,0 +1,34 @@
+package jam
+
+import (
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+)
+
+func FuzzRead(f *testing.F) {
+	for _, variant := range []Variant{JAM2, FSTA} {
+		archive := testgen.JAM(variant.String(),
+			testgen.Member{Name: "LEVEL", Ext: "TPL", Data: testgen.TPL(testgen.TPLImage{Format: testgen.C8, Width: 8, Height: 4}).Data},
+			testgen.Member{Name: "LEVEL", Ext: "GMD", Data: testgen.GMD([]byte("model data"))},
+			testgen.Member{Name: "README", Ext: "TXT", Data: []byte("hello")},
+		)
+		f.Add(archive.Data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := Read(data)
+		if err != nil {
+			return
+		}
+		Write(file)
+		Decode(file)
+
+		editor, err := NewEditor(file)
+		if err != nil {
+			return
+		}
+		editor.Members()
+		editor.Write()
+	})
+}
@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 	"os"
+
+	"github.com/ProfElements/go-files/internal/binstruct"
+	"github.com/ProfElements/go-files/pkg/formats"
 )
 
 /*
@@ -37,162 +41,312 @@ type KRTImage struct {
 	height        uint32
 	imageFormat   uint32
 	blockSize     uint16
+	unknown1      uint8
+	unknown2      uint8
 	unknown3      uint8
+	imageSize     uint32
 	paletteOffset uint32
 	imageOffset   uint32
 	fileSize      uint32
+	headerData    []byte
 	paletteData   []byte
 	imageData     []byte
 }
 
+const headerSize = 0x78
+
+/*
+header ...
+The header of a KRT texture as it is stored, up to the file size. ImageData isn't part of it,
+it is where the image offset and file size say. The padding isn't always zero, KRTImage keeps
+the header as it was read in headerData and writes the fields over it.
+*/
+type header struct {
+	_             [0x20]byte
+	Width         uint32
+	Height        uint32
+	ImageFormat   uint32
+	BlockSize     uint16
+	Unknown1      uint8
+	Unknown2      uint8
+	_             [4]byte
+	Unknown3      uint8
+	_             [3]byte
+	ImageSize     uint32
+	PaletteOffset uint32 `bin:"at=0x6C"`
+	ImageOffset   uint32
+	FileSize      uint32
+	ImageData     []byte `bin:"ptr=ImageOffset,end=FileSize"`
+}
+
+/*
+ReadKRT ...
+Read a KRT file from path and return a KRTImage pointer, or error
+*/
 func ReadKRT(filepath string) (*KRTImage, error) {
 	raw, err := os.ReadFile(filepath)
 	if err != nil {
-		return nil, fmt.Errorf("error while reading KRTImage %v", err)
+		return nil, fmt.Errorf("error while reading KRTImage %w", err)
 	}
 
+	return ReadKRTData(raw)
+}
+
+/*
+ReadKRTData ...
+Read KRT data and return a KRTImage pointer, or error
+*/
+func ReadKRTData(raw []byte) (*KRTImage, error) {
 	if len(raw) < 0xA0 {
-		return nil, fmt.Errorf("data is not large enough to be KRTImage")
+		return nil, formatError(0, "header", fmt.Errorf("%w, it is 0xA0 bytes and there are 0x%X", formats.ErrTruncated, len(raw)))
 	}
 
-	//index starts at 0x20 because the the first 0x20 bytes are just zeroes and hold no value
-	image := &KRTImage{}
-	index := 0x20
-
-	image.width = binary.BigEndian.Uint32(raw[index : index+4])
-	index += 4
-
-	image.height = binary.BigEndian.Uint32(raw[index : index+4])
-	index += 4
-
-	image.imageFormat = binary.BigEndian.Uint32(raw[index : index+4])
-	index += 4
-
-	image.blockSize = binary.BigEndian.Uint16(raw[index : index+2])
-	index += 2
-
-	//skip unknown1 and unknown2 and just assume they are one until otherwise found
-	index += 2
-
-	//Skip 4 padding bytes.
-	index += 4
-
-	image.unknown3 = uint8(raw[index])
-	index += 4
-
-	//Skip imageSize
-	index += 4
-
-	//Skip 0x30 padding bytes
-	index += 0x30
-
-	image.paletteOffset = binary.BigEndian.Uint32(raw[index : index+4])
-	index += 4
+	var header header
+	err := binstruct.Read(raw, 0, binary.BigEndian, &header)
+	if err != nil {
+		return nil, binstruct.Wrap(err, "krt", "header")
+	}
 
-	image.imageOffset = binary.BigEndian.Uint32(raw[index : index+4])
-	index += 4
+	image := &KRTImage{
+		width:         header.Width,
+		height:        header.Height,
+		imageFormat:   header.ImageFormat,
+		blockSize:     header.BlockSize,
+		unknown1:      header.Unknown1,
+		unknown2:      header.Unknown2,
+		unknown3:      header.Unknown3,
+		imageSize:     header.ImageSize,
+		paletteOffset: header.PaletteOffset,
+		imageOffset:   header.ImageOffset,
+		fileSize:      header.FileSize,
+		imageData:     header.ImageData,
+	}
 
-	image.fileSize = binary.BigEndian.Uint32(raw[index : index+4])
+	//The header and the padding after it, up to the palette or the image data.
+	dataStart := int(image.imageOffset)
+	if image.paletteOffset != 0 {
+		dataStart = int(image.paletteOffset)
+	}
+	if dataStart < headerSize {
+		dataStart = headerSize
+	}
+	image.headerData = raw[:dataStart]
 
 	if image.paletteOffset != 0 {
+		if image.paletteOffset > image.imageOffset {
+			return nil, formatError(0x6C, "palette offset", fmt.Errorf("%w, 0x%X is after the image data at 0x%X", formats.ErrInvalid, image.paletteOffset, image.imageOffset))
+		}
 		image.paletteData = raw[image.paletteOffset:image.imageOffset]
 	}
 
-	image.imageData = raw[image.imageOffset:image.fileSize]
-
 	return image, nil
 }
 
+/*
+WriteKRT ...
+Write a KRTImage to a file at path, or error
+*/
 func (image *KRTImage) WriteKRT(filepath string) error {
-	buf := bytes.NewBuffer([]byte{})
-
-	//Write padding
-	binary.Write(buf, binary.BigEndian, make([]byte, 0x20))
-
-	binary.Write(buf, binary.BigEndian, image.width)
-	binary.Write(buf, binary.BigEndian, image.height)
-	binary.Write(buf, binary.BigEndian, image.imageFormat)
-	binary.Write(buf, binary.BigEndian, image.blockSize)
-
-	//Write unknown1 and unknown as 1 since that is what almost all textures are
-	binary.Write(buf, binary.BigEndian, []byte{0x01, 0x01})
-
-	//Write padding
-	binary.Write(buf, binary.BigEndian, make([]byte, 4))
+	raw, err := image.WriteKRTData()
+	if err != nil {
+		return err
+	}
 
-	binary.Write(buf, binary.BigEndian, uint32(image.unknown3))
-	binary.Write(buf, binary.BigEndian, uint32(image.width*image.height))
-	//Write padding
-	binary.Write(buf, binary.BigEndian, make([]byte, 0x30))
+	err = os.WriteFile(filepath, raw, 0666)
+	if err != nil {
+		return fmt.Errorf("failed to write file due to %v to %v", err, filepath)
+	}
 
-	binary.Write(buf, binary.BigEndian, image.paletteOffset)
-	binary.Write(buf, binary.BigEndian, image.imageOffset)
-	binary.Write(buf, binary.BigEndian, image.fileSize)
+	return nil
+}
 
-	if image.paletteOffset != 0 && buf.Len() < int(image.paletteOffset) {
-		padding := int(image.paletteOffset) - buf.Len()
-		binary.Write(buf, binary.BigEndian, make([]byte, padding))
-		binary.Write(buf, binary.BigEndian, image.paletteData)
-		binary.Write(buf, binary.BigEndian, image.imageData)
+/*
+WriteKRTData ...
+Write a KRTImage to KRT data, or error. The padding of the header and after it is written as
+it was read, zeroes for an encoded texture.
+*/
+func (image *KRTImage) WriteKRTData() ([]byte, error) {
+	raw := make([]byte, headerSize)
+	copy(raw, image.headerData)
+	err := binstruct.Write(raw, 0, binary.BigEndian, header{
+		Width:         image.width,
+		Height:        image.height,
+		ImageFormat:   image.imageFormat,
+		BlockSize:     image.blockSize,
+		Unknown1:      image.unknown1,
+		Unknown2:      image.unknown2,
+		Unknown3:      image.unknown3,
+		ImageSize:     image.imageSize,
+		PaletteOffset: image.paletteOffset,
+		ImageOffset:   image.imageOffset,
+		FileSize:      image.fileSize,
+	})
+	if err != nil {
+		return nil, binstruct.Wrap(err, "krt", "header")
+	}
+	buf := bytes.NewBuffer(raw)
 
-		err := os.WriteFile(filepath, buf.Bytes(), 0666)
-		if err != nil {
-			return fmt.Errorf("failed to write file due to %v to %v", err, filepath)
+	if image.paletteOffset != 0 {
+		if buf.Len() > int(image.paletteOffset) {
+			return nil, formatError(0x6C, "palette offset", fmt.Errorf("%w, 0x%X is inside the header", formats.ErrInvalid, image.paletteOffset))
 		}
+		buf.Write(image.padding(buf.Len(), int(image.paletteOffset)))
+		binary.Write(buf, binary.BigEndian, image.paletteData)
+	}
 
-		return nil
+	if buf.Len() > int(image.imageOffset) {
+		return nil, formatError(0x70, "image offset", fmt.Errorf("%w, 0x%X is inside the header or palette", formats.ErrInvalid, image.imageOffset))
 	}
+	buf.Write(image.padding(buf.Len(), int(image.imageOffset)))
+	binary.Write(buf, binary.BigEndian, image.imageData)
 
-	if buf.Len() < int(image.imageOffset) {
-		padding := int(image.imageOffset) - buf.Len()
-		binary.Write(buf, binary.BigEndian, make([]byte, padding))
-		binary.Write(buf, binary.BigEndian, image.imageData)
+	return buf.Bytes(), nil
+}
 
-		err := os.WriteFile(filepath, buf.Bytes(), 0666)
-		if err != nil {
-			return fmt.Errorf("failed to write file due to %v to %v", err, filepath)
-		}
+/*
+padding ...
+The bytes from start to end as they were read, zeroes past what was read
+*/
+func (image *KRTImage) padding(start int, end int) []byte {
+	padding := make([]byte, end-start)
+	if start < len(image.headerData) {
+		copy(padding, image.headerData[start:])
+	}
+	return padding
+}
 
-		return nil
+/*
+EncodeToKRT ...
+Encode an image to an RGBA8 KRTImage, or error. Width and height have to be multiples of 4.
+An *image.NRGBA is encoded as it is, any other image goes through NRGBA first.
+*/
+func EncodeToKRT(img image.Image) (*KRTImage, error) {
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+		draw.Draw(nrgba, nrgba.Bounds(), img, img.Bounds().Min, draw.Src)
 	}
+	width, height := nrgba.Rect.Dx(), nrgba.Rect.Dy()
 
-	return nil
-}
+	imageData, err := encodeTexture(0xF, nrgba, nil)
+	if err != nil {
+		return nil, err
+	}
 
-func EncodeToKRT(rgba *image.RGBA) (*KRTImage, error) {
-	image := &KRTImage{
-		width:         uint32(rgba.Rect.Dx()),
-		height:        uint32(rgba.Rect.Dy()),
+	return &KRTImage{
+		width:         uint32(width),
+		height:        uint32(height),
 		imageFormat:   0xF,
 		blockSize:     16,
+		unknown1:      1,
+		unknown2:      1,
 		unknown3:      0xFF, //Assume it FF for now
+		imageSize:     uint32(width * height),
 		paletteOffset: 0x00,
 		imageOffset:   0xA0,
-		fileSize:      uint32(0xA0 + (rgba.Rect.Dx() * rgba.Rect.Dy())),
+		fileSize:      uint32(0xA0 + len(imageData)),
 		paletteData:   []byte{},
+		imageData:     imageData,
+	}, nil
+}
+
+/*
+blockSizes ...
+The width and height in pixels of the blocks of every image format
+*/
+var blockSizes = map[uint32][2]int{
+	0xF:  {4, 4},
+	0x10: {4, 4},
+	0x11: {8, 4},
+	0x12: {8, 4},
+	0x13: {8, 8},
+	0x16: {8, 8},
+	0x17: {4, 4},
+}
+
+/*
+encodeTexture ...
+Encode img in an image format, the opposite of getTexture. The palette formats keep the
+palette in paletteData and every pixel gets the index of the nearest color in it. I4 stores
+one intensity that decodes to both the color and the alpha.
+*/
+func encodeTexture(format uint32, img *image.NRGBA, paletteData []byte) ([]byte, error) {
+	size, ok := blockSizes[format]
+	if !ok {
+		return nil, fmt.Errorf("%w, image format 0x%X", formats.ErrUnsupportedFormat, format)
 	}
-	
-	
-	for y := rgba.Bounds().Min.Y; y < rgba.Bounds().Max.Y; y++ {
-	  for x := rgba.Bounds().Min.X; x < rgba.Bounds().Max.X; x++ {
-	  	blockSize := blockWidth * blockHeight
-				blocksPerRow := int(width) / blockWidth
-				block_i := imagePixelIndex % blockSize
-				block_id := imagePixelIndex / blockSize
-				blockCol := block_id % blocksPerRow
-				blockRow := block_id / blocksPerRow
-				Ix := blockCol*blockWidth + (block_i % blockWidth)
-			  Iy := blockRow*blockHeight + (block_i / blockWidth)
-	  }
+	blockWidth, blockHeight := size[0], size[1]
+
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	if width == 0 || height == 0 || width%blockWidth != 0 || height%blockHeight != 0 {
+		return nil, fmt.Errorf("%w, format 0x%X is stored in %vx%v blocks, %vx%v isn't a whole number of them", formats.ErrInvalid, format, blockWidth, blockHeight, width, height)
 	}
 
-	return image, nil
+	var palette []color.NRGBA
+	switch format {
+	case 0x11, 0x12, 0x13:
+		palette = paletteColors(format, paletteData)
+		//A C4 index only reaches the first 16 colors, a C8 index the first 256.
+		if format == 0x13 && len(palette) > 0x10 {
+			palette = palette[:0x10]
+		} else if len(palette) > 0x100 {
+			palette = palette[:0x100]
+		}
+		if len(palette) == 0 {
+			return nil, fmt.Errorf("%w, format 0x%X needs a palette and the texture has none", formats.ErrInvalid, format)
+		}
+	}
+
+	pixels := width * height
+	var out []byte
+	switch format {
+	case 0xF:
+		out = make([]byte, pixels*4)
+	case 0x10, 0x17:
+		out = make([]byte, pixels*2)
+	case 0x11, 0x12:
+		out = make([]byte, pixels)
+	case 0x13, 0x16:
+		out = make([]byte, pixels/2)
+	}
+
+	blockSize := blockWidth * blockHeight
+	blocksPerRow := width / blockWidth
+	for pixel := 0; pixel < pixels; pixel++ {
+		blockI := pixel % blockSize
+		blockID := pixel / blockSize
+		x := img.Rect.Min.X + (blockID%blocksPerRow)*blockWidth + blockI%blockWidth
+		y := img.Rect.Min.Y + (blockID/blocksPerRow)*blockHeight + blockI/blockWidth
+		c := img.NRGBAAt(x, y)
+
+		switch format {
+		case 0xF:
+			//Every 4x4 block is 64 bytes, the alpha and red of its 16 pixels and then their green and blue.
+			block := out[blockID*64:]
+			block[blockI*2] = c.A
+			block[1+blockI*2] = c.R
+			block[32+blockI*2] = c.G
+			block[33+blockI*2] = c.B
+		case 0x10:
+			binary.BigEndian.PutUint16(out[pixel*2:], toRGB5A3(c))
+		case 0x17:
+			binary.BigEndian.PutUint16(out[pixel*2:], toRGB565(c))
+		case 0x11, 0x12:
+			out[pixel] = uint8(nearest(palette, c))
+		case 0x13:
+			putNibble(out, pixel, uint8(nearest(palette, c)))
+		case 0x16:
+			putNibble(out, pixel, to4(intensity(c)))
+		}
+	}
+
+	return out, nil
 }
 
-func (image *KRTImage) DecodeFromKRT() (*image.RGBA, error) {
+func (image *KRTImage) DecodeFromKRT() (*image.NRGBA, error) {
 	rgba, err := getTexture(image.width, image.height, image.imageFormat, image.imageData, image.paletteData)
 	if err != nil {
-		return nil, fmt.Errorf("error while decoding from KRTImage %v", err)
+		return nil, formatError(int(image.imageOffset), "image data", err)
 	}
 
 	return rgba, nil
@@ -210,19 +364,43 @@ func (image *KRTImage) DecodeFromKRT() (*image.RGBA, error) {
 	I4
 	CMPR
 */
-func getTexture(width uint32, height uint32, format uint32, data []byte, paletteData []byte) (*image.RGBA, error) {
-	img := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+func getTexture(width uint32, height uint32, format uint32, data []byte, paletteData []byte) (*image.NRGBA, error) {
+	if width == 0 || height == 0 || width > 4096 || height > 4096 {
+		return nil, fmt.Errorf("%w, texture is %vx%v, textures are 1x1 up to 4096x4096", formats.ErrInvalid, width, height)
+	}
+
+	//How many bytes of image data each format reads, and how wide its blocks are.
+	pixels := int(width) * int(height)
+	need, minWidth := 0, 0
+	switch format {
+	case 0xF:
+		minWidth = 4
+	case 0x10, 0x17:
+		need, minWidth = pixels*2, 4
+	case 0x11, 0x12:
+		need, minWidth = pixels, 8
+	case 0x13, 0x16:
+		need, minWidth = (pixels+1)/2, 8
+	}
+	if int(width) < minWidth {
+		return nil, fmt.Errorf("%w, texture is %v wide, format 0x%X is stored in blocks %v wide", formats.ErrInvalid, width, format, minWidth)
+	}
+	if len(data) < need {
+		return nil, fmt.Errorf("%w, image data is 0x%X bytes, a %vx%v texture of format 0x%X needs 0x%X", formats.ErrTruncated, len(data), width, height, format, need)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, int(width), int(height)))
 
 	imagePixelIndex := 0
 	imageDataIndex := 0
 
 	if format == 0xF { //RGBA8
 		blockWidth, blockHeight := 4, 4
-		paletteImg := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
-		for i := 0; i < len(data) && imageDataIndex < len(data); i++ {
+		paletteImg := image.NewNRGBA(image.Rect(0, 0, int(width), int(height)))
+		for imageDataIndex+64 <= len(data) {
 			tempBuf := data[imageDataIndex : imageDataIndex+64]
 			imageDataIndex += 64
-			for j := 0; j < 16 && imageDataIndex < len(data); j++ {
+			for j := 0; j < 16; j++ {
 
 				blockSize := blockWidth * blockHeight
 				blocksPerRow := int(width) / blockWidth
@@ -233,7 +411,7 @@ func getTexture(width uint32, height uint32, format uint32, data []byte, palette
 				Ix := blockCol*blockWidth + (block_i % blockWidth)
 			  Iy := blockRow*blockHeight + (block_i / blockWidth)
 
-				paletteImg.Set(Ix, Iy, color.RGBA{
+				paletteImg.Set(Ix, Iy, color.NRGBA{
 					R: uint8(tempBuf[1+j*2]),
 					G: uint8(tempBuf[32+j*2]),
 					B: uint8(tempBuf[33+j*2]),
@@ -261,7 +439,7 @@ func getTexture(width uint32, height uint32, format uint32, data []byte, palette
 				Iy := blockRow*blockHeight + (block_i / blockWidth)
 
 				if format == 0x17 {
-					img.Set(Ix, Iy, color.RGBA{
+					img.Set(Ix, Iy, color.NRGBA{
 						R: convert5to8(uint8((pixel >> 11))),
 						G: convert6to8(uint8((pixel >> 5 & 0x3F))),
 						B: convert5to8(uint8((pixel & 0x1F))),
@@ -270,14 +448,14 @@ func getTexture(width uint32, height uint32, format uint32, data []byte, palette
 				} else {
 					hasAlpha := pixel & 0x8000
 					if hasAlpha == 0 {
-						img.Set(Ix, Iy, color.RGBA{
+						img.Set(Ix, Iy, color.NRGBA{
 							R: convert4to8(uint8((pixel >> 8 & 0xF))),
 							G: convert4to8(uint8((pixel >> 4 & 0xF))),
 							B: convert4to8(uint8((pixel & 0xF))),
 							A: convert3to8(uint8((pixel >> 12 & 0x7))),
 						})
 					} else {
-						img.Set(Ix, Iy, color.RGBA{
+						img.Set(Ix, Iy, color.NRGBA{
 							R: convert5to8(uint8((pixel >> 10 & 0x1F))),
 							G: convert5to8(uint8((pixel >> 5 & 0x1F))),
 							B: convert5to8(uint8((pixel & 0x1F))),
@@ -292,45 +470,15 @@ func getTexture(width uint32, height uint32, format uint32, data []byte, palette
 		}
 		return img, nil
 	} else if format == 0x11 || format == 0x12 { // CI4 / CI8 - RGB565 / RGB5A3
-		var paletteEntries []*color.RGBA
-
-		paletteDataIndex := 0
-
-		for paletteDataIndex < len(paletteData) {
-			pixel := binary.BigEndian.Uint16(paletteData[paletteDataIndex : paletteDataIndex+2])
-
-			if format == 0x11 {
-				paletteEntries = append(paletteEntries, &color.RGBA{
-					R: convert5to8(uint8((pixel >> 11) & 0x1F)),
-					G: convert6to8(uint8((pixel >> 5) & 0x3F)),
-					B: convert5to8(uint8((pixel & 0x1F))),
-					A: 255,
-				})
-			} else {
-				hasAlpha := pixel & 0x8000
-				if hasAlpha == 0 {
-					paletteEntries = append(paletteEntries, &color.RGBA{
-						R: convert4to8(uint8((pixel >> 8) & 0x0F)),
-						G: convert4to8(uint8((pixel >> 4) & 0x0F)),
-						B: convert4to8(uint8((pixel & 0x0F))),
-						A: convert3to8(uint8((pixel >> 12) & 0x07)),
-					})
-				} else {
-					paletteEntries = append(paletteEntries, &color.RGBA{
-						R: convert5to8(uint8((pixel >> 10) & 0x1F)),
-						G: convert5to8(uint8((pixel >> 5) & 0x1F)),
-						B: convert5to8(uint8((pixel & 0x1F))),
-						A: 255,
-					})
-				}
-			}
-			paletteDataIndex += 2
-		}
+		paletteEntries := paletteColors(format, paletteData)
 
 		//Setup for
 		bits := 0
 		for y := 0; y < int(height); y++ {
 			for x := 0; x < int(width); x++ {
+				if int(data[imageDataIndex]) >= len(paletteEntries) {
+					return nil, fmt.Errorf("%w, palette index %v is past the %v palette entries", formats.ErrInvalid, data[imageDataIndex], len(paletteEntries))
+				}
 				pixelImg := paletteEntries[uint8(data[imageDataIndex])]
 
 				blockWidth, blockHeight := 8, 4
@@ -344,9 +492,7 @@ func getTexture(width uint32, height uint32, format uint32, data []byte, palette
 				Ix := blockCol*blockWidth + (block_i % blockWidth)
 				Iy := blockRow*blockHeight + (block_i / blockWidth)
 
-				//fmt.Printf("pixel - Index: %v, X: %v, Y: %v, Color: %v\n", uint8(texture.imageData[imageDataIndex]), Ix, Iy, pixelImg)
-
-				img.Set(Ix, Iy, pixelImg)
+				img.SetNRGBA(Ix, Iy, pixelImg)
 
 				imageDataIndex++
 				bits += 4
@@ -356,45 +502,15 @@ func getTexture(width uint32, height uint32, format uint32, data []byte, palette
 		return img, nil
 
 	} else if format == 0x13 {
-		var paletteEntries []*color.RGBA
-
-		paletteDataIndex := 0
-
-		for paletteDataIndex < len(paletteData) {
-			pixel := binary.BigEndian.Uint16(paletteData[paletteDataIndex : paletteDataIndex+2])
-
-			if format == 0x13 {
-				paletteEntries = append(paletteEntries, &color.RGBA{
-					R: convert5to8(uint8((pixel >> 11) & 0x1F)),
-					G: convert6to8(uint8((pixel >> 5) & 0x3F)),
-					B: convert5to8(uint8((pixel & 0x1F))),
-					A: 255,
-				})
-			} else {
-				hasAlpha := pixel & 0x8000
-				if hasAlpha == 0 {
-					paletteEntries = append(paletteEntries, &color.RGBA{
-						R: convert4to8(uint8((pixel >> 8) & 0x0F)),
-						G: convert4to8(uint8((pixel >> 4) & 0x0F)),
-						B: convert4to8(uint8((pixel & 0x0F))),
-						A: convert3to8(uint8((pixel >> 12) & 0x07)),
-					})
-				} else {
-					paletteEntries = append(paletteEntries, &color.RGBA{
-						R: convert5to8(uint8((pixel >> 10) & 0x1F)),
-						G: convert5to8(uint8((pixel >> 5) & 0x1F)),
-						B: convert5to8(uint8((pixel & 0x1F))),
-						A: 255,
-					})
-				}
-			}
-			paletteDataIndex += 2
-		}
+		paletteEntries := paletteColors(format, paletteData)
 
 		useSecondValue := false
 		for y := 0; y < int(height); y++ {
-			for x := 0; x < int(height); x++ {
+			for x := 0; x < int(width); x++ {
 
+				if int(data[imageDataIndex]>>4) >= len(paletteEntries) || int(data[imageDataIndex]&0xF) >= len(paletteEntries) {
+					return nil, fmt.Errorf("%w, palette index in 0x%X is past the %v palette entries", formats.ErrInvalid, data[imageDataIndex], len(paletteEntries))
+				}
 				pixelImg := paletteEntries[uint8(data[imageDataIndex]>>4)]
 				pixelImg2 := paletteEntries[uint8(data[imageDataIndex]&0xF)]
 
@@ -409,14 +525,13 @@ func getTexture(width uint32, height uint32, format uint32, data []byte, palette
 				Ix := blockCol*blockWidth + (block_i % blockWidth)
 				Iy := blockRow*blockHeight + (block_i / blockWidth)
 
-				//fmt.Printf("pixel - Index: %v, X: %v, Y: %v, Color: %v\n", uint8(texture.imageData[imageDataIndex]), Ix, Iy, pixelImg)
 				if useSecondValue {
-					img.Set(Ix, Iy, pixelImg2)
+					img.SetNRGBA(Ix, Iy, pixelImg2)
 					useSecondValue = false
 					imageDataIndex++
 
 				} else {
-					img.Set(Ix, Iy, pixelImg)
+					img.SetNRGBA(Ix, Iy, pixelImg)
 					useSecondValue = true
 				}
 
@@ -430,10 +545,10 @@ func getTexture(width uint32, height uint32, format uint32, data []byte, palette
 		blockWidth, blockHeight := 8, 8
 		useSecondValue := false
 		for y := 0; y < int(height); y++ {
-			for x := 0; x < int(height); x++ {
+			for x := 0; x < int(width); x++ {
 
-				pixelImg := uint8(data[imagePixelIndex] >> 4)
-				pixelImg2 := uint8(data[imagePixelIndex] & 0xF)
+				pixelImg := uint8(data[imageDataIndex] >> 4)
+				pixelImg2 := uint8(data[imageDataIndex] & 0xF)
 
 				blockSize := blockWidth * blockHeight
 				blocksPerRow := int(width) / blockWidth
@@ -446,7 +561,7 @@ func getTexture(width uint32, height uint32, format uint32, data []byte, palette
 
 				if useSecondValue {
 					pixelImg2 = convert4to8(pixelImg2)
-					img.Set(Ix, Iy, color.RGBA{
+					img.Set(Ix, Iy, color.NRGBA{
 						R: pixelImg2,
 						G: pixelImg2,
 						B: pixelImg2,
@@ -457,7 +572,7 @@ func getTexture(width uint32, height uint32, format uint32, data []byte, palette
 
 				} else {
 					pixelImg = convert4to8(pixelImg)
-					img.Set(Ix, Iy, color.RGBA{
+					img.Set(Ix, Iy, color.NRGBA{
 						R: pixelImg,
 						G: pixelImg,
 						B: pixelImg,
@@ -474,7 +589,47 @@ func getTexture(width uint32, height uint32, format uint32, data []byte, palette
 		return img, nil
 	}
 
-	return nil, fmt.Errorf("getTexture is currently not implemented")
+	return nil, fmt.Errorf("%w, image format 0x%X", formats.ErrUnsupportedFormat, format)
+}
+
+/*
+paletteColors ...
+The colors of the palette of a palette format, RGB565 for 0x11 and 0x13 and RGB5A3 for 0x12
+*/
+func paletteColors(format uint32, paletteData []byte) []color.NRGBA {
+	var paletteEntries []color.NRGBA
+
+	for paletteDataIndex := 0; paletteDataIndex+2 <= len(paletteData); paletteDataIndex += 2 {
+		pixel := binary.BigEndian.Uint16(paletteData[paletteDataIndex : paletteDataIndex+2])
+
+		if format == 0x12 {
+			hasAlpha := pixel & 0x8000
+			if hasAlpha == 0 {
+				paletteEntries = append(paletteEntries, color.NRGBA{
+					R: convert4to8(uint8((pixel >> 8) & 0x0F)),
+					G: convert4to8(uint8((pixel >> 4) & 0x0F)),
+					B: convert4to8(uint8((pixel & 0x0F))),
+					A: convert3to8(uint8((pixel >> 12) & 0x07)),
+				})
+			} else {
+				paletteEntries = append(paletteEntries, color.NRGBA{
+					R: convert5to8(uint8((pixel >> 10) & 0x1F)),
+					G: convert5to8(uint8((pixel >> 5) & 0x1F)),
+					B: convert5to8(uint8((pixel & 0x1F))),
+					A: 255,
+				})
+			}
+		} else {
+			paletteEntries = append(paletteEntries, color.NRGBA{
+				R: convert5to8(uint8((pixel >> 11) & 0x1F)),
+				G: convert6to8(uint8((pixel >> 5) & 0x3F)),
+				B: convert5to8(uint8((pixel & 0x1F))),
+				A: 255,
+			})
+		}
+	}
+
+	return paletteEntries
 }
 
 func convert3to8(v uint8) uint8 {
@@ -492,3 +647,63 @@ func convert5to8(v uint8) uint8 {
 func convert6to8(v uint8) uint8 {
 	return (v << 2) | (v >> 4)
 }
+
+/*
+nearest ...
+Index of the palette color closest to c, alpha counts like the other channels
+*/
+func nearest(palette []color.NRGBA, c color.NRGBA) int {
+	best, bestDistance := 0, -1
+	for idx, candidate := range palette {
+		dr, dg, db := int(candidate.R)-int(c.R), int(candidate.G)-int(c.G), int(candidate.B)-int(c.B)
+		da := int(candidate.A) - int(c.A)
+		distance := dr*dr + dg*dg + db*db + da*da
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = idx, distance
+		}
+	}
+	return best
+}
+
+/*
+putNibble ...
+Set the 4 bits of pixel, the first pixel of a byte is its high nibble
+*/
+func putNibble(data []byte, pixel int, value uint8) {
+	if pixel%2 == 0 {
+		data[pixel/2] |= value << 4
+	} else {
+		data[pixel/2] |= value
+	}
+}
+
+func intensity(c color.NRGBA) uint8 {
+	return uint8((int(c.R)*299 + int(c.G)*587 + int(c.B)*114) / 1000)
+}
+
+func toRGB565(c color.NRGBA) uint16 {
+	return uint16(to5(c.R))<<11 | uint16(to6(c.G))<<5 | uint16(to5(c.B))
+}
+
+func toRGB5A3(c color.NRGBA) uint16 {
+	//A full alpha is stored without one, with five bits for every color.
+	if to3(c.A) == 7 {
+		return 0x8000 | uint16(to5(c.R))<<10 | uint16(to5(c.G))<<5 | uint16(to5(c.B))
+	}
+	return uint16(to3(c.A))<<12 | uint16(to4(c.R))<<8 | uint16(to4(c.G))<<4 | uint16(to4(c.B))
+}
+
+//The nearest value of fewer bits, the opposite of convert3to8 and the others.
+
+func to3(v uint8) uint8 { return uint8((int(v)*7 + 127) / 255) }
+func to4(v uint8) uint8 { return uint8((int(v) + 8) / 17) }
+func to5(v uint8) uint8 { return uint8((int(v)*31 + 127) / 255) }
+func to6(v uint8) uint8 { return uint8((int(v)*63 + 127) / 255) }
+
+/*
+formatError ...
+A problem of the KRT file at offset, err is one of the formats errors or wraps one
+*/
+func formatError(offset int, field string, err error) error {
+	return &formats.FormatError{Format: "krt", Offset: offset, Field: field, Err: err}
+}
@@ -0,0 +1,54 @@
+package crt
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+func FuzzReadKRTData(f *testing.F) {
+	//A texture of every image format, so each decoder sees data.
+	for _, imageFormat := range testgen.KRTFormats {
+		f.Add(testgen.KRT(imageFormat, 8, 8).Data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		format{}.Score(data)
+
+		krt, err := ReadKRTData(data)
+		if err != nil {
+			return
+		}
+		krt.WriteKRTData()
+		krt.DecodeFromKRT()
+	})
+}
+
+func TestWriteErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(krt *KRTImage)
+		offset int
+	}{
+		{"palette in the header", func(krt *KRTImage) { krt.paletteOffset = 0x10 }, 0x6C},
+		{"image in the palette", func(krt *KRTImage) { krt.imageOffset = krt.paletteOffset + 2 }, 0x70},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			krt, err := ReadKRTData(testgen.KRT(testgen.KRTC8RGB565, 8, 8).Data)
+			if err != nil {
+				t.Fatalf("reading: %v", err)
+			}
+			test.change(krt)
+
+			_, err = krt.WriteKRTData()
+			var formatErr *formats.FormatError
+			if !errors.As(err, &formatErr) || formatErr.Offset != test.offset || !errors.Is(err, formats.ErrInvalid) {
+				t.Fatalf("writing gave %v, it has to be invalid at 0x%X", err, test.offset)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package crt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+func init() {
+	formats.Register(format{})
+}
+
+/*
+format ...
+KRT in the formats registry, files are *KRTImage and works are *image.NRGBA.
+Any image.Image can be encoded to RGBA8, it goes through NRGBA first.
+It is a formats.ImageReplacer and a formats.Inspector.
+*/
+type format struct{}
+
+func (format) Name() string         { return "krt" }
+func (format) Extensions() []string { return []string{".krt"} }
+
+func (format) Probe(data []byte) bool {
+	//KRT has no magic, only its 0x20 leading zeroes and a size that can't be zero.
+	if len(data) < 0xA0 || !bytes.Equal(data[:0x20], make([]byte, 0x20)) {
+		return false
+	}
+	width := binary.BigEndian.Uint32(data[0x20:0x24])
+	height := binary.BigEndian.Uint32(data[0x24:0x28])
+	return width != 0 && height != 0
+}
+
+func (format) Read(data []byte) (interface{}, error) {
+	return ReadKRTData(data)
+}
+
+func (format) Write(file interface{}) ([]byte, error) {
+	krt, ok := file.(*KRTImage)
+	if !ok {
+		return nil, formats.WrongType("krt", "*crt.KRTImage", file)
+	}
+	return krt.WriteKRTData()
+}
+
+func (format) Decode(file interface{}) (interface{}, error) {
+	krt, ok := file.(*KRTImage)
+	if !ok {
+		return nil, formats.WrongType("krt", "*crt.KRTImage", file)
+	}
+	return krt.DecodeFromKRT()
+}
+
+func (format) Encode(work interface{}) (interface{}, error) {
+	img, ok := work.(image.Image)
+	if !ok {
+		return nil, formats.WrongType("krt", "image.Image", work)
+	}
+	return EncodeToKRT(img)
+}
+
+func (format) Inspect(file interface{}) (*formats.Inspection, error) {
+	krt, ok := file.(*KRTImage)
+	if !ok {
+		return nil, formats.WrongType("krt", "*crt.KRTImage", file)
+	}
+	return krt.Inspect(), nil
+}
+
+/*
+Score ...
+Rate how plausible the KRT header is. Zeroes, sizes and offsets that fit the file and a
+known image format each add to the score, so a run of zeroes alone isn't enough.
+*/
+func (krt format) Score(data []byte) float64 {
+	if !krt.Probe(data) {
+		return 0
+	}
+
+	score := 0.2
+
+	width := binary.BigEndian.Uint32(data[0x20:0x24])
+	height := binary.BigEndian.Uint32(data[0x24:0x28])
+	if width <= 4096 && height <= 4096 && width%4 == 0 && height%4 == 0 {
+		score += 0.2
+	}
+
+	switch binary.BigEndian.Uint32(data[0x28:0x2C]) {
+	case 0xF, 0x10, 0x11, 0x12, 0x13, 0x16, 0x17:
+		score += 0.2
+	}
+
+	switch binary.BigEndian.Uint16(data[0x2C:0x2E]) {
+	case 16, 32, 64:
+		score += 0.1
+	}
+
+	paletteOffset := binary.BigEndian.Uint32(data[0x6C:0x70])
+	imageOffset := binary.BigEndian.Uint32(data[0x70:0x74])
+	fileSize := binary.BigEndian.Uint32(data[0x74:0x78])
+	if imageOffset >= 0x78 && imageOffset <= fileSize && fileSize <= uint32(len(data)) && (paletteOffset == 0 || (paletteOffset >= 0x78 && paletteOffset <= imageOffset)) {
+		score += 0.2
+	}
+
+	return score
+}
+
+/*
+ReplaceImage ...
+Encode img into a KRT texture of the same size in its image format, keeping its header,
+palette and everything around the image data as it was.
+*/
+func (format) ReplaceImage(data []byte, index int, img image.Image) ([]byte, error) {
+	krt, err := ReadKRTData(data)
+	if err != nil {
+		return nil, err
+	}
+	if index != 0 {
+		return nil, fmt.Errorf("krt textures hold one image, there is no image %v", index)
+	}
+	if uint32(img.Bounds().Dx()) != krt.width || uint32(img.Bounds().Dy()) != krt.height {
+		return nil, fmt.Errorf("the texture is %vx%v, a %vx%v image can't replace it", krt.width, krt.height, img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(nrgba, nrgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	encoded, err := encodeTexture(krt.imageFormat, nrgba, krt.paletteData)
+	if err != nil {
+		return nil, formatError(0x28, "image format", err)
+	}
+
+	end := int(krt.imageOffset) + len(encoded)
+	if end > len(data) {
+		return nil, formatError(int(krt.imageOffset), "image data", fmt.Errorf("%w, it ends at 0x%X of 0x%X bytes", formats.ErrTruncated, end, len(data)))
+	}
+	out := append([]byte{}, data...)
+	copy(out[krt.imageOffset:], encoded)
+
+	return out, nil
+}
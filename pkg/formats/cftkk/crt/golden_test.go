@@ -0,0 +1,157 @@
+package crt
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+)
+
+func TestGoldenFormats(t *testing.T) {
+	for _, format := range testgen.KRTFormats {
+		t.Run(fmt.Sprintf("format 0x%X", format), func(t *testing.T) {
+			texture := testgen.KRT(format, 16, 8)
+
+			krt, err := ReadKRTData(texture.Data)
+			if err != nil {
+				t.Fatalf("reading: %v", err)
+			}
+
+			written, err := krt.WriteKRTData()
+			if err != nil {
+				t.Fatalf("writing: %v", err)
+			}
+			if !bytes.Equal(written, texture.Data) {
+				t.Fatalf("writing changed the texture, 0x%X bytes became 0x%X", len(texture.Data), len(written))
+			}
+			if _, err = ReadKRTData(written); err != nil {
+				t.Fatalf("reading the written texture: %v", err)
+			}
+
+			img, err := krt.DecodeFromKRT()
+			if err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+			comparePixels(t, img, texture.Images[0])
+		})
+	}
+}
+
+/*
+TestGoldenHeader ...
+The flags, the image size and the padding of the header don't always hold what is usual, writing
+has to keep them as they were read
+*/
+func TestGoldenHeader(t *testing.T) {
+	texture := testgen.KRT(testgen.KRTC8RGB565, 16, 8)
+	data := texture.Data
+	data[0x2E], data[0x2F] = 0x02, 0x00
+	data[0x31] = 0x5A
+	data[0x36] = 0x01
+	data[0x3A], data[0x3B] = 0x12, 0x34
+	data[0x40] = 0xA5
+	data[0x90] = 0x7E
+
+	krt, err := ReadKRTData(data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	written, err := krt.WriteKRTData()
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	if !bytes.Equal(written, data) {
+		for idx := range data {
+			if idx < len(written) && written[idx] != data[idx] {
+				t.Fatalf("writing changed byte 0x%X from 0x%02X to 0x%02X", idx, data[idx], written[idx])
+			}
+		}
+		t.Fatalf("writing changed the texture, 0x%X bytes became 0x%X", len(data), len(written))
+	}
+
+	//Encoding a new image into it keeps them too.
+	replaced, err := format{}.ReplaceImage(data, 0, texture.Images[0])
+	if err != nil {
+		t.Fatalf("encoding: %v", err)
+	}
+	if !bytes.Equal(replaced[:krt.imageOffset], data[:krt.imageOffset]) {
+		t.Fatalf("encoding changed the header")
+	}
+}
+
+func TestReplaceImage(t *testing.T) {
+	for _, imageFormat := range testgen.KRTFormats {
+		t.Run(fmt.Sprintf("format 0x%X", imageFormat), func(t *testing.T) {
+			texture := testgen.KRT(imageFormat, 16, 8)
+
+			//A color that isn't in the palette gets the nearest one that is, here the opaque red.
+			img := image.NewNRGBA(texture.Images[0].Rect)
+			copy(img.Pix, texture.Images[0].Pix)
+			want := img
+			if imageFormat == testgen.KRTC8RGB5A3 {
+				want = image.NewNRGBA(img.Rect)
+				copy(want.Pix, img.Pix)
+				img.SetNRGBA(0, 0, color.NRGBA{0xF0, 0x08, 0x08, 0xF8})
+				want.SetNRGBA(0, 0, color.NRGBA{0xFF, 0x00, 0x00, 0xFF})
+			}
+
+			out, err := format{}.ReplaceImage(texture.Data, 0, img)
+			if err != nil {
+				t.Fatalf("encoding: %v", err)
+			}
+			//The header and the palette before the image data stay as they were.
+			krt, err := ReadKRTData(out)
+			if err != nil {
+				t.Fatalf("reading the encoded texture: %v", err)
+			}
+			if len(out) != len(texture.Data) || !bytes.Equal(out[:krt.imageOffset], texture.Data[:krt.imageOffset]) {
+				t.Fatalf("encoding changed the texture around the image data")
+			}
+
+			got, err := krt.DecodeFromKRT()
+			if err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+			comparePixels(t, got, want)
+		})
+	}
+}
+
+func comparePixels(t *testing.T, got *image.NRGBA, want *image.NRGBA) {
+	t.Helper()
+
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("the image is %v, it has to be %v", got.Bounds(), want.Bounds())
+	}
+	for y := 0; y < want.Bounds().Dy(); y++ {
+		for x := 0; x < want.Bounds().Dx(); x++ {
+			if got.NRGBAAt(x, y) != want.NRGBAAt(x, y) {
+				t.Fatalf("pixel %v,%v is %v, it has to be %v", x, y, got.NRGBAAt(x, y), want.NRGBAAt(x, y))
+			}
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	names := map[uint32]string{
+		testgen.KRTRGBA8:    "RGBA8 (0xF)",
+		testgen.KRTRGB5A3:   "RGB5A3 (0x10)",
+		testgen.KRTC8RGB565: "CI8 RGB565 (0x11)",
+		testgen.KRTC8RGB5A3: "CI8 RGB5A3 (0x12)",
+		testgen.KRTC4RGB565: "CI4 RGB565 (0x13)",
+		testgen.KRTI4:       "I4 (0x16)",
+		testgen.KRTRGB565:   "RGB565 (0x17)",
+	}
+	for _, imageFormat := range testgen.KRTFormats {
+		krt, err := ReadKRTData(testgen.KRT(imageFormat, 8, 8).Data)
+		if err != nil {
+			t.Fatalf("reading: %v", err)
+		}
+		if got := krt.Inspect().Sections[0].Fields[2].Value; got != names[imageFormat] {
+			t.Fatalf("format 0x%X is inspected as %v, not %v", imageFormat, got, names[imageFormat])
+		}
+	}
+}
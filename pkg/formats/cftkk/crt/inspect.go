@@ -0,0 +1,50 @@
+package crt
+
+import (
+	"fmt"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+var imageFormatNames = map[uint32]string{
+	0xF:  "RGBA8",
+	0x10: "RGB5A3",
+	0x11: "CI8 RGB565",
+	0x12: "CI8 RGB5A3",
+	0x13: "CI4 RGB565",
+	0x16: "I4",
+	0x17: "RGB565",
+}
+
+/*
+Inspect ...
+Show every field of the KRT header, the unknown ones included, and the size of the palette and
+image data they point at
+*/
+func (image *KRTImage) Inspect() *formats.Inspection {
+	inspection := formats.NewInspection("krt")
+
+	format := fmt.Sprintf("0x%X", image.imageFormat)
+	if name, ok := imageFormatNames[image.imageFormat]; ok {
+		format = fmt.Sprintf("%v (0x%X)", name, image.imageFormat)
+	}
+
+	inspection.Section("header").
+		Field("width", image.width).
+		Field("height", image.height).
+		Field("image format", format).
+		Field("block size", image.blockSize).
+		Field("unknown 1", formats.Hex(image.unknown1)).
+		Field("unknown 2", formats.Hex(image.unknown2)).
+		Field("unknown 3", formats.Hex(image.unknown3)).
+		Field("image size", formats.Hex(image.imageSize)).
+		Field("palette offset", formats.Hex(image.paletteOffset)).
+		Field("image offset", formats.Hex(image.imageOffset)).
+		Field("file size", formats.Hex(image.fileSize))
+
+	inspection.Section("data").
+		Field("palette size", formats.Hex(len(image.paletteData))).
+		Field("image size", formats.Hex(len(image.imageData)))
+
+	return inspection
+}
@@ -0,0 +1,68 @@
+package fetm
+
+/*
+The entity classes of Creature from the Krusty Krab the tools know. Only the start of their
+parameters is known: the position of the entity and the name it goes by, which Diff and Merge
+match nodes with. These layouts are provisional, they haven't been checked against the levels
+of the game yet, so they are only registered by RegisterKrustyKrabClasses. Everything after
+them is kept in Rest as it was read.
+*/
+
+/*
+Vector ...
+A point or a size in a level, three f32 parameters
+*/
+type Vector struct {
+	X F32
+	Y F32
+	Z F32
+}
+
+/*
+Pickup ...
+An item the player collects
+*/
+type Pickup struct {
+	Position Vector
+	Name     Str
+	Rest     []Variant
+}
+
+/*
+Spawner ...
+A place enemies or items appear at
+*/
+type Spawner struct {
+	Position Vector
+	Name     Str
+	Rest     []Variant
+}
+
+/*
+Trigger ...
+A box that starts an event when the player enters it, Size is its extent around Position
+*/
+type Trigger struct {
+	Position Vector
+	Size     Vector
+	Name     Str
+	Rest     []Variant
+}
+
+/*
+RegisterKrustyKrabClasses ...
+Register Pickup, Spawner and Trigger. Until a program does, every node is a RawEntity whose
+parameters are addressed by index.
+*/
+func RegisterKrustyKrabClasses() {
+	for class, layout := range map[string]interface{}{
+		"Pickup":  Pickup{},
+		"Spawner": Spawner{},
+		"Trigger": Trigger{},
+	} {
+		err := RegisterEntityClass(class, layout)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
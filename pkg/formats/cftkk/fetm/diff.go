@@ -0,0 +1,439 @@
+package fetm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ChangeKind uint8
+
+const (
+	Modified ChangeKind = 0x00
+	Added    ChangeKind = 0x01
+	Removed  ChangeKind = 0x02
+	Moved    ChangeKind = 0x03
+)
+
+/*
+FieldChange ...
+One value that differs, Path names it like FieldNames does. Old holds no data for an
+added parameter and New holds none for a removed one.
+*/
+type FieldChange struct {
+	Path string
+	Old  Variant
+	New  Variant
+}
+
+/*
+NodeChange ...
+A node that was added, removed, modified or moved. OldIndex and NewIndex are its index in the
+Nodes of either file, -1 where it doesn't exist. Node is the node as it is in the new file,
+or in the old one when it was removed. A moved node also lists its changed parameters.
+*/
+type NodeChange struct {
+	Kind     ChangeKind
+	OldIndex int
+	NewIndex int
+	Node     Node
+	Fields   []FieldChange
+}
+
+/*
+Difference ...
+Every change between two FETM files, see Diff
+*/
+type Difference struct {
+	Header []FieldChange
+	World  []FieldChange
+	Sector []FieldChange
+	Nodes  []NodeChange
+}
+
+/*
+Diff ...
+Compare two FETM files value by value. Nodes are matched by identity rather than position,
+see matchNodes, so inserting or moving a node reports that node and not a change to every
+node after it. Matched nodes report their changed parameters.
+*/
+func Diff(a *FETM, b *FETM) *Difference {
+	diff := &Difference{}
+
+	diff.Header = diffValues(headerValues(a), headerValues(b), headerNames)
+
+	diff.World = diffValues([]Variant{a.World.Name}, []Variant{b.World.Name}, []string{"name"})
+	diff.World = append(diff.World, diffValues(a.World.Params, b.World.Params, nil)...)
+
+	diff.Sector = diffValues([]Variant{a.Sector.Name}, []Variant{b.Sector.Name}, []string{"name"})
+	diff.Sector = append(diff.Sector, prefixPaths("lead.", diffValues(a.Sector.Lead, b.Sector.Lead, nil))...)
+	diff.Sector = append(diff.Sector, diffValues(a.Sector.Params, b.Sector.Params, nil)...)
+
+	pairs := matchNodes(a.Nodes, b.Nodes)
+	moved := movedPairs(pairs)
+	for idx, pair := range pairs {
+		switch {
+		case pair.old < 0:
+			diff.Nodes = append(diff.Nodes, NodeChange{Kind: Added, OldIndex: -1, NewIndex: pair.new, Node: b.Nodes[pair.new]})
+		case pair.new < 0:
+			diff.Nodes = append(diff.Nodes, NodeChange{Kind: Removed, OldIndex: pair.old, NewIndex: -1, Node: a.Nodes[pair.old]})
+		default:
+			fields := diffNode(&a.Nodes[pair.old], &b.Nodes[pair.new])
+			if moved[idx] {
+				diff.Nodes = append(diff.Nodes, NodeChange{Kind: Moved, OldIndex: pair.old, NewIndex: pair.new, Node: b.Nodes[pair.new], Fields: fields})
+			} else if len(fields) > 0 {
+				diff.Nodes = append(diff.Nodes, NodeChange{Kind: Modified, OldIndex: pair.old, NewIndex: pair.new, Node: b.Nodes[pair.new], Fields: fields})
+			}
+		}
+	}
+
+	return diff
+}
+
+/*
+Empty ...
+Return whether the two files were the same
+*/
+func (diff *Difference) Empty() bool {
+	return len(diff.Header) == 0 && len(diff.World) == 0 && len(diff.Sector) == 0 && len(diff.Nodes) == 0
+}
+
+/*
+String ...
+Return the changes one per line, in the literals of the text format
+*/
+func (diff *Difference) String() string {
+	var builder strings.Builder
+
+	writeFields := func(prefix string, fields []FieldChange) {
+		for _, field := range fields {
+			fmt.Fprintf(&builder, "%v%v: %v -> %v\n", prefix, field.Path, describeText(field.Old), describeText(field.New))
+		}
+	}
+
+	writeFields("header ", diff.Header)
+	writeFields("world ", diff.World)
+	writeFields("sector ", diff.Sector)
+
+	for _, change := range diff.Nodes {
+		switch change.Kind {
+		case Added:
+			fmt.Fprintf(&builder, "+ node %v %v\n", change.NewIndex, change.Node.identity())
+		case Removed:
+			fmt.Fprintf(&builder, "- node %v %v\n", change.OldIndex, change.Node.identity())
+		case Modified:
+			fmt.Fprintf(&builder, "~ node %v %v\n", change.NewIndex, change.Node.identity())
+			writeFields("\t", change.Fields)
+		case Moved:
+			fmt.Fprintf(&builder, "> node %v %v, was node %v\n", change.NewIndex, change.Node.identity(), change.OldIndex)
+			writeFields("\t", change.Fields)
+		}
+	}
+
+	return builder.String()
+}
+
+var headerNames = []string{"magic", "note", "sectioncount", "unknown"}
+
+func headerValues(data *FETM) []Variant {
+	return []Variant{data.Header.Magic, data.Header.Note, data.Header.SectionCount, data.Header.Unknown}
+}
+
+func describeText(variant Variant) string {
+	if variant.Data == nil {
+		return "nothing"
+	}
+	return formatText(variant.Data)
+}
+
+func (node *Node) key() string {
+	return describeText(node.NodeType) + " " + describeText(node.NodeFactory) + " " + describeText(node.EntityClass)
+}
+
+/*
+identity ...
+The key of a node followed by its name, for nodes of a registered class with a name field
+*/
+func (node *Node) identity() string {
+	name, err := node.Field("name")
+	if err != nil {
+		return node.key()
+	}
+	return node.key() + " " + describeText(Variant{name})
+}
+
+func (node *Node) named() bool {
+	_, err := node.Field("name")
+	return err == nil
+}
+
+func sameValue(a Variant, b Variant) bool {
+	if a.Data == nil || b.Data == nil {
+		return a.Data == nil && b.Data == nil
+	}
+	if a.Data.ident() != b.Data.ident() {
+		return false
+	}
+	//Compare floats by their bits, so NaNs equal themselves and 0 and -0 differ like they do in the file.
+	if value, ok := a.Data.(F32); ok {
+		return math.Float32bits(float32(value)) == math.Float32bits(float32(b.Data.(F32)))
+	}
+	return a.Data == b.Data
+}
+
+func sameValues(a []Variant, b []Variant) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if !sameValue(a[idx], b[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+diffValues ...
+Compare two value lists position by position, names gives the path of each position
+and the index is used past its end
+*/
+func diffValues(a []Variant, b []Variant, names []string) []FieldChange {
+	var changes []FieldChange
+
+	count := len(a)
+	if len(b) > count {
+		count = len(b)
+	}
+
+	for idx := 0; idx < count; idx++ {
+		var oldValue, newValue Variant
+		if idx < len(a) {
+			oldValue = a[idx]
+		}
+		if idx < len(b) {
+			newValue = b[idx]
+		}
+		if sameValue(oldValue, newValue) {
+			continue
+		}
+
+		path := strconv.Itoa(idx)
+		if idx < len(names) {
+			path = names[idx]
+		}
+		changes = append(changes, FieldChange{Path: path, Old: oldValue, New: newValue})
+	}
+
+	return changes
+}
+
+func diffNode(a *Node, b *Node) []FieldChange {
+	names := b.FieldNames()
+	if len(a.DependentData) > len(b.DependentData) {
+		names = a.FieldNames()
+	}
+	return diffValues(a.DependentData, b.DependentData, names)
+}
+
+func prefixPaths(prefix string, changes []FieldChange) []FieldChange {
+	for idx := range changes {
+		changes[idx].Path = prefix + changes[idx].Path
+	}
+	return changes
+}
+
+type nodePair struct {
+	old int
+	new int
+}
+
+/*
+matchNodes ...
+Line the nodes of two files up. A named node, see identity, whose name is used once in
+either file is matched with the node of that name wherever it is, one whose name is only in
+one file was added or removed. The other nodes, unnamed ones and names that repeat, are
+lined up by the longest common subsequence of their keys. Every node of either list is in
+the result once, in the order of b with the nodes only a has in front of the node that
+followed them in a, matched or paired with -1.
+*/
+func matchNodes(a []Node, b []Node) []nodePair {
+	oldOf := make([]int, len(b))
+	for idx := range oldOf {
+		oldOf[idx] = -1
+	}
+	newOf := make([]int, len(a))
+	for idx := range newOf {
+		newOf[idx] = -1
+	}
+
+	oldCount, newCount := countIdentities(a), countIdentities(b)
+	named := make(map[string]int)
+	for idx := range a {
+		if a[idx].named() && oldCount[a[idx].identity()] == 1 {
+			named[a[idx].identity()] = idx
+		}
+	}
+
+	var oldRest, newRest []int
+	for idx := range b {
+		identity := b[idx].identity()
+		switch {
+		case !b[idx].named() || newCount[identity] > 1 || oldCount[identity] > 1:
+			newRest = append(newRest, idx)
+		case oldCount[identity] == 1:
+			oldOf[idx] = named[identity]
+			newOf[named[identity]] = idx
+		}
+	}
+	for idx := range a {
+		identity := a[idx].identity()
+		if !a[idx].named() || oldCount[identity] > 1 || newCount[identity] > 1 {
+			oldRest = append(oldRest, idx)
+		}
+	}
+
+	for _, pair := range matchKeys(a, b, oldRest, newRest) {
+		oldOf[pair.new] = pair.old
+		newOf[pair.old] = pair.new
+	}
+
+	var pairs []nodePair
+	next := 0
+	for idx := range b {
+		//Nodes only a has go in front of the next matched node that followed them in a.
+		for ; next < oldOf[idx]; next++ {
+			if newOf[next] < 0 {
+				pairs = append(pairs, nodePair{next, -1})
+			}
+		}
+		pairs = append(pairs, nodePair{oldOf[idx], idx})
+	}
+	for ; next < len(a); next++ {
+		if newOf[next] < 0 {
+			pairs = append(pairs, nodePair{next, -1})
+		}
+	}
+
+	return pairs
+}
+
+func countIdentities(nodes []Node) map[string]int {
+	counts := make(map[string]int)
+	for idx := range nodes {
+		if nodes[idx].named() {
+			counts[nodes[idx].identity()]++
+		}
+	}
+	return counts
+}
+
+/*
+matchKeys ...
+Match the nodes of a at oldIndices with the nodes of b at newIndices by the longest
+common subsequence of their keys, return the matched pairs in order
+*/
+func matchKeys(a []Node, b []Node, oldIndices []int, newIndices []int) []nodePair {
+	var pairs []nodePair
+
+	//Levels are mostly unchanged, leaving the equal ends out keeps the table small.
+	prefix := 0
+	for prefix < len(oldIndices) && prefix < len(newIndices) && a[oldIndices[prefix]].key() == b[newIndices[prefix]].key() {
+		pairs = append(pairs, nodePair{oldIndices[prefix], newIndices[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldIndices)-prefix && suffix < len(newIndices)-prefix && a[oldIndices[len(oldIndices)-1-suffix]].key() == b[newIndices[len(newIndices)-1-suffix]].key() {
+		suffix++
+	}
+
+	oldKeys := make([]string, len(oldIndices)-prefix-suffix)
+	for idx := range oldKeys {
+		oldKeys[idx] = a[oldIndices[prefix+idx]].key()
+	}
+	newKeys := make([]string, len(newIndices)-prefix-suffix)
+	for idx := range newKeys {
+		newKeys[idx] = b[newIndices[prefix+idx]].key()
+	}
+
+	//lengths[i][j] is the common subsequence length of oldKeys[i:] and newKeys[j:].
+	lengths := make([][]int, len(oldKeys)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(newKeys)+1)
+	}
+	for i := len(oldKeys) - 1; i >= 0; i-- {
+		for j := len(newKeys) - 1; j >= 0; j-- {
+			if oldKeys[i] == newKeys[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(oldKeys) && j < len(newKeys) {
+		switch {
+		case oldKeys[i] == newKeys[j]:
+			pairs = append(pairs, nodePair{oldIndices[prefix+i], newIndices[prefix+j]})
+			i++
+			j++
+		case lengths[i][j+1] >= lengths[i+1][j]:
+			j++
+		default:
+			i++
+		}
+	}
+
+	for idx := suffix; idx > 0; idx-- {
+		pairs = append(pairs, nodePair{oldIndices[len(oldIndices)-idx], newIndices[len(newIndices)-idx]})
+	}
+
+	return pairs
+}
+
+/*
+movedPairs ...
+Return the indices of the matched pairs that changed their order. The longest run of matched
+pairs whose old indices go up stays in place, every other matched pair moved.
+*/
+func movedPairs(pairs []nodePair) map[int]bool {
+	//tails[n] is the pair ending the best run of length n+1 found so far, parents links the runs back.
+	var tails []int
+	parents := make([]int, len(pairs))
+	for idx, pair := range pairs {
+		if pair.old < 0 || pair.new < 0 {
+			continue
+		}
+		length := sort.Search(len(tails), func(n int) bool { return pairs[tails[n]].old >= pair.old })
+		parents[idx] = -1
+		if length > 0 {
+			parents[idx] = tails[length-1]
+		}
+		if length == len(tails) {
+			tails = append(tails, idx)
+		} else {
+			tails[length] = idx
+		}
+	}
+
+	kept := make(map[int]bool)
+	if len(tails) > 0 {
+		for idx := tails[len(tails)-1]; idx >= 0; idx = parents[idx] {
+			kept[idx] = true
+		}
+	}
+
+	moved := make(map[int]bool)
+	for idx, pair := range pairs {
+		if pair.old >= 0 && pair.new >= 0 && !kept[idx] {
+			moved[idx] = true
+		}
+	}
+
+	return moved
+}
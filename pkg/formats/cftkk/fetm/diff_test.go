@@ -0,0 +1,104 @@
+package fetm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func pickup(name string, x float32) Node {
+	return Node{
+		NodeType:      Variant{U16(1)},
+		NodeFactory:   Variant{Str("Factory")},
+		EntityClass:   Variant{Str("Pickup")},
+		DependentData: []Variant{{F32(x)}, {F32(0)}, {F32(0)}, {Str(name)}},
+	}
+}
+
+/*
+unnamed ...
+A node of a class that isn't registered, it can only be matched by its key
+*/
+func unnamed(value uint8) Node {
+	return Node{
+		NodeType:      Variant{U16(2)},
+		NodeFactory:   Variant{Str("x")},
+		EntityClass:   Variant{Str("Unknown")},
+		DependentData: []Variant{{U8(value)}},
+	}
+}
+
+func levelOf(t *testing.T, nodes ...Node) *FETM {
+	t.Helper()
+
+	file := readLevel(t)
+	file.Nodes = nodes
+	return file
+}
+
+func describeChanges(changes []NodeChange) []string {
+	var described []string
+	for _, change := range changes {
+		var paths []string
+		for _, field := range change.Fields {
+			paths = append(paths, field.Path)
+		}
+		described = append(described, fmt.Sprintf("%v %v->%v %v", change.Kind, change.OldIndex, change.NewIndex, strings.Join(paths, ",")))
+	}
+	return described
+}
+
+func TestDiffNodes(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []Node
+		b    []Node
+		want []string
+	}{
+		{"same", []Node{pickup("coin", 0), pickup("gem", 0)}, []Node{pickup("coin", 0), pickup("gem", 0)}, nil},
+		{"insert", []Node{pickup("coin", 0), pickup("gem", 1)}, []Node{pickup("ruby", 2), pickup("coin", 0), pickup("gem", 1)},
+			[]string{"1 -1->0 "}},
+		{"delete", []Node{pickup("coin", 0), pickup("gem", 1), pickup("ruby", 2)}, []Node{pickup("coin", 0), pickup("ruby", 2)},
+			[]string{"2 1->-1 "}},
+		{"reorder", []Node{pickup("coin", 0), pickup("gem", 1), pickup("ruby", 2)}, []Node{pickup("ruby", 2), pickup("coin", 0), pickup("gem", 1)},
+			[]string{"3 2->0 "}},
+		{"reorder and edit", []Node{pickup("coin", 0), pickup("gem", 1)}, []Node{pickup("gem", 1), pickup("coin", 5)},
+			[]string{"3 1->0 ", "0 0->1 position.x"}},
+		{"rename", []Node{pickup("coin", 0)}, []Node{pickup("gem", 0)},
+			[]string{"1 -1->0 ", "2 0->-1 "}},
+		{"unnamed by position", []Node{unnamed(1), unnamed(2)}, []Node{unnamed(1), unnamed(3), unnamed(2)},
+			[]string{"0 1->1 0", "1 -1->2 "}},
+		{"unnamed around named", []Node{unnamed(1), pickup("coin", 0)}, []Node{pickup("coin", 0), unnamed(1)},
+			[]string{"3 1->0 "}},
+		{"repeated names by position", []Node{pickup("coin", 0), pickup("coin", 1)}, []Node{pickup("coin", 1)},
+			[]string{"0 0->0 position.x", "2 1->-1 "}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff := Diff(levelOf(t, test.a...), levelOf(t, test.b...))
+			if got := describeChanges(diff.Nodes); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("the changes are %q, not %q\n%v", got, test.want, diff)
+			}
+			if diff.Empty() != (len(test.want) == 0) {
+				t.Fatalf("Empty is %v with %v changes", diff.Empty(), len(test.want))
+			}
+		})
+	}
+}
+
+func TestDiffString(t *testing.T) {
+	a := levelOf(t, pickup("coin", 0), pickup("gem", 1))
+	b := levelOf(t, pickup("gem", 1), pickup("coin", 5), pickup("ruby", 2))
+	b.World.Params[1] = Variant{S8(3)}
+
+	want := "world 1: s8 -2 -> s8 3\n" +
+		"> node 0 u16 1 str \"Factory\" str \"Pickup\" str \"gem\", was node 1\n" +
+		"~ node 1 u16 1 str \"Factory\" str \"Pickup\" str \"coin\"\n" +
+		"\tposition.x: f32 0 -> f32 5\n" +
+		"+ node 2 u16 1 str \"Factory\" str \"Pickup\" str \"ruby\"\n"
+	if got := Diff(a, b).String(); got != want {
+		t.Fatalf("the diff is\n%v\nnot\n%v", got, want)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	"reflect"
+	"strings"
+
+	"github.com/ProfElements/go-files/pkg/formats"
 )
 
 type identifer uint8
@@ -21,34 +23,46 @@ const (
 	str identifer = 0x07
 )
 
-//Make this structure more workable for encoding to json.
-type variantData struct {
-	Ident identifer
-	Data  interface{}
-}
-
-type header struct {
-	Magic        variantData
-	Note         variantData
-	SectionCount variantData
-	unknown      variantData
-	Raw          []variantData
+/*
+Header ...
+The four values every FETM starts with.
+*/
+type Header struct {
+	Magic        Variant
+	Note         Variant
+	SectionCount Variant
+	Unknown      Variant
 }
 
-type world struct {
-	World variantData
-	Raw   []variantData
+/*
+World ...
+The world block, the "world" marker and the values that follow it up to the sector.
+*/
+type World struct {
+	Name   Variant
+	Params []Variant
 }
 
-type sector struct {
-	Raw []variantData
+/*
+Sector ...
+The sector block. Lead holds the two values in front of the "World Sector" marker,
+Params the values between the marker and the first node.
+*/
+type Sector struct {
+	Lead   []Variant
+	Name   Variant
+	Params []Variant
 }
 
-type node struct {
-	NodeType      variantData
-	NodeFactory   variantData
-	EntityClass   variantData
-	DependentData []variantData
+/*
+Node ...
+One node of the sector node list, DependentData holds the parameters of its entity class.
+*/
+type Node struct {
+	NodeType      Variant
+	NodeFactory   Variant
+	EntityClass   Variant
+	DependentData []Variant
 }
 
 /*
@@ -56,11 +70,10 @@ FETM ...
 FETM file structure to read and write to and from files.
 */
 type FETM struct {
-	Header header
-	World  world
-	Sector sector
-	Nodes  []variantData //make this nodes later
-	Raw    []variantData
+	Header Header
+	World  World
+	Sector Sector
+	Nodes  []Node
 }
 
 /*
@@ -68,101 +81,29 @@ Read ...
 Read data from file and return a FETM structure pointer, or error
 */
 func Read(data []byte) (*FETM, error) {
-	fetm := &FETM{}
-
 	if len(data) < 3 {
-		return nil, fmt.Errorf("data is not long enough to be considered a FETM\n")
+		return nil, formatError(0, "magic", fmt.Errorf("%w, it is 3 bytes and there are %v", formats.ErrTruncated, len(data)))
 	}
 
 	if !bytes.Equal(data[:3], []byte{0x01, 0x7C, 0x07}) {
-		return nil, fmt.Errorf("this file does not have FETM header magic\n")
+		return nil, formatError(0, "magic", fmt.Errorf("%w, % X isn't 01 7C 07", formats.ErrBadMagic, data[:3]))
 	}
 
-	readIndex := 0
-
-	for len(data) > readIndex {
-
-		curIdent := identifer(uint8(data[readIndex]))
-
-		//fmt.Printf("curIdent %v\n", curIdent)
-
-		switch curIdent {
-		case s8:
-			fetm.Raw = append(fetm.Raw, variantData{curIdent, int8(data[readIndex+1])})
-			readIndex += 2
-		case u8:
-			fetm.Raw = append(fetm.Raw, variantData{curIdent, uint8(data[readIndex+1])})
-			readIndex += 2
-		case s16:
-			var val int16
-			buf := bytes.NewReader(data[readIndex : readIndex+1])
-			binary.Read(buf, binary.BigEndian, &val)
-
-			fetm.Raw = append(fetm.Raw, variantData{curIdent, val})
-			readIndex += 2
-		case u16:
-			var val uint16
-			buf := bytes.NewReader(data[readIndex+1 : readIndex+3])
-			binary.Read(buf, binary.BigEndian, &val)
-
-			fetm.Raw = append(fetm.Raw, variantData{curIdent, val})
-			readIndex += 3
-		case u32:
-			var val uint32
-			buf := bytes.NewReader(data[readIndex+1 : readIndex+5])
-			binary.Read(buf, binary.BigEndian, &val)
-
-			fetm.Raw = append(fetm.Raw, variantData{curIdent, val})
-			readIndex += 5
-		case hex:
-			var val uint32
-			buf := bytes.NewReader(data[readIndex+1 : readIndex+5])
-			binary.Read(buf, binary.BigEndian, &val)
-
-			fetm.Raw = append(fetm.Raw, variantData{curIdent, val})
-			readIndex += 5
-		case f32:
-			var val float32
-			buf := bytes.NewReader(data[readIndex+1 : readIndex+5])
-			binary.Read(buf, binary.BigEndian, &val)
-
-			fetm.Raw = append(fetm.Raw, variantData{curIdent, val})
-			readIndex += 5
-		case str:
-			strData := findStr(data, readIndex)
-
-			fetm.Raw = append(fetm.Raw, variantData{curIdent, strData})
-			if len(strData) == 1 {
-				readIndex++
-			} else {
-				readIndex += len(strData) + 2
-			}
-
-		default:
-			return nil, fmt.Errorf("unknown idenifier %v at %x", curIdent, readIndex)
-		}
-
+	tokens, err := Tokenize(data)
+	if err != nil {
+		return nil, err
 	}
 
-	fetm.Header.Magic = fetm.Raw[0]
-	fetm.Header.Note = fetm.Raw[1]
-	fetm.Header.SectionCount = fetm.Raw[2]
-	fetm.Header.unknown = fetm.Raw[3]
-	fetm.Header.Raw = fetm.Raw[0:3]
-
-	if reflect.TypeOf(fetm.Raw[4].Data).String() == "string" && fetm.Raw[4].Data.(string) == "world" {
-		fetm.World.World = fetm.Raw[4]
+	if len(tokens) < 5 {
+		return nil, formatError(len(data), "values", fmt.Errorf("%w, there are only %v, a FETM needs at least a header and a world", formats.ErrTruncated, len(tokens)))
+	}
 
-		for idx, value := range fetm.Raw {
-			if reflect.TypeOf(value.Data).String() == "string" && value.Data.(string) == "World Sector" {
-				fetm.World.Raw = fetm.Raw[4 : idx-2]
-				//This includes nodes as of right due to not being able to look at source to figure out how they find nodes
-				fetm.Sector.Raw = fetm.Raw[idx-2:]
-			}
-		}
+	raw := make([]Variant, len(tokens))
+	for idx, token := range tokens {
+		raw[idx] = token.Variant
 	}
 
-	return fetm, nil
+	return parse(raw)
 }
 
 /*
@@ -172,27 +113,39 @@ Write FETM data to file or error
 func (data *FETM) Write() ([]byte, error) {
 	var buffer bytes.Buffer
 
-	for _, value := range data.Raw {
-		binary.Write(&buffer, binary.BigEndian, byte(value.Ident))
-		switch value.Ident {
-		case s8:
-			binary.Write(&buffer, binary.BigEndian, int8(value.Data.(float64)))
-		case u8:
-			binary.Write(&buffer, binary.BigEndian, uint8(value.Data.(float64)))
-		case s16:
-			binary.Write(&buffer, binary.BigEndian, int16(value.Data.(float64)))
-		case u16:
-			binary.Write(&buffer, binary.BigEndian, uint16(value.Data.(float64)))
-		case u32:
-			binary.Write(&buffer, binary.BigEndian, uint32(value.Data.(float64)))
-		case hex:
-			binary.Write(&buffer, binary.BigEndian, uint32(value.Data.(float64)))
-		case f32:
-			binary.Write(&buffer, binary.BigEndian, float32(value.Data.(float64)))
-		case str:
-			binary.Write(&buffer, binary.BigEndian, []byte(value.Data.(string)))
+	tokens := data.Tokens()
+	for idx, value := range tokens {
+		if value.Data == nil {
+			return nil, fmt.Errorf("value %v holds no data", idx)
+		}
+		if _, ok := value.Data.(UnterminatedStr); ok && idx != len(tokens)-1 {
+			return nil, fmt.Errorf("value %v is an unterminated str, only the last value can be one", idx)
+		}
+		if text, ok := value.Data.(Str); ok && strings.IndexByte(string(text), 0x00) >= 0 {
+			return nil, fmt.Errorf("value %v is a str holding a 0 byte, it would end the str early", idx)
+		}
+
+		binary.Write(&buffer, binary.BigEndian, byte(value.Data.ident()))
+		switch value := value.Data.(type) {
+		case S8:
+			binary.Write(&buffer, binary.BigEndian, int8(value))
+		case U8:
+			binary.Write(&buffer, binary.BigEndian, uint8(value))
+		case S16:
+			binary.Write(&buffer, binary.BigEndian, int16(value))
+		case U16:
+			binary.Write(&buffer, binary.BigEndian, uint16(value))
+		case U32:
+			binary.Write(&buffer, binary.BigEndian, uint32(value))
+		case Hex:
+			binary.Write(&buffer, binary.BigEndian, uint32(value))
+		case F32:
+			binary.Write(&buffer, binary.BigEndian, float32(value))
+		case Str:
+			binary.Write(&buffer, binary.BigEndian, []byte(value))
 			binary.Write(&buffer, binary.BigEndian, byte(0x00))
-		default:
+		case UnterminatedStr:
+			binary.Write(&buffer, binary.BigEndian, []byte(value))
 		}
 	}
 
@@ -204,18 +157,19 @@ DecodeFromJSON ...
 Read data from a json file and decode it to FETM structure or error
 */
 func DecodeFromJSON(data []byte, isRaw bool) (*FETM, error) {
-	fetm := FETM{}
-
 	if isRaw {
-		err := json.Unmarshal(data, &fetm.Raw)
+		var raw []Variant
+		err := json.Unmarshal(data, &raw)
 		if err != nil {
-			return nil, fmt.Errorf("something went wrong when decoding from json %v", err)
-		}
-	} else {
-		err := json.Unmarshal(data, &fetm)
-		if err != nil {
-			return nil, fmt.Errorf("something went wrong when decoding from json %v", err)
+			return nil, fmt.Errorf("decoding from json failed, %w", err)
 		}
+		return parse(raw)
+	}
+
+	fetm := FETM{}
+	err := json.Unmarshal(data, &fetm)
+	if err != nil {
+		return nil, fmt.Errorf("decoding from json failed, %w", err)
 	}
 
 	return &fetm, nil
@@ -227,32 +181,25 @@ Encode and write FETM data to JSON file or error
 */
 func (data *FETM) EncodeToJSON(isRaw bool) ([]byte, error) {
 	if isRaw {
-		raw, err := json.Marshal(data.Raw)
+		raw, err := json.Marshal(data.Tokens())
 		if err != nil {
-			return nil, fmt.Errorf("something went wrong when encoding the json")
+			return nil, fmt.Errorf("encoding to json failed, %w", err)
 		}
 		return raw, nil
 	} else {
 		raw, err := json.Marshal(data)
 		if err != nil {
-			return nil, fmt.Errorf("something went wrong when encoding the json")
+			return nil, fmt.Errorf("encoding to json failed, %w", err)
 		}
 		return raw, nil
 	}
 
 }
 
-func findStr(data []byte, startIndex int) string {
-	startIndex++
-	currentIndex := startIndex
-	for len(data) > currentIndex {
-		if data[currentIndex] == 0x00 {
-			return string(data[startIndex:currentIndex])
-		}
-		currentIndex++
-	}
-
-	return ""
+/*
+formatError ...
+A problem of the FETM file at offset, err is one of the formats errors or wraps one
+*/
+func formatError(offset int, field string, err error) error {
+	return &formats.FormatError{Format: "fetm", Offset: offset, Field: field, Err: err}
 }
-
-//Look at ParseWorldBlock, ParseSectorBlock, and ParseNodeList
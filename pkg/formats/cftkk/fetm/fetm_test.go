@@ -0,0 +1,32 @@
+package fetm
+
+import (
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+)
+
+func FuzzRead(f *testing.F) {
+	level := testgen.FETM()
+	f.Add(level.Data)
+	//A level that ends in a string without its terminator.
+	f.Add(append(level.Data, 7, 'a', 'b'))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		Validate(data)
+
+		file, err := Read(data)
+		if err != nil {
+			return
+		}
+		file.Write()
+		file.EncodeToJSON(true)
+		file.EncodeToJSON(false)
+
+		text, err := file.EncodeToText()
+		if err != nil {
+			return
+		}
+		DecodeFromText(text)
+	})
+}
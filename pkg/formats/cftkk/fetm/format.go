@@ -0,0 +1,62 @@
+package fetm
+
+import (
+	"bytes"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+func init() {
+	formats.Register(format{})
+}
+
+/*
+format ...
+FETM in the formats registry. The parsed tree already is the form to work with,
+so files and works are both *FETM and Decode and Encode hand it through.
+It is a formats.Inspector.
+*/
+type format struct{}
+
+func (format) Name() string         { return "fetm" }
+func (format) Extensions() []string { return []string{".fetm"} }
+
+func (format) Probe(data []byte) bool {
+	return len(data) >= 3 && bytes.Equal(data[:3], []byte{0x01, 0x7C, 0x07})
+}
+
+func (format) Read(data []byte) (interface{}, error) {
+	return Read(data)
+}
+
+func (format) Write(file interface{}) ([]byte, error) {
+	fetmFile, ok := file.(*FETM)
+	if !ok {
+		return nil, formats.WrongType("fetm", "*fetm.FETM", file)
+	}
+	return fetmFile.Write()
+}
+
+func (format) Decode(file interface{}) (interface{}, error) {
+	fetmFile, ok := file.(*FETM)
+	if !ok {
+		return nil, formats.WrongType("fetm", "*fetm.FETM", file)
+	}
+	return fetmFile, nil
+}
+
+func (format) Encode(work interface{}) (interface{}, error) {
+	fetmFile, ok := work.(*FETM)
+	if !ok {
+		return nil, formats.WrongType("fetm", "*fetm.FETM", work)
+	}
+	return fetmFile, nil
+}
+
+func (format) Inspect(file interface{}) (*formats.Inspection, error) {
+	fetmFile, ok := file.(*FETM)
+	if !ok {
+		return nil, formats.WrongType("fetm", "*fetm.FETM", file)
+	}
+	return fetmFile.Inspect(), nil
+}
@@ -0,0 +1,73 @@
+package fetm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+)
+
+func TestGolden(t *testing.T) {
+	level := testgen.FETM()
+
+	file, err := Read(level.Data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	if len(file.Tokens()) != level.Values {
+		t.Fatalf("read %v values, the level has %v", len(file.Tokens()), level.Values)
+	}
+	if file.World.Name.Data != worldMarker || file.Sector.Name.Data != sectorMarker {
+		t.Fatalf("the world is %v and the sector %v", file.World.Name.Data, file.Sector.Name.Data)
+	}
+	if len(file.Nodes) != len(level.Classes) {
+		t.Fatalf("read %v nodes, the level has %v", len(file.Nodes), len(level.Classes))
+	}
+	for idx, class := range level.Classes {
+		if file.Nodes[idx].EntityClass.Data != Str(class) {
+			t.Fatalf("node %v has class %v, it has to be %v", idx, file.Nodes[idx].EntityClass.Data, class)
+		}
+	}
+
+	written, err := file.Write()
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	if !bytes.Equal(written, level.Data) {
+		t.Fatalf("writing changed the level:\n% X\n% X", level.Data, written)
+	}
+
+	text, err := file.EncodeToText()
+	if err != nil {
+		t.Fatalf("encoding to text: %v", err)
+	}
+	checkRoundTrip(t, "text", level.Data, func() (*FETM, error) { return DecodeFromText(text) })
+
+	for _, isRaw := range []bool{true, false} {
+		raw, err := file.EncodeToJSON(isRaw)
+		if err != nil {
+			t.Fatalf("encoding to json: %v", err)
+		}
+		checkRoundTrip(t, "json", level.Data, func() (*FETM, error) { return DecodeFromJSON(raw, isRaw) })
+	}
+}
+
+/*
+checkRoundTrip ...
+Decode a level from another encoding and check it writes back to the original data
+*/
+func checkRoundTrip(t *testing.T, name string, data []byte, decode func() (*FETM, error)) {
+	t.Helper()
+
+	file, err := decode()
+	if err != nil {
+		t.Fatalf("decoding from %v: %v", name, err)
+	}
+	written, err := file.Write()
+	if err != nil {
+		t.Fatalf("writing what %v decoded to: %v", name, err)
+	}
+	if !bytes.Equal(written, data) {
+		t.Fatalf("%v changed the level:\n% X\n% X", name, data, written)
+	}
+}
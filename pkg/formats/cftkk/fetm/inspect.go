@@ -0,0 +1,45 @@
+package fetm
+
+import (
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+Inspect ...
+Show the header values of a FETM file, how many values its sections and nodes hold, and how
+many nodes there are of each entity class, in the order the classes first show up
+*/
+func (data *FETM) Inspect() *formats.Inspection {
+	inspection := formats.NewInspection("fetm")
+
+	header := inspection.Section("header")
+	for idx, value := range headerValues(data) {
+		header.Field(headerNames[idx], describeText(value))
+	}
+
+	inspection.Section("counts").
+		Field("world params", len(data.World.Params)).
+		Field("sector lead", len(data.Sector.Lead)).
+		Field("sector params", len(data.Sector.Params)).
+		Field("nodes", len(data.Nodes)).
+		Field("values", len(data.Tokens()))
+
+	var order []string
+	counts := map[string]int{}
+	for _, node := range data.Nodes {
+		class := describeText(node.EntityClass)
+		if str, ok := node.EntityClass.Data.(Str); ok {
+			class = string(str)
+		}
+		if counts[class] == 0 {
+			order = append(order, class)
+		}
+		counts[class]++
+	}
+	classes := inspection.Table("classes", "class", "nodes")
+	for _, class := range order {
+		classes.Row(class, counts[class])
+	}
+
+	return inspection
+}
@@ -0,0 +1,210 @@
+package fetm
+
+import "fmt"
+
+/*
+Conflict ...
+A value or node both sides changed in different ways. Merge keeps our side of it.
+*/
+type Conflict struct {
+	Location string
+	Reason   string
+}
+
+func (conflict Conflict) String() string {
+	return conflict.Location + ": " + conflict.Reason
+}
+
+/*
+Merge ...
+Merge the changes ours and theirs made to base. A value changed on only one side takes that
+change, a value changed the same way on both sides is taken once. Nodes are matched like Diff
+matches them and merged in the order of ours, so a node theirs moved stays where ours has it.
+Nodes added on either side are kept in place and nodes removed on one side are removed unless
+the other side changed them. Everything else is a conflict, the merged file
+keeps our side there and the conflicts are returned with it.
+*/
+func Merge(base *FETM, ours *FETM, theirs *FETM) (*FETM, []Conflict) {
+	merger := &merger{}
+	merged := &FETM{}
+
+	header := merger.values("header", headerValues(base), headerValues(ours), headerValues(theirs), headerNames)
+	merged.Header = Header{Magic: header[0], Note: header[1], SectionCount: header[2], Unknown: header[3]}
+
+	merged.World.Name = merger.values("world", []Variant{base.World.Name}, []Variant{ours.World.Name}, []Variant{theirs.World.Name}, []string{"name"})[0]
+	merged.World.Params = merger.values("world", base.World.Params, ours.World.Params, theirs.World.Params, nil)
+
+	merged.Sector.Name = merger.values("sector", []Variant{base.Sector.Name}, []Variant{ours.Sector.Name}, []Variant{theirs.Sector.Name}, []string{"name"})[0]
+	merged.Sector.Lead = merger.values("sector lead", base.Sector.Lead, ours.Sector.Lead, theirs.Sector.Lead, nil)
+	merged.Sector.Params = merger.values("sector", base.Sector.Params, ours.Sector.Params, theirs.Sector.Params, nil)
+
+	merged.Nodes = merger.nodes(base.Nodes, ours.Nodes, theirs.Nodes)
+
+	return merged, merger.conflicts
+}
+
+type merger struct {
+	conflicts []Conflict
+}
+
+func (merger *merger) conflict(location string, format string, args ...interface{}) {
+	merger.conflicts = append(merger.conflicts, Conflict{Location: location, Reason: fmt.Sprintf(format, args...)})
+}
+
+/*
+values ...
+Merge three value lists, position by position when no side changed the length
+*/
+func (merger *merger) values(location string, base []Variant, ours []Variant, theirs []Variant, names []string) []Variant {
+	switch {
+	case sameValues(ours, base):
+		return append([]Variant{}, theirs...)
+	case sameValues(theirs, base), sameValues(ours, theirs):
+		return append([]Variant{}, ours...)
+	case len(ours) != len(base) || len(theirs) != len(base):
+		merger.conflict(location, "both sides changed the values and one changed how many there are, %v in base, %v in ours, %v in theirs", len(base), len(ours), len(theirs))
+		return append([]Variant{}, ours...)
+	}
+
+	merged := make([]Variant, len(base))
+	for idx := range base {
+		switch {
+		case sameValue(ours[idx], base[idx]):
+			merged[idx] = theirs[idx]
+		case sameValue(theirs[idx], base[idx]), sameValue(ours[idx], theirs[idx]):
+			merged[idx] = ours[idx]
+		default:
+			path := fmt.Sprint(idx)
+			if idx < len(names) {
+				path = names[idx]
+			}
+			merger.conflict(location+" "+path, "%v in base, %v in ours, %v in theirs", describeText(base[idx]), describeText(ours[idx]), describeText(theirs[idx]))
+			merged[idx] = ours[idx]
+		}
+	}
+
+	return merged
+}
+
+func (merger *merger) nodes(base []Node, ours []Node, theirs []Node) []Node {
+	oursOf, baseOfOurs := matchIndices(base, ours)
+	theirsOf, baseOfTheirs := matchIndices(base, theirs)
+
+	//Nodes only theirs has are placed after the base node they follow in theirs, -1 for the front.
+	added := make(map[int][]int)
+	anchor := -1
+	for idx := range theirs {
+		if baseOfTheirs[idx] >= 0 {
+			anchor = baseOfTheirs[idx]
+		} else {
+			added[anchor] = append(added[anchor], idx)
+		}
+	}
+
+	oursAdded := make(map[string][]*Node)
+	for idx := range ours {
+		if baseOfOurs[idx] < 0 {
+			identity := ours[idx].identity()
+			oursAdded[identity] = append(oursAdded[identity], &ours[idx])
+		}
+	}
+
+	var merged []Node
+
+	addTheirs := func(anchor int) {
+		for _, idx := range added[anchor] {
+			node := theirs[idx]
+			location := "node " + node.identity()
+
+			//A node both sides added is only added once.
+			same, clash := false, false
+			for _, other := range oursAdded[node.identity()] {
+				if sameValues(other.DependentData, node.DependentData) {
+					same = true
+				} else {
+					clash = true
+				}
+			}
+			if same {
+				continue
+			}
+			if clash {
+				merger.conflict(location, "both sides added this node with different values")
+				continue
+			}
+
+			merged = append(merged, node)
+		}
+	}
+
+	addBase := func(idx int) {
+		location := "node " + base[idx].identity()
+		oursIdx, theirsIdx := oursOf[idx], theirsOf[idx]
+
+		switch {
+		case oursIdx >= 0 && theirsIdx >= 0:
+			node := ours[oursIdx]
+			names := node.FieldNames()
+			node.DependentData = merger.values(location, base[idx].DependentData, ours[oursIdx].DependentData, theirs[theirsIdx].DependentData, names)
+			merged = append(merged, node)
+		case oursIdx >= 0:
+			if !sameValues(ours[oursIdx].DependentData, base[idx].DependentData) {
+				merger.conflict(location, "theirs removed this node, ours changed it")
+				merged = append(merged, ours[oursIdx])
+			}
+		case theirsIdx >= 0:
+			if !sameValues(theirs[theirsIdx].DependentData, base[idx].DependentData) {
+				merger.conflict(location, "ours removed this node, theirs changed it")
+				merged = append(merged, theirs[theirsIdx])
+			}
+		}
+
+		addTheirs(idx)
+	}
+
+	addTheirs(-1)
+
+	next := 0
+	for idx := range ours {
+		baseIdx := baseOfOurs[idx]
+		if baseIdx < 0 {
+			merged = append(merged, ours[idx])
+			continue
+		}
+		//Base nodes before this one that ours doesn't have were removed by ours.
+		for ; next < baseIdx; next++ {
+			if oursOf[next] < 0 {
+				addBase(next)
+			}
+		}
+		addBase(baseIdx)
+	}
+	for ; next < len(base); next++ {
+		if oursOf[next] < 0 {
+			addBase(next)
+		}
+	}
+
+	return merged
+}
+
+/*
+matchIndices ...
+Match the nodes of base and other, return the other index of every base node and
+the base index of every other node, -1 where a node has no match
+*/
+func matchIndices(base []Node, other []Node) ([]int, []int) {
+	otherOf := make([]int, len(base))
+	baseOf := make([]int, len(other))
+
+	for _, pair := range matchNodes(base, other) {
+		if pair.old >= 0 {
+			otherOf[pair.old] = pair.new
+		}
+		if pair.new >= 0 {
+			baseOf[pair.new] = pair.old
+		}
+	}
+
+	return otherOf, baseOf
+}
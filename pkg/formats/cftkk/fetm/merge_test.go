@@ -0,0 +1,131 @@
+package fetm
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func describeNodes(nodes []Node) []string {
+	var described []string
+	for _, node := range nodes {
+		name, _ := node.Field("name")
+		x, _ := node.Field("position.x")
+		described = append(described, fmt.Sprintf("%v %v", name, x))
+	}
+	return described
+}
+
+func TestMergeNodes(t *testing.T) {
+	coinLocation := "node u16 1 str \"Factory\" str \"Pickup\" str \"coin\""
+	gemLocation := "node u16 1 str \"Factory\" str \"Pickup\" str \"gem\""
+	rubyLocation := "node u16 1 str \"Factory\" str \"Pickup\" str \"ruby\""
+
+	tests := []struct {
+		name      string
+		base      []Node
+		ours      []Node
+		theirs    []Node
+		want      []string
+		conflicts []string
+	}{
+		{"insert and edit",
+			[]Node{pickup("coin", 0), pickup("gem", 1)},
+			[]Node{pickup("ruby", 2), pickup("coin", 0), pickup("gem", 1)},
+			[]Node{pickup("coin", 0), pickup("gem", 5)},
+			[]string{"ruby 2", "coin 0", "gem 5"}, nil},
+		{"edit and insert",
+			[]Node{pickup("coin", 0), pickup("gem", 1)},
+			[]Node{pickup("coin", 3), pickup("gem", 1)},
+			[]Node{pickup("coin", 0), pickup("ruby", 2), pickup("gem", 1)},
+			[]string{"coin 3", "ruby 2", "gem 1"}, nil},
+		{"reorder and edit",
+			[]Node{pickup("coin", 0), pickup("gem", 1), pickup("ruby", 2)},
+			[]Node{pickup("ruby", 2), pickup("coin", 0), pickup("gem", 1)},
+			[]Node{pickup("coin", 0), pickup("gem", 5), pickup("ruby", 2)},
+			[]string{"ruby 2", "coin 0", "gem 5"}, nil},
+		{"their reorder keeps our order",
+			[]Node{pickup("coin", 0), pickup("gem", 1)},
+			[]Node{pickup("coin", 3), pickup("gem", 1)},
+			[]Node{pickup("gem", 1), pickup("coin", 0)},
+			[]string{"coin 3", "gem 1"}, nil},
+		{"delete",
+			[]Node{pickup("coin", 0), pickup("gem", 1)},
+			[]Node{pickup("coin", 0), pickup("gem", 1)},
+			[]Node{pickup("gem", 1)},
+			[]string{"gem 1"}, nil},
+		{"delete and insert after it",
+			[]Node{pickup("coin", 0), pickup("gem", 1)},
+			[]Node{pickup("gem", 1)},
+			[]Node{pickup("coin", 0), pickup("ruby", 2), pickup("gem", 1)},
+			[]string{"ruby 2", "gem 1"}, nil},
+		{"same insert on both sides",
+			[]Node{pickup("coin", 0)},
+			[]Node{pickup("coin", 0), pickup("ruby", 2)},
+			[]Node{pickup("coin", 0), pickup("ruby", 2)},
+			[]string{"coin 0", "ruby 2"}, nil},
+		{"both edit the same field",
+			[]Node{pickup("coin", 0), pickup("gem", 1)},
+			[]Node{pickup("coin", 3), pickup("gem", 1)},
+			[]Node{pickup("gem", 1), pickup("coin", 4)},
+			[]string{"coin 3", "gem 1"}, []string{coinLocation + " position.x"}},
+		{"we delete what they edit",
+			[]Node{pickup("coin", 0), pickup("gem", 1)},
+			[]Node{pickup("coin", 0)},
+			[]Node{pickup("coin", 0), pickup("gem", 5)},
+			[]string{"coin 0", "gem 5"}, []string{gemLocation}},
+		{"they delete what we edit",
+			[]Node{pickup("coin", 0), pickup("gem", 1)},
+			[]Node{pickup("coin", 3), pickup("gem", 1)},
+			[]Node{pickup("gem", 1)},
+			[]string{"coin 3", "gem 1"}, []string{coinLocation}},
+		{"different inserts of one node",
+			[]Node{pickup("coin", 0)},
+			[]Node{pickup("coin", 0), pickup("ruby", 2)},
+			[]Node{pickup("coin", 0), pickup("ruby", 3)},
+			[]string{"coin 0", "ruby 2"}, []string{rubyLocation}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, conflicts := Merge(levelOf(t, test.base...), levelOf(t, test.ours...), levelOf(t, test.theirs...))
+
+			if got := describeNodes(merged.Nodes); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("the merged nodes are %q, not %q", got, test.want)
+			}
+
+			var locations []string
+			for _, conflict := range conflicts {
+				locations = append(locations, conflict.Location)
+			}
+			if !reflect.DeepEqual(locations, test.conflicts) {
+				t.Fatalf("the conflicts are %v, not at %q", conflicts, test.conflicts)
+			}
+		})
+	}
+}
+
+func TestMergeValues(t *testing.T) {
+	base := levelOf(t)
+	ours := levelOf(t)
+	theirs := levelOf(t)
+	ours.World.Params[0] = Variant{F32(2)}
+	theirs.World.Params[2] = Variant{S16(7)}
+	ours.Sector.Params[0] = Variant{U8(4)}
+	theirs.Sector.Params[0] = Variant{U8(5)}
+
+	merged, conflicts := Merge(base, ours, theirs)
+	if len(conflicts) != 1 || conflicts[0].Location != "sector 0" {
+		t.Fatalf("the conflicts are %v, not one at sector 0", conflicts)
+	}
+	want := []Variant{{F32(2)}, {S8(-2)}, {S16(7)}}
+	if !reflect.DeepEqual(merged.World.Params, want) || merged.Sector.Params[0] != (Variant{U8(4)}) {
+		t.Fatalf("the merged world is %v and the sector %v", merged.World.Params, merged.Sector.Params)
+	}
+
+	theirs.World.Params = theirs.World.Params[:2]
+	_, conflicts = Merge(base, ours, theirs)
+	if len(conflicts) != 2 || conflicts[0].Location != "world" {
+		t.Fatalf("changing the world on both sides and its length on one has to conflict, the conflicts are %v", conflicts)
+	}
+}
@@ -0,0 +1,308 @@
+package fetm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+Selector ...
+Decide whether a node is part of a query, index is its index in Nodes
+*/
+type Selector func(index int, node *Node) bool
+
+/*
+All ...
+Select every node
+*/
+func All() Selector {
+	return func(int, *Node) bool { return true }
+}
+
+/*
+Class ...
+Select the nodes of an entity class
+*/
+func Class(class string) Selector {
+	return func(_ int, node *Node) bool { return sameValue(node.EntityClass, Variant{Str(class)}) }
+}
+
+/*
+Factory ...
+Select the nodes made by a node factory
+*/
+func Factory(factory string) Selector {
+	return func(_ int, node *Node) bool { return sameValue(node.NodeFactory, Variant{Str(factory)}) }
+}
+
+/*
+Type ...
+Select the nodes with a node type, kind and value have to match
+*/
+func Type(value Value) Selector {
+	return func(_ int, node *Node) bool { return sameValue(node.NodeType, Variant{value}) }
+}
+
+/*
+FieldEquals ...
+Select the nodes whose parameter at path, see Field, is value. Nodes without that parameter aren't selected.
+*/
+func FieldEquals(path string, value Value) Selector {
+	return func(_ int, node *Node) bool {
+		field, err := node.Field(path)
+		return err == nil && sameValue(Variant{field}, Variant{value})
+	}
+}
+
+/*
+And ...
+Select the nodes every selector selects
+*/
+func And(selectors ...Selector) Selector {
+	return func(index int, node *Node) bool {
+		for _, selector := range selectors {
+			if !selector(index, node) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+/*
+Or ...
+Select the nodes any selector selects
+*/
+func Or(selectors ...Selector) Selector {
+	return func(index int, node *Node) bool {
+		for _, selector := range selectors {
+			if selector(index, node) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+/*
+Not ...
+Select the nodes selector doesn't
+*/
+func Not(selector Selector) Selector {
+	return func(index int, node *Node) bool { return !selector(index, node) }
+}
+
+/*
+ParseSelector ...
+Parse a selector term as the command line takes it, one of "*", "class=Name", "factory=Name",
+"type=u16 3" or "path=f32 1.5" for a parameter. Values are written as in the text format,
+names can be quoted.
+*/
+func ParseSelector(term string) (Selector, error) {
+	if strings.TrimSpace(term) == "*" {
+		return All(), nil
+	}
+
+	split := strings.Index(term, "=")
+	if split < 0 {
+		return nil, fmt.Errorf("selector %v should look like key=value", term)
+	}
+	key := strings.TrimSpace(term[:split])
+	literal := strings.TrimSpace(term[split+1:])
+
+	switch key {
+	case "class", "factory":
+		name := literal
+		if unquoted, err := strconv.Unquote(literal); err == nil {
+			name = unquoted
+		}
+		if key == "class" {
+			return Class(name), nil
+		}
+		return Factory(name), nil
+	}
+
+	value, err := ParseValue(literal)
+	if err != nil {
+		return nil, fmt.Errorf("selector %v %v", term, err)
+	}
+	if key == "type" {
+		return Type(value), nil
+	}
+	return FieldEquals(key, value), nil
+}
+
+/*
+ParseValue ...
+Parse one value written as in the text format, like "f32 1.5" or `str "name"`
+*/
+func ParseValue(literal string) (Value, error) {
+	fields, err := splitText(literal)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("%v should be a kind and a literal like f32 1.5", literal)
+	}
+	return parseText(fields[0], fields[1])
+}
+
+/*
+Query ...
+The nodes of a FETM a selector picked, in file order. Changes made through a query are made to the FETM.
+*/
+type Query struct {
+	fetm    *FETM
+	indices []int
+}
+
+/*
+Select ...
+Return a query of every node selector picks
+*/
+func (data *FETM) Select(selector Selector) *Query {
+	query := &Query{fetm: data}
+	for idx := range data.Nodes {
+		if selector(idx, &data.Nodes[idx]) {
+			query.indices = append(query.indices, idx)
+		}
+	}
+	return query
+}
+
+/*
+Filter ...
+Return a query of the nodes of this one that selector picks
+*/
+func (query *Query) Filter(selector Selector) *Query {
+	filtered := &Query{fetm: query.fetm}
+	for _, idx := range query.indices {
+		if selector(idx, &query.fetm.Nodes[idx]) {
+			filtered.indices = append(filtered.indices, idx)
+		}
+	}
+	return filtered
+}
+
+func (query *Query) Len() int {
+	return len(query.indices)
+}
+
+/*
+Indices ...
+Return the index in Nodes of every node of the query
+*/
+func (query *Query) Indices() []int {
+	return append([]int{}, query.indices...)
+}
+
+/*
+Nodes ...
+Return the nodes of the query, changing them changes the FETM
+*/
+func (query *Query) Nodes() []*Node {
+	nodes := make([]*Node, len(query.indices))
+	for idx, nodeIdx := range query.indices {
+		nodes[idx] = &query.fetm.Nodes[nodeIdx]
+	}
+	return nodes
+}
+
+/*
+Map ...
+Replace the parameter at path of every node with what fn returns for it. Nothing is changed
+unless fn succeeds and keeps the kind for every node, the error names the node that failed.
+*/
+func (query *Query) Map(path string, fn func(Value) (Value, error)) error {
+	values := make([]Value, len(query.indices))
+	for idx, nodeIdx := range query.indices {
+		node := &query.fetm.Nodes[nodeIdx]
+
+		old, err := node.Field(path)
+		if err == nil && old == nil {
+			err = fmt.Errorf("parameter %v holds no data", path)
+		}
+		if err != nil {
+			return fmt.Errorf("node %v %v", nodeIdx, err)
+		}
+		value, err := fn(old)
+		if err != nil {
+			return fmt.Errorf("node %v parameter %v %v", nodeIdx, path, err)
+		}
+		if value == nil || value.ident() != old.ident() {
+			return fmt.Errorf("node %v parameter %v is %v, it can't be set to %v", nodeIdx, path, describe(Variant{old}), describe(Variant{value}))
+		}
+		values[idx] = value
+	}
+
+	for idx, nodeIdx := range query.indices {
+		node := &query.fetm.Nodes[nodeIdx]
+		err := node.SetField(path, values[idx])
+		if err != nil {
+			return fmt.Errorf("node %v %v", nodeIdx, err)
+		}
+	}
+
+	return nil
+}
+
+/*
+Set ...
+Set the parameter at path of every node to value
+*/
+func (query *Query) Set(path string, value Value) error {
+	return query.Map(path, func(Value) (Value, error) { return value, nil })
+}
+
+/*
+Scale ...
+Multiply the parameter at path of every node by factor. f32 values are scaled as they are,
+the integer kinds S8 to U32 are rounded to the nearest value and error when it doesn't fit.
+Hex values are bit patterns and strings aren't numbers, neither can be scaled.
+*/
+func (query *Query) Scale(path string, factor float32) error {
+	return query.Map(path, func(value Value) (Value, error) {
+		if number, ok := value.(F32); ok {
+			scaled := F32(float32(number) * factor)
+			if math.IsInf(float64(scaled), 0) && !math.IsInf(float64(number), 0) {
+				return nil, fmt.Errorf("overflows when %v is scaled by %v", number, factor)
+			}
+			return scaled, nil
+		}
+
+		var number, min, max float64
+		switch value := value.(type) {
+		case S8:
+			number, min, max = float64(value), math.MinInt8, math.MaxInt8
+		case U8:
+			number, min, max = float64(value), 0, math.MaxUint8
+		case S16:
+			number, min, max = float64(value), math.MinInt16, math.MaxInt16
+		case U16:
+			number, min, max = float64(value), 0, math.MaxUint16
+		case U32:
+			number, min, max = float64(value), 0, math.MaxUint32
+		default:
+			return nil, fmt.Errorf("is %v, only f32 and integer values can be scaled", describe(Variant{value}))
+		}
+
+		scaled := math.Round(number * float64(factor))
+		if math.IsNaN(scaled) || scaled < min || scaled > max {
+			return nil, fmt.Errorf("is %v, scaled by %v it doesn't fit", describe(Variant{value}), factor)
+		}
+
+		switch value.(type) {
+		case S8:
+			return S8(scaled), nil
+		case U8:
+			return U8(scaled), nil
+		case S16:
+			return S16(scaled), nil
+		case U16:
+			return U16(scaled), nil
+		}
+		return U32(scaled), nil
+	})
+}
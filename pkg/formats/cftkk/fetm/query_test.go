@@ -0,0 +1,161 @@
+package fetm
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+/*
+queryLevel ...
+A pickup and a spawner of the generated level, a second pickup and a node of an unregistered class
+*/
+func queryLevel(t *testing.T) *FETM {
+	t.Helper()
+
+	file := readLevel(t)
+	file.Nodes = append(file.Nodes, pickup("gem", 1.5), unnamed(3))
+	return file
+}
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		term string
+		want []int
+	}{
+		{"*", []int{0, 1, 2, 3}},
+		{" * ", []int{0, 1, 2, 3}},
+		{"class=Pickup", []int{0, 2}},
+		{"class = Pickup", []int{0, 2}},
+		{`class="Spawner"`, []int{1}},
+		{"class=pickup", nil},
+		{"class=Pick*", nil},
+		{"factory=x", []int{1, 3}},
+		{`factory="Factory"`, []int{0, 2}},
+		{"type=u16 2", []int{1, 3}},
+		{"type=u8 2", nil},
+		{"type=u16 0x1", []int{0, 2}},
+		{`name=str "gem"`, []int{2}},
+		{"position.x=f32 1.5", []int{2}},
+		{"position.x=f32 0x3FC00000", []int{2}},
+		{"position.x=u32 0", nil},
+		{"0=u8 3", []int{3}},
+		{"0=f32 2.5", []int{0}},
+		{"4=u8 3", nil},
+	}
+
+	file := queryLevel(t)
+	for _, test := range tests {
+		t.Run(test.term, func(t *testing.T) {
+			selector, err := ParseSelector(test.term)
+			if err != nil {
+				t.Fatalf("parsing: %v", err)
+			}
+			if got := file.Select(selector).Indices(); !reflect.DeepEqual(got, append([]int{}, test.want...)) {
+				t.Fatalf("selected %v, not %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, term := range []string{
+		"",
+		"**",
+		"class",
+		"Pickup",
+		"=",
+		"type=u16",
+		"type=u16 70000",
+		"type=q 1",
+		"type=2",
+		`name=str "gem`,
+		"name=str gem",
+		"position.x=f32 1.5 f32 2",
+	} {
+		if _, err := ParseSelector(term); err == nil {
+			t.Fatalf("parsing %q has to fail", term)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	file := queryLevel(t)
+
+	query := file.Select(Or(Class("Spawner"), FieldEquals("name", Str("gem")), Factory("x")))
+	if got := query.Indices(); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Fatalf("the query selected %v", got)
+	}
+	query = query.Filter(And(Not(Type(U16(2))), Not(Class("Spawner"))))
+	if got := query.Indices(); !reflect.DeepEqual(got, []int{2}) {
+		t.Fatalf("the filtered query selected %v", got)
+	}
+
+	if err := query.Set("name", Str("ruby")); err != nil {
+		t.Fatalf("setting the name: %v", err)
+	}
+	if query.Nodes()[0] != &file.Nodes[2] || file.Nodes[2].DependentData[3].Data != Str("ruby") {
+		t.Fatalf("setting through the query didn't change the level, the name is %v", file.Nodes[2].DependentData[3].Data)
+	}
+
+	//A node without the parameter stops the whole query, the nodes before it keep their values.
+	all := file.Select(All())
+	if err := all.Set("name", Str("gold")); err == nil {
+		t.Fatalf("setting a name on a node without one has to fail")
+	}
+	if err := all.Set("0", U8(1)); err == nil {
+		t.Fatalf("setting an f32 parameter to an u8 has to fail")
+	}
+	if got := describeNodes(file.Nodes[:3]); !reflect.DeepEqual(got, []string{"coin 2.5", "start 0", "ruby 1.5"}) {
+		t.Fatalf("a failed set changed the nodes to %v", got)
+	}
+}
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  Value
+		factor float32
+		want   Value
+	}{
+		{"f32", F32(2.5), 2, F32(5)},
+		{"f32 negative", F32(2.5), -0.5, F32(-1.25)},
+		{"f32 infinity", F32(math.Inf(1)), 2, F32(math.Inf(1))},
+		{"f32 overflow", F32(3e38), 10, nil},
+		{"s8", S8(-3), 2.5, S8(-8)},
+		{"s8 minimum", S8(-100), 1.28, S8(-128)},
+		{"s8 overflow", S8(100), 2, nil},
+		{"u8", U8(10), 1.5, U8(15)},
+		{"u8 maximum", U8(200), 1.275, U8(255)},
+		{"u8 negative", U8(10), -1, nil},
+		{"s16", S16(-1000), 0.5, S16(-500)},
+		{"s16 overflow", S16(20000), 2, nil},
+		{"u16 rounds", U16(3), 0.5, U16(2)},
+		{"u16 overflow", U16(40000), 2, nil},
+		{"u32", U32(1000000000), 4, U32(4000000000)},
+		{"u32 overflow", U32(2000000000), 3, nil},
+		{"u32 nan", U32(1), float32(math.NaN()), nil},
+		{"hex", Hex(1), 2, nil},
+		{"str", Str("coin"), 2, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := unnamed(0)
+			node.DependentData = []Variant{{test.value}}
+			file := levelOf(t, node)
+
+			err := file.Select(All()).Scale("0", test.factor)
+			got := file.Nodes[0].DependentData[0].Data
+			if test.want == nil {
+				if err == nil || got != test.value {
+					t.Fatalf("scaling has to fail and keep the value, it gave %v and %v", got, err)
+				}
+				return
+			}
+			if err != nil || !sameValue(Variant{got}, Variant{test.want}) {
+				t.Fatalf("scaling gave %v, %v, not %v", describe(Variant{got}), err, describe(Variant{test.want}))
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package fetm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+RawEntity ...
+The parameters of a node whose entity class isn't registered, in file order.
+*/
+type RawEntity []Variant
+
+type entityField struct {
+	path  string
+	index []int
+	ident identifer
+}
+
+type entityLayout struct {
+	layout reflect.Type
+	fields []entityField
+	//rest is the index of a trailing []Variant field that takes every parameter after fields, or nil.
+	rest []int
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]*entityLayout)
+)
+
+var (
+	valueType    = reflect.TypeOf((*Value)(nil)).Elem()
+	variantsType = reflect.TypeOf([]Variant(nil))
+)
+
+/*
+RegisterEntityClass ...
+Register the parameter layout of an entity class. layout is a struct, or a pointer to one,
+whose exported fields are the parameters of the class in file order. A field is one of the
+value kinds S8 to Str, or a nested struct of them. Fields are addressed by their lower case
+name, or the name in a `fetm:"name"` tag, nested fields as "position.x". A last field of
+type []Variant takes every remaining parameter, for classes that are only partly known.
+*/
+func RegisterEntityClass(class string, layout interface{}) error {
+	layoutType := reflect.TypeOf(layout)
+	if layoutType != nil && layoutType.Kind() == reflect.Ptr {
+		layoutType = layoutType.Elem()
+	}
+	if layoutType == nil || layoutType.Kind() != reflect.Struct {
+		return fmt.Errorf("layout of entity class %v has to be a struct", class)
+	}
+
+	entity := &entityLayout{layout: layoutType}
+	err := entity.addFields(layoutType, nil, "")
+	if err != nil {
+		return fmt.Errorf("layout of entity class %v %v", class, err)
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[class] = entity
+
+	return nil
+}
+
+/*
+EntityClasses ...
+Return the names of every registered entity class, sorted
+*/
+func EntityClasses() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	var classes []string
+	for class := range registry {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	return classes
+}
+
+func (entity *entityLayout) addFields(structType reflect.Type, index []int, prefix string) error {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if entity.rest != nil {
+			return fmt.Errorf("has field %v after its []Variant field", field.Name)
+		}
+
+		name := strings.ToLower(field.Name)
+		if tag := field.Tag.Get("fetm"); tag != "" {
+			name = tag
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+
+		switch {
+		case field.Type == variantsType:
+			if len(index) > 0 {
+				return fmt.Errorf("has its []Variant field %v inside a nested struct", field.Name)
+			}
+			entity.rest = fieldIndex
+		case field.Type.Implements(valueType):
+			ident := reflect.Zero(field.Type).Interface().(Value).ident()
+			entity.fields = append(entity.fields, entityField{path: prefix + name, index: fieldIndex, ident: ident})
+		case field.Type.Kind() == reflect.Struct:
+			err := entity.addFields(field.Type, fieldIndex, prefix+name+".")
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("has field %v of type %v, which is not a fetm value", field.Name, field.Type)
+		}
+	}
+
+	return nil
+}
+
+func lookupClass(node *Node) (*entityLayout, bool) {
+	class, ok := node.EntityClass.Data.(Str)
+	if !ok {
+		return nil, false
+	}
+
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	entity, ok := registry[string(class)]
+
+	return entity, ok
+}
+
+/*
+Arity ...
+Return how many parameters a node of the given class has, and whether that number is fixed.
+A class that isn't registered or ends in a []Variant field has at least that many parameters.
+*/
+func Arity(class string) (int, bool, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	entity, ok := registry[class]
+	if !ok {
+		return 0, false, false
+	}
+
+	return len(entity.fields), entity.rest == nil, true
+}
+
+/*
+Entity ...
+Return the parameters of a node as a pointer to a new struct of its registered entity class,
+or as a RawEntity when the class isn't registered. It errors when the parameters don't fit the layout.
+*/
+func (node *Node) Entity() (interface{}, error) {
+	entity, ok := lookupClass(node)
+	if !ok {
+		return append(RawEntity{}, node.DependentData...), nil
+	}
+
+	params := node.DependentData
+	if len(params) < len(entity.fields) || (entity.rest == nil && len(params) != len(entity.fields)) {
+		return nil, fmt.Errorf("entity class %v has %v parameters, the node has %v", node.EntityClass.Data, len(entity.fields), len(params))
+	}
+
+	value := reflect.New(entity.layout)
+	for idx, field := range entity.fields {
+		fieldValue := value.Elem().FieldByIndex(field.index)
+		if params[idx].Data == nil || reflect.TypeOf(params[idx].Data) != fieldValue.Type() {
+			return nil, fmt.Errorf("parameter %v of entity class %v should be %v, the node has %v", field.path, node.EntityClass.Data, field.ident, describe(params[idx]))
+		}
+		fieldValue.Set(reflect.ValueOf(params[idx].Data))
+	}
+	if entity.rest != nil {
+		rest := append([]Variant{}, params[len(entity.fields):]...)
+		value.Elem().FieldByIndex(entity.rest).Set(reflect.ValueOf(rest))
+	}
+
+	return value.Interface(), nil
+}
+
+/*
+SetEntity ...
+Replace the parameters of a node with the fields of entity, a struct of its registered
+entity class or a pointer to one, or with a RawEntity
+*/
+func (node *Node) SetEntity(entity interface{}) error {
+	if raw, ok := entity.(RawEntity); ok {
+		node.DependentData = append([]Variant{}, raw...)
+		return nil
+	}
+
+	layout, ok := lookupClass(node)
+	if !ok {
+		return fmt.Errorf("entity class %v is not registered, only a RawEntity can be set", node.EntityClass.Data)
+	}
+
+	value := reflect.ValueOf(entity)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return fmt.Errorf("entity class %v is registered as %v, there is no entity to set", node.EntityClass.Data, layout.layout)
+	}
+	if value.Type() != layout.layout {
+		return fmt.Errorf("entity class %v is registered as %v, not %v", node.EntityClass.Data, layout.layout, value.Type())
+	}
+
+	params := make([]Variant, 0, len(layout.fields))
+	for _, field := range layout.fields {
+		params = append(params, Variant{value.FieldByIndex(field.index).Interface().(Value)})
+	}
+	if layout.rest != nil {
+		params = append(params, value.FieldByIndex(layout.rest).Interface().([]Variant)...)
+	}
+	node.DependentData = params
+
+	return nil
+}
+
+/*
+FieldNames ...
+Return the paths of every parameter of a node, names for registered classes and indices otherwise
+*/
+func (node *Node) FieldNames() []string {
+	var names []string
+
+	entity, ok := lookupClass(node)
+	if ok {
+		for _, field := range entity.fields {
+			names = append(names, field.path)
+		}
+	}
+	for idx := len(names); idx < len(node.DependentData); idx++ {
+		names = append(names, strconv.Itoa(idx))
+	}
+
+	return names
+}
+
+/*
+Field ...
+Return the parameter at path, a field name like "position.x" of the registered class or a parameter index
+*/
+func (node *Node) Field(path string) (Value, error) {
+	idx, err := node.fieldIndex(path)
+	if err != nil {
+		return nil, err
+	}
+	return node.DependentData[idx].Data, nil
+}
+
+/*
+SetField ...
+Set the parameter at path, the new value has to be of the same kind as the one it replaces
+*/
+func (node *Node) SetField(path string, value Value) error {
+	idx, err := node.fieldIndex(path)
+	if err != nil {
+		return err
+	}
+
+	old := node.DependentData[idx]
+	if value == nil || (old.Data != nil && old.Data.ident() != value.ident()) {
+		return fmt.Errorf("parameter %v is %v, it can't be set to %v", path, describe(old), describe(Variant{value}))
+	}
+	node.DependentData[idx] = Variant{value}
+
+	return nil
+}
+
+func (node *Node) fieldIndex(path string) (int, error) {
+	if idx, err := strconv.Atoi(path); err == nil {
+		if idx < 0 || idx >= len(node.DependentData) {
+			return 0, fmt.Errorf("node has %v parameters, there is no parameter %v", len(node.DependentData), idx)
+		}
+		return idx, nil
+	}
+
+	entity, ok := lookupClass(node)
+	if !ok {
+		return 0, fmt.Errorf("entity class %v is not registered, parameters can only be addressed by index", node.EntityClass.Data)
+	}
+	for idx, field := range entity.fields {
+		if field.path == path {
+			if idx >= len(node.DependentData) {
+				return 0, fmt.Errorf("node has %v parameters, %v is parameter %v", len(node.DependentData), path, idx)
+			}
+			return idx, nil
+		}
+	}
+
+	return 0, fmt.Errorf("entity class %v has no parameter %v", node.EntityClass.Data, path)
+}
+
+func describe(variant Variant) string {
+	if variant.Data == nil {
+		return "nothing"
+	}
+	return fmt.Sprintf("%v %v", variant.Data.ident(), variant.Data)
+}
@@ -0,0 +1,166 @@
+package fetm
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+)
+
+/*
+defaultClasses ...
+The classes registered before the tests register the Krusty Krab ones, which most of them use
+*/
+var defaultClasses []string
+
+func TestMain(m *testing.M) {
+	defaultClasses = EntityClasses()
+	RegisterKrustyKrabClasses()
+	os.Exit(m.Run())
+}
+
+func readLevel(t *testing.T) *FETM {
+	t.Helper()
+
+	file, err := Read(testgen.FETM().Data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	return file
+}
+
+func TestEntityRoundTrip(t *testing.T) {
+	file := readLevel(t)
+
+	entity, err := file.Nodes[0].Entity()
+	if err != nil {
+		t.Fatalf("reading the pickup: %v", err)
+	}
+	pickup, ok := entity.(*Pickup)
+	if !ok || !reflect.DeepEqual(pickup, &Pickup{Position: Vector{2.5, 0, -1}, Name: "coin", Rest: []Variant{}}) {
+		t.Fatalf("the pickup is %#v", entity)
+	}
+
+	pickup.Position.X = 3
+	pickup.Name = "gem"
+	pickup.Rest = []Variant{{U8(7)}}
+	err = file.Nodes[0].SetEntity(pickup)
+	if err != nil {
+		t.Fatalf("setting the pickup: %v", err)
+	}
+	err = file.Nodes[1].SetEntity(Spawner{Position: Vector{1, 2, 3}, Name: "exit"})
+	if err != nil {
+		t.Fatalf("setting the spawner: %v", err)
+	}
+
+	written, err := file.Write()
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	reread, err := Read(written)
+	if err != nil {
+		t.Fatalf("reading the written level: %v", err)
+	}
+
+	entity, err = reread.Nodes[0].Entity()
+	if err != nil || !reflect.DeepEqual(entity, &Pickup{Position: Vector{3, 0, -1}, Name: "gem", Rest: []Variant{{U8(7)}}}) {
+		t.Fatalf("the written pickup is %#v, %v", entity, err)
+	}
+	entity, err = reread.Nodes[1].Entity()
+	if err != nil || !reflect.DeepEqual(entity, &Spawner{Position: Vector{1, 2, 3}, Name: "exit", Rest: []Variant{}}) {
+		t.Fatalf("the written spawner is %#v, %v", entity, err)
+	}
+}
+
+func TestFields(t *testing.T) {
+	node := &readLevel(t).Nodes[0]
+	node.DependentData = append(node.DependentData, Variant{U8(7)})
+
+	if names := node.FieldNames(); !reflect.DeepEqual(names, []string{"position.x", "position.y", "position.z", "name", "4"}) {
+		t.Fatalf("the fields are %v", names)
+	}
+
+	value, err := node.Field("position.z")
+	if err != nil || value != F32(-1) {
+		t.Fatalf("position.z is %v, %v", value, err)
+	}
+	err = node.SetField("name", Str("gem"))
+	if err != nil || node.DependentData[3].Data != Str("gem") {
+		t.Fatalf("setting the name gave %v, it is %v", err, node.DependentData[3].Data)
+	}
+	err = node.SetField("4", U8(8))
+	if err != nil || node.DependentData[4].Data != U8(8) {
+		t.Fatalf("setting parameter 4 gave %v, it is %v", err, node.DependentData[4].Data)
+	}
+
+	if node.SetField("position.x", Str("left")) == nil {
+		t.Fatalf("setting an f32 to a str has to fail")
+	}
+	if _, err := node.Field("position.w"); err == nil {
+		t.Fatalf("a field the class doesn't have has to fail")
+	}
+	if _, err := node.Field("5"); err == nil {
+		t.Fatalf("a parameter past the last one has to fail")
+	}
+}
+
+func TestEntityErrors(t *testing.T) {
+	file := readLevel(t)
+	pickup := &file.Nodes[0]
+
+	for _, entity := range []interface{}{nil, (*Pickup)(nil), Spawner{}, 5} {
+		if err := pickup.SetEntity(entity); err == nil {
+			t.Fatalf("setting %#v on a pickup has to fail", entity)
+		}
+	}
+
+	//A node whose parameters don't fit its class is only readable by index.
+	pickup.DependentData[1] = Variant{Str("up")}
+	if _, err := pickup.Entity(); err == nil {
+		t.Fatalf("a pickup with a str position has to fail")
+	}
+	pickup.DependentData = pickup.DependentData[:2]
+	if _, err := pickup.Entity(); err == nil {
+		t.Fatalf("a pickup with two parameters has to fail")
+	}
+
+	other := Node{EntityClass: Variant{Str("Unknown")}, DependentData: []Variant{{U8(1)}}}
+	entity, err := other.Entity()
+	if err != nil || !reflect.DeepEqual(entity, RawEntity{{U8(1)}}) {
+		t.Fatalf("an unregistered class gave %#v, %v", entity, err)
+	}
+	if other.SetEntity(Pickup{}) == nil {
+		t.Fatalf("setting a pickup on an unregistered class has to fail")
+	}
+	if other.SetEntity(RawEntity{{U16(2)}}) != nil || other.DependentData[0].Data != U16(2) {
+		t.Fatalf("setting a raw entity didn't work, the node has %v", other.DependentData)
+	}
+}
+
+func TestRegisterEntityClass(t *testing.T) {
+	if len(defaultClasses) != 0 {
+		t.Fatalf("the provisional classes %v are registered without RegisterKrustyKrabClasses", defaultClasses)
+	}
+	if classes := EntityClasses(); !reflect.DeepEqual(classes, []string{"Pickup", "Spawner", "Trigger"}) {
+		t.Fatalf("the registered classes are %v", classes)
+	}
+	if count, fixed, ok := Arity("Trigger"); count != 7 || fixed || !ok {
+		t.Fatalf("a trigger has %v parameters, fixed %v and registered %v", count, fixed, ok)
+	}
+
+	bad := []interface{}{
+		5,
+		struct{ Count int }{},
+		struct {
+			Rest []Variant
+			Name Str
+		}{},
+		struct{ Inner struct{ Rest []Variant } }{},
+	}
+	for _, layout := range bad {
+		if err := RegisterEntityClass("Bad", layout); err == nil {
+			t.Fatalf("registering %#v has to fail", layout)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package fetm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+The text format writes the tree one value per line, every value as its kind and a literal:
+
+	header
+		u8 1
+		str "note"
+		u16 2
+		u32 0
+	world str "world"
+		f32 1.5
+	sector str "World Sector"
+		lead
+			u8 0
+			u8 1
+		u32 3
+	node u8 1 str "Factory" str "Class"
+		f32 0x7FC00000 # position.x
+
+Blocks start at the first column, their values are indented below them. Strings are quoted
+Go style so every byte survives, a str the file ends in without its terminator is written
+as unterminated "...". Non finite f32 values are written as their bits in hex.
+Everything after a # outside a string is a comment, node parameters of a registered entity
+class are commented with their field name.
+*/
+
+/*
+EncodeToText ...
+Encode FETM data to the text format or error
+*/
+func (data *FETM) EncodeToText() ([]byte, error) {
+	var buffer bytes.Buffer
+
+	header := []Variant{data.Header.Magic, data.Header.Note, data.Header.SectionCount, data.Header.Unknown}
+	buffer.WriteString("header\n")
+	for idx, value := range header {
+		if value.Data == nil {
+			return nil, fmt.Errorf("header value %v holds no data", idx)
+		}
+		buffer.WriteString("\t" + formatText(value.Data) + "\n")
+	}
+
+	buffer.WriteString("world")
+	if data.World.Name.Data != nil {
+		buffer.WriteString(" " + formatText(data.World.Name.Data))
+	}
+	buffer.WriteString("\n")
+	err := writeTextValues(&buffer, "\t", data.World.Params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("world %v", err)
+	}
+
+	if data.Sector.Name.Data != nil || len(data.Sector.Lead) > 0 || len(data.Sector.Params) > 0 {
+		buffer.WriteString("sector")
+		if data.Sector.Name.Data != nil {
+			buffer.WriteString(" " + formatText(data.Sector.Name.Data))
+		}
+		buffer.WriteString("\n")
+		if len(data.Sector.Lead) > 0 {
+			buffer.WriteString("\tlead\n")
+			err = writeTextValues(&buffer, "\t\t", data.Sector.Lead, nil)
+			if err != nil {
+				return nil, fmt.Errorf("sector lead %v", err)
+			}
+		}
+		err = writeTextValues(&buffer, "\t", data.Sector.Params, nil)
+		if err != nil {
+			return nil, fmt.Errorf("sector %v", err)
+		}
+	}
+
+	for idx, node := range data.Nodes {
+		if node.NodeType.Data == nil || node.NodeFactory.Data == nil || node.EntityClass.Data == nil {
+			return nil, fmt.Errorf("node %v is missing its type, factory or entity class", idx)
+		}
+		fmt.Fprintf(&buffer, "node %v %v %v\n", formatText(node.NodeType.Data), formatText(node.NodeFactory.Data), formatText(node.EntityClass.Data))
+
+		err = writeTextValues(&buffer, "\t", node.DependentData, node.FieldNames())
+		if err != nil {
+			return nil, fmt.Errorf("node %v %v", idx, err)
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func writeTextValues(buffer *bytes.Buffer, indent string, values []Variant, names []string) error {
+	for idx, value := range values {
+		if value.Data == nil {
+			return fmt.Errorf("value %v holds no data", idx)
+		}
+		buffer.WriteString(indent + formatText(value.Data))
+		//Index names carry nothing the line order doesn't, only field names are worth a comment.
+		if idx < len(names) && names[idx] != strconv.Itoa(idx) {
+			buffer.WriteString(" # " + names[idx])
+		}
+		buffer.WriteString("\n")
+	}
+	return nil
+}
+
+func formatText(value Value) string {
+	switch value := value.(type) {
+	case S8:
+		return fmt.Sprintf("s8 %d", value)
+	case U8:
+		return fmt.Sprintf("u8 %d", value)
+	case S16:
+		return fmt.Sprintf("s16 %d", value)
+	case U16:
+		return fmt.Sprintf("u16 %d", value)
+	case U32:
+		return fmt.Sprintf("u32 %d", value)
+	case Hex:
+		return fmt.Sprintf("hex 0x%08X", uint32(value))
+	case F32:
+		if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+			return fmt.Sprintf("f32 0x%08X", math.Float32bits(float32(value)))
+		}
+		return "f32 " + strconv.FormatFloat(float64(value), 'g', -1, 32)
+	case Str:
+		return "str " + strconv.Quote(string(value))
+	case UnterminatedStr:
+		return "unterminated " + strconv.Quote(string(value))
+	}
+	return ""
+}
+
+/*
+DecodeFromText ...
+Read data in the text format and decode it to FETM structure or error
+*/
+func DecodeFromText(data []byte) (*FETM, error) {
+	fetm := &FETM{}
+
+	var header []Variant
+	section := ""
+	leadIndent := -1
+	hasWorld := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := scanner.Text()
+		indent := len(text) - len(strings.TrimLeft(text, " \t"))
+		fields, err := splitText(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		if indent == 0 {
+			values, err := parseTextValues(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v", line, err)
+			}
+
+			section = fields[0]
+			leadIndent = -1
+			switch section {
+			case "header":
+				if len(values) != 0 || len(header) != 0 {
+					return nil, fmt.Errorf("line %v: there can only be one header and it takes no values", line)
+				}
+			case "world":
+				if len(values) > 1 || hasWorld || len(fetm.Sector.Lead) > 0 || fetm.Sector.Name.Data != nil || len(fetm.Nodes) > 0 {
+					return nil, fmt.Errorf("line %v: there can only be one world, before the sector and nodes, with at most one value", line)
+				}
+				hasWorld = true
+				if len(values) == 1 {
+					fetm.World.Name = values[0]
+				}
+			case "sector":
+				if len(values) > 1 || len(fetm.Nodes) > 0 {
+					return nil, fmt.Errorf("line %v: the sector comes before the nodes and has at most one value", line)
+				}
+				if len(values) == 1 {
+					fetm.Sector.Name = values[0]
+				}
+			case "node":
+				if len(values) != 3 {
+					return nil, fmt.Errorf("line %v: a node needs its type, factory and entity class, it has %v values", line, len(values))
+				}
+				fetm.Nodes = append(fetm.Nodes, Node{NodeType: values[0], NodeFactory: values[1], EntityClass: values[2]})
+			default:
+				return nil, fmt.Errorf("line %v: unknown block %v", line, section)
+			}
+			continue
+		}
+
+		if section == "sector" && fields[0] == "lead" && len(fields) == 1 {
+			if leadIndent >= 0 || len(fetm.Sector.Params) > 0 {
+				return nil, fmt.Errorf("line %v: the sector lead has to come first and only once", line)
+			}
+			leadIndent = indent
+			continue
+		}
+
+		values, err := parseTextValues(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+
+		switch section {
+		case "header":
+			header = append(header, values...)
+		case "world":
+			fetm.World.Params = append(fetm.World.Params, values...)
+		case "sector":
+			if leadIndent < 0 || indent <= leadIndent {
+				fetm.Sector.Params = append(fetm.Sector.Params, values...)
+			} else if len(fetm.Sector.Params) == 0 {
+				fetm.Sector.Lead = append(fetm.Sector.Lead, values...)
+			} else {
+				return nil, fmt.Errorf("line %v: the sector lead has to come before the sector values", line)
+			}
+		case "node":
+			node := &fetm.Nodes[len(fetm.Nodes)-1]
+			node.DependentData = append(node.DependentData, values...)
+		default:
+			return nil, fmt.Errorf("line %v: values have to be inside a block", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(header) != 4 {
+		return nil, fmt.Errorf("the header has %v values, it needs 4", len(header))
+	}
+	fetm.Header = Header{Magic: header[0], Note: header[1], SectionCount: header[2], Unknown: header[3]}
+
+	return fetm, nil
+}
+
+/*
+splitText ...
+Split a line into its words, a quoted string is one word and a # outside one ends the line
+*/
+func splitText(text string) ([]string, error) {
+	var fields []string
+
+	for {
+		text = strings.TrimLeft(text, " \t")
+		if text == "" || text[0] == '#' {
+			return fields, nil
+		}
+
+		if text[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(text)
+			if err != nil {
+				return nil, fmt.Errorf("unterminated string %v", text)
+			}
+			fields = append(fields, quoted)
+			text = text[len(quoted):]
+			continue
+		}
+
+		end := strings.IndexAny(text, " \t#")
+		if end < 0 {
+			end = len(text)
+		}
+		fields = append(fields, text[:end])
+		text = text[end:]
+	}
+}
+
+func parseTextValues(fields []string) ([]Variant, error) {
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("every value needs a kind and a literal, %v is missing one", fields[len(fields)-1])
+	}
+
+	var values []Variant
+	for idx := 0; idx < len(fields); idx += 2 {
+		value, err := parseText(fields[idx], fields[idx+1])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, Variant{value})
+	}
+
+	return values, nil
+}
+
+func parseText(kind string, literal string) (Value, error) {
+	var value Value
+	var err error
+
+	switch kind {
+	case "s8":
+		var parsed int64
+		parsed, err = strconv.ParseInt(literal, 0, 8)
+		value = S8(parsed)
+	case "u8":
+		var parsed uint64
+		parsed, err = strconv.ParseUint(literal, 0, 8)
+		value = U8(parsed)
+	case "s16":
+		var parsed int64
+		parsed, err = strconv.ParseInt(literal, 0, 16)
+		value = S16(parsed)
+	case "u16":
+		var parsed uint64
+		parsed, err = strconv.ParseUint(literal, 0, 16)
+		value = U16(parsed)
+	case "u32":
+		var parsed uint64
+		parsed, err = strconv.ParseUint(literal, 0, 32)
+		value = U32(parsed)
+	case "hex":
+		var parsed uint64
+		parsed, err = strconv.ParseUint(literal, 0, 32)
+		value = Hex(parsed)
+	case "f32":
+		if strings.HasPrefix(literal, "0x") || strings.HasPrefix(literal, "0X") {
+			var bits uint64
+			bits, err = strconv.ParseUint(literal[2:], 16, 32)
+			value = F32(math.Float32frombits(uint32(bits)))
+		} else {
+			var parsed float64
+			parsed, err = strconv.ParseFloat(literal, 32)
+			value = F32(parsed)
+		}
+	case "str":
+		var parsed string
+		parsed, err = strconv.Unquote(literal)
+		value = Str(parsed)
+	case "unterminated":
+		var parsed string
+		parsed, err = strconv.Unquote(literal)
+		value = UnterminatedStr(parsed)
+	default:
+		return nil, fmt.Errorf("unknown kind %v", kind)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%v is not a valid %v", literal, kind)
+	}
+
+	return value, nil
+}
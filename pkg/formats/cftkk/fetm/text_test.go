@@ -0,0 +1,124 @@
+package fetm
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTextRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(file *FETM)
+	}{
+		{"level", func(file *FETM) {}},
+		{"escaped quotes", func(file *FETM) {
+			file.Nodes[0].DependentData[3] = Variant{Str("say \"hi\" # not a comment\\")}
+		}},
+		{"non utf-8", func(file *FETM) {
+			file.Nodes[1].DependentData[3] = Variant{Str("\xFF\xFEstart\x80")}
+		}},
+		{"unterminated final string", func(file *FETM) {
+			file.Nodes[1].DependentData = append(file.Nodes[1].DependentData, Variant{UnterminatedStr("tail \"\xC0")})
+		}},
+		{"empty unterminated string", func(file *FETM) {
+			file.Nodes[1].DependentData = append(file.Nodes[1].DependentData, Variant{UnterminatedStr("")})
+		}},
+		{"non finite f32", func(file *FETM) {
+			file.World.Params[0] = Variant{F32(math.Float32frombits(0x7FC00001))}
+			file.Nodes[0].DependentData[0] = Variant{F32(math.Inf(-1))}
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := readLevel(t)
+			test.edit(file)
+			want, err := file.Write()
+			if err != nil {
+				t.Fatalf("writing: %v", err)
+			}
+
+			text, err := file.EncodeToText()
+			if err != nil {
+				t.Fatalf("encoding: %v", err)
+			}
+			parsed, err := DecodeFromText(text)
+			if err != nil {
+				t.Fatalf("decoding: %v\n%s", err, text)
+			}
+
+			//The written bytes compare NaN by its bits, which a deep compare of the values can't.
+			got, err := parsed.Write()
+			if err != nil {
+				t.Fatalf("writing the decoded level: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("the decoded level is\n% X\nnot\n% X", got, want)
+			}
+
+			again, err := parsed.EncodeToText()
+			if err != nil || !bytes.Equal(again, text) {
+				t.Fatalf("encoding the decoded level gave %v\n%s\nnot\n%s", err, again, text)
+			}
+		})
+	}
+}
+
+func TestTextComments(t *testing.T) {
+	text, err := readLevel(t).EncodeToText()
+	if err != nil {
+		t.Fatalf("encoding: %v", err)
+	}
+	if !strings.Contains(string(text), "\tstr \"coin\" # name\n") || !strings.Contains(string(text), "\tf32 2.5 # position.x\n") {
+		t.Fatalf("the pickup parameters aren't commented with their fields\n%s", text)
+	}
+
+	commented := "# a level\n" + strings.Replace(string(text), "world str \"world\"\n", "world str \"world\" # the world block\n", 1) + "\n\t\n"
+	if commented == "# a level\n"+string(text)+"\n\t\n" {
+		t.Fatalf("the level has no world line to comment\n%s", text)
+	}
+	file, err := DecodeFromText([]byte(commented))
+	if err != nil {
+		t.Fatalf("decoding with comments: %v", err)
+	}
+	again, err := file.EncodeToText()
+	if err != nil || !bytes.Equal(again, text) {
+		t.Fatalf("the comments changed the level, %v\n%s", err, again)
+	}
+}
+
+func TestDecodeFromTextErrors(t *testing.T) {
+	const header = "header\n\tu8 1\n\tstr \"note\"\n\tu8 2\n\tu32 0\n"
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"unterminated quote", header + "world str \"world\n"},
+		{"unterminated final line", header + "world\n\tstr \"end"},
+		{"bad escape", header + "world\n\tstr \"\\q\"\n"},
+		{"unquoted string", header + "world\n\tstr world\n"},
+		{"unknown kind", header + "world\n\ts32 1\n"},
+		{"missing literal", header + "world\n\tu8\n"},
+		{"out of range", header + "world\n\tu8 256\n"},
+		{"negative unsigned", header + "world\n\tu16 -1\n"},
+		{"bad f32 bits", header + "world\n\tf32 0xFFFFFFFFF\n"},
+		{"unknown block", header + "level\n"},
+		{"value outside a block", "\tu8 1\n" + header},
+		{"second header", header + header},
+		{"short header", "header\n\tu8 1\n\tstr \"note\"\n\tu8 2\nworld\n"},
+		{"world after the nodes", header + "world\nnode u16 1 str \"x\" str \"Pickup\"\nworld\n"},
+		{"node without its class", header + "world\nnode u16 1 str \"x\"\n"},
+		{"lead after the sector values", header + "world\nsector\n\tu8 1\n\tlead\n\t\tu8 2\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if file, err := DecodeFromText([]byte(test.text)); err == nil {
+				t.Fatalf("decoding has to fail, it gave %+v", file)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package fetm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+Token ...
+A value read from a FETM file together with the offset of its identifier byte.
+*/
+type Token struct {
+	Offset int
+	Variant
+}
+
+func (ident identifer) String() string {
+	switch ident {
+	case s8:
+		return "s8"
+	case u8:
+		return "u8"
+	case s16:
+		return "s16"
+	case u16:
+		return "u16"
+	case u32:
+		return "u32"
+	case hex:
+		return "hex"
+	case f32:
+		return "f32"
+	case str:
+		return "str"
+	}
+	return fmt.Sprintf("identifier 0x%02X", uint8(ident))
+}
+
+/*
+Tokenize ...
+Split FETM data into its values. Every value is checked to fit in data, a value that
+doesn't, or an unknown identifier, is reported with the offset it starts at. A str the
+data ends in before its terminator is returned as an UnterminatedStr.
+*/
+func Tokenize(data []byte) ([]Token, error) {
+	var tokens []Token
+
+	readIndex := 0
+	for readIndex < len(data) {
+		curIdent := identifer(data[readIndex])
+
+		size := 0
+		switch curIdent {
+		case s8, u8:
+			size = 1
+		case s16, u16:
+			size = 2
+		case u32, hex, f32:
+			size = 4
+		case str:
+			end := bytes.IndexByte(data[readIndex+1:], 0x00)
+			if end < 0 {
+				//The file ends inside the string, keep what there is so it is written back the same.
+				payload := data[readIndex+1:]
+				tokens = append(tokens, Token{Offset: readIndex, Variant: Variant{UnterminatedStr(payload)}})
+				return tokens, nil
+			}
+			size = end + 1
+		default:
+			return tokens, formatError(readIndex, "value identifier", fmt.Errorf("%w, there is no identifier 0x%02X", formats.ErrInvalid, uint8(curIdent)))
+		}
+
+		if readIndex+1+size > len(data) {
+			return tokens, formatError(readIndex, fmt.Sprintf("%v value", curIdent), formats.ErrTruncated)
+		}
+		payload := data[readIndex+1 : readIndex+1+size]
+
+		var value Value
+		switch curIdent {
+		case s8:
+			value = S8(int8(payload[0]))
+		case u8:
+			value = U8(payload[0])
+		case s16:
+			value = S16(int16(binary.BigEndian.Uint16(payload)))
+		case u16:
+			value = U16(binary.BigEndian.Uint16(payload))
+		case u32:
+			value = U32(binary.BigEndian.Uint32(payload))
+		case hex:
+			value = Hex(binary.BigEndian.Uint32(payload))
+		case f32:
+			value = F32(math.Float32frombits(binary.BigEndian.Uint32(payload)))
+		case str:
+			value = Str(payload[:size-1])
+		}
+
+		tokens = append(tokens, Token{Offset: readIndex, Variant: Variant{value}})
+		readIndex += 1 + size
+	}
+
+	return tokens, nil
+}
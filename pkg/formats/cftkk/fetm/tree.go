@@ -0,0 +1,129 @@
+package fetm
+
+import (
+	"fmt"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+const (
+	worldMarker  = Str("world")
+	sectorMarker = Str("World Sector")
+)
+
+/*
+parse ...
+Build the world, sector and node tree from a flat list of values.
+
+Nothing in the file says where a block or node ends, so the tree is found by its markers:
+the world starts at the "world" value, the sector two values before the first "World Sector"
+value, and every node at a value that is followed by two strings, its factory and entity class.
+Whatever the split, Tokens gives back exactly the values that were parsed.
+*/
+func parse(raw []Variant) (*FETM, error) {
+	fetm := &FETM{}
+
+	if len(raw) < 4 {
+		return nil, formatError(-1, "header", fmt.Errorf("%w, there are only %v values and it needs 4", formats.ErrTruncated, len(raw)))
+	}
+
+	fetm.Header.Magic = raw[0]
+	fetm.Header.Note = raw[1]
+	fetm.Header.SectionCount = raw[2]
+	fetm.Header.Unknown = raw[3]
+
+	rest := raw[4:]
+
+	marker := -1
+	for idx, value := range rest {
+		if value.Data == sectorMarker {
+			marker = idx
+			break
+		}
+	}
+
+	worldEnd := len(rest)
+	if marker >= 0 {
+		worldEnd = marker - 2
+		if worldEnd < 0 {
+			worldEnd = 0
+		}
+	}
+
+	world := rest[:worldEnd]
+	if len(world) > 0 && world[0].Data == worldMarker {
+		fetm.World.Name = world[0]
+		world = world[1:]
+	}
+	fetm.World.Params = world
+
+	if marker < 0 {
+		return fetm, nil
+	}
+
+	fetm.Sector.Lead = rest[worldEnd:marker]
+	fetm.Sector.Name = rest[marker]
+
+	nodes := rest[marker+1:]
+	start := nextNode(nodes, 0)
+	fetm.Sector.Params = nodes[:start]
+
+	for start < len(nodes) {
+		end := nextNode(nodes, start+3)
+		fetm.Nodes = append(fetm.Nodes, Node{
+			NodeType:      nodes[start],
+			NodeFactory:   nodes[start+1],
+			EntityClass:   nodes[start+2],
+			DependentData: nodes[start+3 : end],
+		})
+		start = end
+	}
+
+	return fetm, nil
+}
+
+/*
+nextNode ...
+Return the index of the first node starting at or after from, or len(values) if there is none
+*/
+func nextNode(values []Variant, from int) int {
+	for idx := from; idx+2 < len(values); idx++ {
+		if _, ok := values[idx].Data.(Str); ok {
+			continue
+		}
+		_, factory := values[idx+1].Data.(Str)
+		_, class := values[idx+2].Data.(Str)
+		if factory && class {
+			return idx
+		}
+	}
+	return len(values)
+}
+
+/*
+Tokens ...
+Flatten the tree back to the list of values it is written as
+*/
+func (data *FETM) Tokens() []Variant {
+	var raw []Variant
+
+	raw = append(raw, data.Header.Magic, data.Header.Note, data.Header.SectionCount, data.Header.Unknown)
+
+	if data.World.Name.Data != nil {
+		raw = append(raw, data.World.Name)
+	}
+	raw = append(raw, data.World.Params...)
+
+	raw = append(raw, data.Sector.Lead...)
+	if data.Sector.Name.Data != nil {
+		raw = append(raw, data.Sector.Name)
+	}
+	raw = append(raw, data.Sector.Params...)
+
+	for _, node := range data.Nodes {
+		raw = append(raw, node.NodeType, node.NodeFactory, node.EntityClass)
+		raw = append(raw, node.DependentData...)
+	}
+
+	return raw
+}
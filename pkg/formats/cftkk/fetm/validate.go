@@ -0,0 +1,167 @@
+package fetm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+Problem ...
+Something wrong with a FETM file, Offset is the byte offset of the value it is about and Field
+names that value, like "section count" or "node 0 position.x". Warning is set for nodes that
+don't fit the layout of their registered entity class: the layouts are provisional, so it may
+be the layout that is wrong and not the level.
+*/
+type Problem struct {
+	Offset  int
+	Field   string
+	Message string
+	Warning bool
+}
+
+func (problem Problem) String() string {
+	if problem.Warning {
+		return fmt.Sprintf("warning: 0x%X %v: %v", problem.Offset, problem.Field, problem.Message)
+	}
+	return fmt.Sprintf("0x%X %v: %v", problem.Offset, problem.Field, problem.Message)
+}
+
+/*
+Validate ...
+Check FETM data for everything the game relies on and Read lets through: the header magic,
+that every value is complete and every string terminated, that the header section count
+matches the world and sector markers there are, that the world and sector markers exist,
+and that nodes of a registered entity class have the parameters it is registered with, the
+last as warnings. Every problem found is returned, the file is fine when there are none but
+warnings.
+*/
+func Validate(data []byte) []Problem {
+	var problems []Problem
+	report := func(offset int, field string, format string, args ...interface{}) {
+		problems = append(problems, Problem{Offset: offset, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(offset int, field string, format string, args ...interface{}) {
+		problems = append(problems, Problem{Offset: offset, Field: field, Message: fmt.Sprintf(format, args...), Warning: true})
+	}
+
+	if len(data) < 3 || !bytes.Equal(data[:3], []byte{0x01, 0x7C, 0x07}) {
+		report(0, "magic", "the file does not start with the FETM header magic")
+	}
+
+	tokens, err := Tokenize(data)
+	if err != nil {
+		//Nothing after a broken value can be read, so the structure isn't checked.
+		var formatErr *formats.FormatError
+		if errors.As(err, &formatErr) {
+			report(formatErr.Offset, formatErr.Field, "%v", formatErr.Err)
+		} else {
+			report(0, "values", "%v", err)
+		}
+		return problems
+	}
+
+	if len(tokens) > 0 {
+		last := tokens[len(tokens)-1]
+		if _, ok := last.Data.(UnterminatedStr); ok {
+			report(last.Offset, "str terminator", "the file ends in a str without its terminator")
+		}
+	}
+
+	if len(tokens) < 4 {
+		report(len(data), "header", "there are only %v values, the header needs 4", len(tokens))
+		return problems
+	}
+
+	raw := make([]Variant, len(tokens))
+	for idx, token := range tokens {
+		raw[idx] = token.Variant
+	}
+	fetm, err := parse(raw)
+	if err != nil {
+		report(0, "values", "%v", err)
+		return problems
+	}
+
+	//Sections are the world and every sector, wherever their markers are.
+	markers := 0
+	hasSector := false
+	for _, token := range tokens {
+		switch token.Data {
+		case worldMarker:
+			markers++
+		case sectorMarker:
+			markers++
+			hasSector = true
+		}
+	}
+
+	if count, ok := integer(fetm.Header.SectionCount.Data); !ok {
+		report(tokens[2].Offset, "section count", "the section count is %v, it should be an integer", describe(fetm.Header.SectionCount))
+	} else if count != markers {
+		report(tokens[2].Offset, "section count", "the header counts %v sections, there are %v world and sector markers", count, markers)
+	}
+
+	if len(tokens) < 5 {
+		report(len(data), "world marker", "the world marker %q should follow the header, the file ends", worldMarker)
+	} else if fetm.World.Name.Data != worldMarker {
+		report(tokens[4].Offset, "world marker", "the world marker %q should follow the header, found %v", worldMarker, describe(raw[4]))
+	}
+	if !hasSector {
+		report(len(data), "sector marker", "there is no %q marker", sectorMarker)
+	}
+
+	//Find the first value of every node from the sizes of the blocks in front of them.
+	index := 4 + len(fetm.World.Params) + len(fetm.Sector.Lead) + len(fetm.Sector.Params)
+	if fetm.World.Name.Data != nil {
+		index++
+	}
+	if fetm.Sector.Name.Data != nil {
+		index++
+	}
+
+	for nodeIdx, node := range fetm.Nodes {
+		start := index
+		index += 3 + len(node.DependentData)
+
+		entity, ok := lookupClass(&node)
+		if !ok {
+			continue
+		}
+
+		if len(node.DependentData) < len(entity.fields) || (entity.rest == nil && len(node.DependentData) != len(entity.fields)) {
+			warn(tokens[start].Offset, fmt.Sprintf("node %v parameters", nodeIdx), "node %v of entity class %v has %v parameters, the class has %v", nodeIdx, node.EntityClass.Data, len(node.DependentData), len(entity.fields))
+		}
+		for idx, field := range entity.fields {
+			if idx >= len(node.DependentData) {
+				break
+			}
+			param := node.DependentData[idx]
+			if param.Data.ident() != field.ident {
+				warn(tokens[start+3+idx].Offset, fmt.Sprintf("node %v %v", nodeIdx, field.path), "parameter %v of node %v should be %v, it is %v", field.path, nodeIdx, field.ident, describe(param))
+			}
+		}
+	}
+
+	return problems
+}
+
+func integer(value Value) (int, bool) {
+	switch value := value.(type) {
+	case S8:
+		return int(value), true
+	case U8:
+		return int(value), true
+	case S16:
+		return int(value), true
+	case U16:
+		return int(value), true
+	case U32:
+		return int(value), true
+	case Hex:
+		return int(value), true
+	}
+	return 0, false
+}
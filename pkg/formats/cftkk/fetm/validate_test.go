@@ -0,0 +1,95 @@
+package fetm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+)
+
+/*
+TestValidate ...
+Corrupt the generated level and check every problem is found at its value. The values of the
+level start at: 0x08 the section count, 0x0F the world marker, 0x1B an s8 of the world,
+0x28 the sector marker, 0x38 the Pickup node with its parameters at 0x4C, 0x51, 0x56 and 0x5B,
+0x61 the Spawner node with its parameters at 0x70, 0x75, 0x7A and 0x7F. The level is 0x86 bytes.
+*/
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(data []byte) []byte
+		want    []Problem
+	}{
+		{"valid", func(data []byte) []byte { return data }, nil},
+		{"bad magic", func(data []byte) []byte {
+			data[1] = 0x7D
+			return data
+		}, []Problem{{0x00, "magic", "header magic", false}}},
+		{"unknown identifier", func(data []byte) []byte {
+			data[0x1B] = 0x09
+			return data
+		}, []Problem{{0x1B, "value identifier", "no identifier 0x09", false}}},
+		{"truncated value", func(data []byte) []byte {
+			return data[:0x77]
+		}, []Problem{{0x75, "f32 value", "truncated", false}}},
+		{"unterminated final string", func(data []byte) []byte {
+			return data[:0x82]
+		}, []Problem{{0x7F, "str terminator", "without its terminator", false}}},
+		{"too few values", func(data []byte) []byte {
+			return data[:0x0A]
+		}, []Problem{{0x0A, "header", "only 3 values", false}}},
+		{"ends after the header", func(data []byte) []byte {
+			return data[:0x0F]
+		}, []Problem{{0x08, "section count", "there are 0 world and sector markers", false}, {0x0F, "world marker", "the file ends", false},
+			{0x0F, "sector marker", "no \"World Sector\" marker", false}}},
+		{"wrong section count", func(data []byte) []byte {
+			data[0x09] = 3
+			return data
+		}, []Problem{{0x08, "section count", "counts 3 sections, there are 2", false}}},
+		{"section count not an integer", func(data []byte) []byte {
+			//An empty str is as long as the u8 it replaces.
+			data[0x08], data[0x09] = 0x07, 0x00
+			return data
+		}, []Problem{{0x08, "section count", "should be an integer", false}}},
+		{"no world marker", func(data []byte) []byte {
+			data[0x14] = 'e'
+			return data
+		}, []Problem{{0x08, "section count", "there are 1 world and sector markers", false}, {0x0F, "world marker", "found str worle", false}}},
+		{"no sector marker", func(data []byte) []byte {
+			data[0x34] = 's'
+			return data
+		}, []Problem{{0x08, "section count", "there are 1 world and sector markers", false}, {0x86, "sector marker", "no \"World Sector\" marker", false}}},
+		{"wrong parameter kind", func(data []byte) []byte {
+			data[0x51] = 0x04
+			return data
+		}, []Problem{{0x51, "node 0 position.y", "should be f32, it is u32 0", true}}},
+		{"too few parameters", func(data []byte) []byte {
+			return data[:0x7F]
+		}, []Problem{{0x61, "node 1 parameters", "has 3 parameters, the class has 4", true}}},
+		{"too few parameters and wrong kind", func(data []byte) []byte {
+			data[0x56] = 0x05
+			return data[:0x5B]
+		}, []Problem{{0x38, "node 0 parameters", "has 3 parameters, the class has 4", true}, {0x56, "node 0 position.z", "should be f32, it is hex", true}}},
+		{"unregistered class", func(data []byte) []byte {
+			//Pickup becomes Pickus, whose parameters aren't known and so can't be wrong.
+			data[0x4A] = 's'
+			data[0x51] = 0x04
+			return data[:0x5B]
+		}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems := Validate(test.corrupt(testgen.FETM().Data))
+			if len(problems) != len(test.want) {
+				t.Fatalf("found %v, not %v problems", problems, len(test.want))
+			}
+			for idx, want := range test.want {
+				got := problems[idx]
+				if got.Offset != want.Offset || got.Field != want.Field || !strings.Contains(got.Message, want.Message) || got.Warning != want.Warning {
+					t.Fatalf("problem %v is %v, not at 0x%X %v with %q, warning %v", idx, got, want.Offset, want.Field, want.Message, want.Warning)
+				}
+			}
+		})
+	}
+}
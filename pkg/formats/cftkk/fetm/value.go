@@ -0,0 +1,204 @@
+package fetm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+Value ...
+One typed FETM value. It is one of S8, U8, S16, U16, U32, Hex, F32, Str or UnterminatedStr,
+each of them is written to the file behind its own identifier byte.
+*/
+type Value interface {
+	ident() identifer
+}
+
+type S8 int8
+type U8 uint8
+type S16 int16
+type U16 uint16
+type U32 uint32
+type Hex uint32
+type F32 float32
+type Str string
+
+/*
+UnterminatedStr ...
+A str the file ends in before its terminator. It is written back without one, so it can only be the last value.
+*/
+type UnterminatedStr string
+
+func (S8) ident() identifer  { return s8 }
+func (U8) ident() identifer  { return u8 }
+func (S16) ident() identifer { return s16 }
+func (U16) ident() identifer { return u16 }
+func (U32) ident() identifer { return u32 }
+func (Hex) ident() identifer { return hex }
+func (F32) ident() identifer { return f32 }
+func (Str) ident() identifer { return str }
+
+func (UnterminatedStr) ident() identifer { return str }
+
+/*
+Variant ...
+A FETM value as it is stored in Raw. In JSON it keeps its identifier next to the data,
+{"Ident":6,"Data":1.5}, so the exact type survives a trip through JSON.
+*/
+type Variant struct {
+	Data Value
+}
+
+/*
+Ident ...
+Return the identifier byte the value is written with
+*/
+func (variant Variant) Ident() uint8 {
+	if variant.Data == nil {
+		return 0xFF
+	}
+	return uint8(variant.Data.ident())
+}
+
+/*
+jsonVariant ...
+Strings that aren't valid UTF-8 can't be json strings, their bytes are kept as base64 in Data instead.
+*/
+type jsonVariant struct {
+	Ident        identifer
+	Data         json.RawMessage
+	Base64       bool `json:",omitempty"`
+	Unterminated bool `json:",omitempty"`
+}
+
+func (variant Variant) MarshalJSON() ([]byte, error) {
+	//Optional values, like a missing world marker, are null.
+	if variant.Data == nil {
+		return []byte("null"), nil
+	}
+
+	var data interface{}
+	encoded := jsonVariant{Ident: variant.Data.ident()}
+
+	switch value := variant.Data.(type) {
+	case S8:
+		data = int8(value)
+	case U8:
+		data = uint8(value)
+	case S16:
+		data = int16(value)
+	case U16:
+		data = uint16(value)
+	case U32:
+		data = uint32(value)
+	case Hex:
+		data = uint32(value)
+	case F32:
+		//JSON has no NaN or infinity, those are kept as their bits instead.
+		if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+			data = fmt.Sprintf("0x%08X", math.Float32bits(float32(value)))
+		} else {
+			data = float32(value)
+		}
+	case Str:
+		data, encoded.Base64 = jsonString(string(value))
+	case UnterminatedStr:
+		data, encoded.Base64 = jsonString(string(value))
+		encoded.Unterminated = true
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	encoded.Data = raw
+
+	return json.Marshal(encoded)
+}
+
+func jsonString(value string) (interface{}, bool) {
+	if utf8.ValidString(value) {
+		return value, false
+	}
+	return []byte(value), true
+}
+
+func (variant *Variant) UnmarshalJSON(raw []byte) error {
+	if string(raw) == "null" {
+		variant.Data = nil
+		return nil
+	}
+
+	var decoded jsonVariant
+	err := json.Unmarshal(raw, &decoded)
+	if err != nil {
+		return err
+	}
+
+	switch decoded.Ident {
+	case s8:
+		var value int8
+		err = json.Unmarshal(decoded.Data, &value)
+		variant.Data = S8(value)
+	case u8:
+		var value uint8
+		err = json.Unmarshal(decoded.Data, &value)
+		variant.Data = U8(value)
+	case s16:
+		var value int16
+		err = json.Unmarshal(decoded.Data, &value)
+		variant.Data = S16(value)
+	case u16:
+		var value uint16
+		err = json.Unmarshal(decoded.Data, &value)
+		variant.Data = U16(value)
+	case u32:
+		var value uint32
+		err = json.Unmarshal(decoded.Data, &value)
+		variant.Data = U32(value)
+	case hex:
+		var value uint32
+		err = json.Unmarshal(decoded.Data, &value)
+		variant.Data = Hex(value)
+	case f32:
+		var value float32
+		err = json.Unmarshal(decoded.Data, &value)
+		if err != nil {
+			var bits string
+			if json.Unmarshal(decoded.Data, &bits) == nil {
+				var parsed uint64
+				parsed, err = strconv.ParseUint(strings.TrimPrefix(bits, "0x"), 16, 32)
+				value = math.Float32frombits(uint32(parsed))
+			}
+		}
+		variant.Data = F32(value)
+	case str:
+		var value string
+		if decoded.Base64 {
+			var decodedBytes []byte
+			err = json.Unmarshal(decoded.Data, &decodedBytes)
+			value = string(decodedBytes)
+		} else {
+			err = json.Unmarshal(decoded.Data, &value)
+		}
+		if decoded.Unterminated {
+			variant.Data = UnterminatedStr(value)
+		} else {
+			variant.Data = Str(value)
+		}
+	default:
+		return fmt.Errorf("%w, unknown identifier %v in json", formats.ErrInvalid, decoded.Ident)
+	}
+
+	if err != nil {
+		return fmt.Errorf("data of identifier %v doesn't fit, %w", decoded.Ident, err)
+	}
+
+	return nil
+}
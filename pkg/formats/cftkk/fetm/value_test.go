@@ -0,0 +1,120 @@
+package fetm
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestVariantJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Value
+		want  string
+	}{
+		{"s8", S8(-2), `{"Ident":0,"Data":-2}`},
+		{"hex", Hex(0xDEADBEEF), `{"Ident":5,"Data":3735928559}`},
+		{"f32", F32(1.5), `{"Ident":6,"Data":1.5}`},
+		{"f32 nan", F32(math.Float32frombits(0x7FC00001)), `{"Ident":6,"Data":"0x7FC00001"}`},
+		{"str", Str("coin"), `{"Ident":7,"Data":"coin"}`},
+		{"escaped quotes", Str(`say "hi" \n`), `{"Ident":7,"Data":"say \"hi\" \\n"}`},
+		{"empty str", Str(""), `{"Ident":7,"Data":""}`},
+		{"non utf-8", Str("\xFF\xFE"), `{"Ident":7,"Data":"//4=","Base64":true}`},
+		{"unterminated", UnterminatedStr("tail"), `{"Ident":7,"Data":"tail","Unterminated":true}`},
+		{"unterminated non utf-8", UnterminatedStr("\x80"), `{"Ident":7,"Data":"gA==","Base64":true,"Unterminated":true}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encoded, err := json.Marshal(Variant{test.value})
+			if err != nil || string(encoded) != test.want {
+				t.Fatalf("encoding gave %s, %v, not %s", encoded, err, test.want)
+			}
+
+			var decoded Variant
+			err = json.Unmarshal(encoded, &decoded)
+			if err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+			if reflect.TypeOf(decoded.Data) != reflect.TypeOf(test.value) || !sameValue(decoded, Variant{test.value}) {
+				t.Fatalf("decoding gave %#v, not %#v", decoded.Data, test.value)
+			}
+
+			again, err := json.Marshal(decoded)
+			if err != nil || !bytes.Equal(again, encoded) {
+				t.Fatalf("encoding the decoded value gave %s, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestVariantJSONErrors(t *testing.T) {
+	for _, raw := range []string{
+		`{"Ident":7,"Data":"!!!","Base64":true}`,
+		`{"Ident":7,"Data":"gA=A","Base64":true}`,
+		`{"Ident":7,"Data":5}`,
+		`{"Ident":7,"Data":"tail","Unterminated":"yes"}`,
+		`{"Ident":8,"Data":1}`,
+		`{"Ident":0,"Data":200}`,
+		`{"Ident":3,"Data":-1}`,
+		`{"Ident":6,"Data":"0xZZ"}`,
+		`{"Ident":1}`,
+		`[7,"coin"]`,
+	} {
+		var variant Variant
+		if err := json.Unmarshal([]byte(raw), &variant); err == nil {
+			t.Fatalf("decoding %s has to fail, it gave %#v", raw, variant.Data)
+		}
+	}
+}
+
+func TestStringsRoundTrip(t *testing.T) {
+	file := readLevel(t)
+	file.Header.Note = Variant{Str("a \"quoted\" note")}
+	file.Nodes[0].DependentData[3] = Variant{Str("\xFF\xFEcoin")}
+	file.Nodes[1].DependentData = append(file.Nodes[1].DependentData, Variant{UnterminatedStr("end \xC0")})
+
+	want, err := file.Write()
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	if !bytes.HasSuffix(want, []byte("\x07end \xC0")) {
+		t.Fatalf("the unterminated str isn't written without its terminator, the level ends in % X", want[len(want)-8:])
+	}
+
+	read, err := Read(want)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	last := read.Nodes[1].DependentData[len(read.Nodes[1].DependentData)-1]
+	if last.Data != UnterminatedStr("end \xC0") {
+		t.Fatalf("the last value is read as %#v", last.Data)
+	}
+
+	for _, isRaw := range []bool{true, false} {
+		encoded, err := read.EncodeToJSON(isRaw)
+		if err != nil {
+			t.Fatalf("encoding to json, raw %v: %v", isRaw, err)
+		}
+		decoded, err := DecodeFromJSON(encoded, isRaw)
+		if err != nil {
+			t.Fatalf("decoding from json, raw %v: %v", isRaw, err)
+		}
+		got, err := decoded.Write()
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("the json, raw %v, wrote %v\n% X\nnot\n% X", isRaw, err, got, want)
+		}
+	}
+
+	//Only the last value can go without a terminator, anywhere else it would swallow the values after it.
+	file.Nodes[0].DependentData[3] = Variant{UnterminatedStr("coin")}
+	if _, err := file.Write(); err == nil {
+		t.Fatalf("writing an unterminated str before the last value has to fail")
+	}
+	file.Nodes[0].DependentData[3] = Variant{Str("co\x00in")}
+	if _, err := file.Write(); err == nil {
+		t.Fatalf("writing a str holding a 0 byte has to fail")
+	}
+}
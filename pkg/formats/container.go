@@ -0,0 +1,37 @@
+package formats
+
+/*
+Member ...
+One file inside a container, Name includes its extension like "LEVEL.TPL"
+*/
+type Member struct {
+	Name string
+	Data []byte
+}
+
+/*
+Container ...
+Implemented by formats that hold several files, like JAM archives.
+Unpack returns every member in the order the container lists them.
+*/
+type Container interface {
+	Unpack(data []byte) ([]Member, error)
+}
+
+/*
+Packer ...
+Implemented by containers that can be built from nothing but their members
+*/
+type Packer interface {
+	Pack(members []Member) ([]byte, error)
+}
+
+/*
+Repacker ...
+Implemented by containers that can give an original file new member data while keeping
+everything else of it, like its header fields and the order of its members. members are
+in the order Unpack returns them, one for each.
+*/
+type Repacker interface {
+	Repack(original []byte, members []Member) ([]byte, error)
+}
@@ -0,0 +1,128 @@
+package formats
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+Scorer ...
+Implemented by formats whose Probe is only a guess, like KRT which has no magic.
+Score rates how much data looks like the format, from 0 to 1, and replaces the default
+score a matching Probe gets.
+*/
+type Scorer interface {
+	Score(data []byte) float64
+}
+
+/*
+Wrapper ...
+Implemented by formats that only hold another file, like Yaz0 compression.
+Unwrap returns the file inside.
+*/
+type Wrapper interface {
+	Unwrap(data []byte) ([]byte, error)
+}
+
+/*
+Detection ...
+A format data is likely to be, Confidence is from 0 to 1. Inner is the file a wrapper
+format holds, nil when the format isn't a wrapper or its content isn't recognized.
+*/
+type Detection struct {
+	Format     Format
+	Confidence float64
+	Inner      *Detection
+}
+
+/*
+String ...
+Describe the detection like "tpl in yaz0 (0.93)"
+*/
+func (detection Detection) String() string {
+	name := detection.Format.Name()
+	for inner := detection.Inner; inner != nil; inner = inner.Inner {
+		name = inner.Format.Name() + " in " + name
+	}
+	return fmt.Sprintf("%v (%.2f)", name, detection.Confidence)
+}
+
+/*
+Innermost ...
+Return the format that is left after unwrapping every wrapper
+*/
+func (detection Detection) Innermost() Format {
+	for detection.Inner != nil {
+		detection = *detection.Inner
+	}
+	return detection.Format
+}
+
+const (
+	probeScore = 0.6 //What a matching Probe is worth, a successful Read then confirms it.
+	maxWrapped = 4   //How many wrappers deep data is unwrapped.
+)
+
+/*
+Detect ...
+Return the most likely format of data among the registered formats, or false when no format
+matches. Wrappers are unwrapped to detect the format inside them. Only the formats of packages
+that are imported are known, import pkg/formats/all to know every one.
+*/
+func Detect(data []byte) (Detection, bool) {
+	detections := DetectAll(data)
+	if len(detections) == 0 {
+		return Detection{}, false
+	}
+	return detections[0], true
+}
+
+/*
+DetectAll ...
+Return every format data could be, most likely first
+*/
+func DetectAll(data []byte) []Detection {
+	return detectAll(data, 0)
+}
+
+func detectAll(data []byte, depth int) []Detection {
+	var detections []Detection
+
+	for _, format := range Formats() {
+		confidence := 0.0
+		if scorer, ok := format.(Scorer); ok {
+			confidence = scorer.Score(data)
+		} else if format.Probe(data) {
+			confidence = probeScore
+		}
+		if confidence <= 0 {
+			continue
+		}
+
+		//The magic checks of Read go further than Probe, a file it reads is much more likely to be right.
+		if _, err := format.Read(data); err == nil {
+			confidence += (1 - confidence) * 0.75
+		} else {
+			confidence *= 0.25
+		}
+
+		detection := Detection{Format: format, Confidence: confidence}
+
+		if wrapper, ok := format.(Wrapper); ok && depth < maxWrapped {
+			inner, err := wrapper.Unwrap(data)
+			if err != nil {
+				detection.Confidence *= 0.5
+			} else if innerDetections := detectAll(inner, depth+1); len(innerDetections) > 0 {
+				detection.Inner = &innerDetections[0]
+			}
+		}
+
+		detections = append(detections, detection)
+	}
+
+	sort.SliceStable(detections, func(i int, j int) bool {
+		return detections[i].Confidence > detections[j].Confidence
+	})
+
+	return detections
+}
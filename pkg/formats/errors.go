@@ -0,0 +1,65 @@
+package formats
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+The kinds of problems a file can have. Format packages return them wrapped in a FormatError,
+or with details added, so errors.Is finds them: ErrBadMagic, ErrTruncated and ErrInvalid mean
+the file is corrupt or another kind of file, ErrUnsupportedFormat that it is fine but uses
+something the package can't handle yet.
+*/
+var (
+	//ErrBadMagic is returned when data doesn't start with the magic of the format, it is another kind of file.
+	ErrBadMagic = errors.New("bad magic")
+	//ErrTruncated is returned when a part of the file ends past the end of the data.
+	ErrTruncated = errors.New("data is truncated")
+	//ErrInvalid is returned when a value of the file can't be right, like an offset inside a header.
+	ErrInvalid = errors.New("invalid value")
+	//ErrUnsupportedFormat is returned for a valid file that uses a variant, image format or layout the package can't handle yet.
+	ErrUnsupportedFormat = errors.New("unsupported format")
+)
+
+/*
+FormatError ...
+A problem of a file, where it is and what kind it is. Format is the format name, Field the
+part of the file that is wrong, like "image 1 header", and Offset where that part is in the
+data, -1 when it isn't known. Err is one of the errors above, or wraps one with details, so
+
+	var formatErr *formats.FormatError
+	if errors.As(err, &formatErr) { ... }
+	if errors.Is(err, formats.ErrTruncated) { ... }
+
+both work on whatever a format package returns.
+*/
+type FormatError struct {
+	Format string
+	Offset int
+	Field  string
+	Err    error
+}
+
+func (err *FormatError) Error() string {
+	where := err.Format
+	if err.Field != "" {
+		where += " " + err.Field
+	}
+	if err.Offset >= 0 {
+		where += fmt.Sprintf(" at 0x%X", err.Offset)
+	}
+	return where + ": " + err.Err.Error()
+}
+
+func (err *FormatError) Unwrap() error {
+	return err.Err
+}
+
+/*
+Unsupported ...
+Report whether err only means a file can't be handled yet, not that it is corrupt
+*/
+func Unsupported(err error) bool {
+	return errors.Is(err, ErrUnsupportedFormat)
+}
@@ -0,0 +1,29 @@
+package formats
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestUnsupported(t *testing.T) {
+	tests := []struct {
+		err         error
+		unsupported bool
+	}{
+		{ErrNotSupported, true},
+		{fmt.Errorf("%w, image format 0x9", ErrUnsupportedFormat), true},
+		{&FormatError{Format: "test", Offset: 4, Field: "hook", Err: ErrNotSupported}, true},
+		{&FormatError{Format: "test", Offset: 4, Field: "image data", Err: ErrTruncated}, false},
+		{errors.New("anything else"), false},
+	}
+
+	for _, test := range tests {
+		if Unsupported(test.err) != test.unsupported {
+			t.Errorf("Unsupported(%v) is %v", test.err, !test.unsupported)
+		}
+	}
+	if !errors.Is(ErrNotSupported, ErrUnsupportedFormat) {
+		t.Fatalf("ErrNotSupported has to be an ErrUnsupportedFormat")
+	}
+}
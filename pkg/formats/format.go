@@ -0,0 +1,129 @@
+package formats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+ErrNotSupported ...
+Returned by a Format hook the format doesn't implement at all. It wraps ErrUnsupportedFormat,
+so errors.Is finds either and Unsupported reports it, but tools can still leave a missing hook
+unmentioned where a layout that can't be handled yet is worth saying.
+*/
+var ErrNotSupported = fmt.Errorf("%w, not implemented by this format", ErrUnsupportedFormat)
+
+/*
+Format ...
+One file format, following the File and Work convention of _template/template.go. Read parses the bytes
+of a file to the File type of the format and Write turns it back into bytes, Decode turns a
+File into the Work type, the form that is meant to be worked with, and Encode goes back.
+Files and works are passed as interface{} and have the types of the format package, a hook
+that gets another type returns an error. Probe only looks at the magic and should be cheap.
+*/
+type Format interface {
+	Name() string
+	Extensions() []string
+	Probe(data []byte) bool
+	Read(data []byte) (interface{}, error)
+	Write(file interface{}) ([]byte, error)
+	Decode(file interface{}) (interface{}, error)
+	Encode(work interface{}) (interface{}, error)
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]Format)
+)
+
+/*
+Register ...
+Make a format known to the registry, format packages call this from init.
+Registering two formats with the same name panics.
+*/
+func Register(format Format) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	name := strings.ToLower(format.Name())
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("formats: format %v is registered twice", format.Name()))
+	}
+	registry[name] = format
+}
+
+/*
+Formats ...
+Return every registered format, sorted by name
+*/
+func Formats() []Format {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	var all []Format
+	for _, format := range registry {
+		all = append(all, format)
+	}
+	sort.Slice(all, func(i int, j int) bool { return all[i].Name() < all[j].Name() })
+
+	return all
+}
+
+/*
+Lookup ...
+Return the format registered under name, ignoring case
+*/
+func Lookup(name string) (Format, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	format, ok := registry[strings.ToLower(name)]
+	return format, ok
+}
+
+/*
+ByExtension ...
+Return the formats that use an extension, with or without its dot, sorted by name
+*/
+func ByExtension(ext string) []Format {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	var found []Format
+	for _, format := range Formats() {
+		for _, formatExt := range format.Extensions() {
+			if strings.ToLower(formatExt) == ext {
+				found = append(found, format)
+				break
+			}
+		}
+	}
+
+	return found
+}
+
+/*
+Probe ...
+Return every registered format whose magic matches data, sorted by name
+*/
+func Probe(data []byte) []Format {
+	var found []Format
+	for _, format := range Formats() {
+		if format.Probe(data) {
+			found = append(found, format)
+		}
+	}
+	return found
+}
+
+/*
+WrongType ...
+The error a hook returns when it gets a file or work of another type than its format uses
+*/
+func WrongType(format string, want string, got interface{}) error {
+	return fmt.Errorf("%v works on %v, not %T", format, want, got)
+}
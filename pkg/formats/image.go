@@ -0,0 +1,13 @@
+package formats
+
+import "image"
+
+/*
+ImageReplacer ...
+Implemented by texture formats that can encode an image into an existing file, keeping its
+image format, its size and the rest of the file as it was. index is the image in the file,
+for formats that hold a single image it is 0.
+*/
+type ImageReplacer interface {
+	ReplaceImage(data []byte, index int, img image.Image) ([]byte, error)
+}
@@ -0,0 +1,143 @@
+package formats
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+/*
+Inspector ...
+Implemented by formats that can show how a file is stored: its header fields, tables and
+offsets, the unknown ones included, for reverse engineering. file is what Read returned.
+*/
+type Inspector interface {
+	Inspect(file interface{}) (*Inspection, error)
+}
+
+/*
+Hex ...
+A value that is printed in hex, like an offset or a magic, JSON still gets it as a number
+*/
+type Hex uint64
+
+func (value Hex) String() string {
+	return fmt.Sprintf("0x%X", uint64(value))
+}
+
+/*
+Inspection ...
+The structure of a file, in sections like "header" or "images". A section has fields, a
+name and a value each, a table of rows, or both. Values are numbers, Hex, strings or anything
+else that prints with %v and encodes to JSON. It prints as aligned text with WriteText and
+the json tags give it as JSON.
+*/
+type Inspection struct {
+	Format   string     `json:"format"`
+	Sections []*Section `json:"sections"`
+}
+
+/*
+Section ...
+A part of an inspection, see Inspection
+*/
+type Section struct {
+	Name    string          `json:"name"`
+	Fields  []Field         `json:"fields,omitempty"`
+	Columns []string        `json:"columns,omitempty"`
+	Rows    [][]interface{} `json:"rows,omitempty"`
+}
+
+/*
+Field ...
+One named value of a section
+*/
+type Field struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+/*
+NewInspection ...
+Start the inspection of a file of format
+*/
+func NewInspection(format string) *Inspection {
+	return &Inspection{Format: format}
+}
+
+/*
+Section ...
+Add a section to the end of the inspection and return it
+*/
+func (inspection *Inspection) Section(name string) *Section {
+	section := &Section{Name: name}
+	inspection.Sections = append(inspection.Sections, section)
+	return section
+}
+
+/*
+Table ...
+Add a section with a table of columns to the end of the inspection and return it
+*/
+func (inspection *Inspection) Table(name string, columns ...string) *Section {
+	section := inspection.Section(name)
+	section.Columns = columns
+	return section
+}
+
+/*
+Field ...
+Add a field to the section and return the section, so fields can be chained
+*/
+func (section *Section) Field(name string, value interface{}) *Section {
+	section.Fields = append(section.Fields, Field{Name: name, Value: value})
+	return section
+}
+
+/*
+Row ...
+Add a row of values to the table of the section, one for each column
+*/
+func (section *Section) Row(values ...interface{}) {
+	section.Rows = append(section.Rows, values)
+}
+
+/*
+WriteText ...
+Write the inspection to w as text, every line starting with indent. Each section is its name
+and then its fields and table, indented once more and aligned in columns.
+*/
+func (inspection *Inspection) WriteText(w io.Writer, indent string) error {
+	for _, section := range inspection.Sections {
+		_, err := fmt.Fprintf(w, "%v%v\n", indent, section.Name)
+		if err != nil {
+			return err
+		}
+
+		table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, field := range section.Fields {
+			fmt.Fprintf(table, "%v  %v\t%v\n", indent, field.Name, field.Value)
+		}
+		if len(section.Fields) > 0 && len(section.Columns) > 0 {
+			fmt.Fprintf(table, "\n")
+		}
+		if len(section.Columns) > 0 {
+			fmt.Fprintf(table, "%v  %v\n", indent, strings.Join(section.Columns, "\t"))
+		}
+		for _, row := range section.Rows {
+			cells := make([]string, len(row))
+			for idx, value := range row {
+				cells[idx] = fmt.Sprint(value)
+			}
+			fmt.Fprintf(table, "%v  %v\n", indent, strings.Join(cells, "\t"))
+		}
+
+		err = table.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
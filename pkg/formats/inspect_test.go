@@ -0,0 +1,35 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestInspection(t *testing.T) {
+	inspection := NewInspection("test")
+	inspection.Section("header").Field("magic", Hex(0xBEEF)).Field("count", 2)
+	table := inspection.Table("entries", "index", "offset")
+	table.Row(0, Hex(0x10))
+	table.Row(1, Hex(0x100))
+
+	var text bytes.Buffer
+	err := inspection.WriteText(&text, "")
+	if err != nil {
+		t.Fatalf("writing text: %v", err)
+	}
+	want := "header\n  magic  0xBEEF\n  count  2\nentries\n  index  offset\n  0      0x10\n  1      0x100\n"
+	if text.String() != want {
+		t.Fatalf("wrote\n%v\nit has to be\n%v", text.String(), want)
+	}
+
+	raw, err := json.Marshal(inspection)
+	if err != nil {
+		t.Fatalf("encoding JSON: %v", err)
+	}
+	want = `{"format":"test","sections":[{"name":"header","fields":[{"name":"magic","value":48879},{"name":"count","value":2}]},` +
+		`{"name":"entries","columns":["index","offset"],"rows":[[0,16],[1,256]]}]}`
+	if string(raw) != want {
+		t.Fatalf("encoded %s, it has to be %v", raw, want)
+	}
+}
@@ -0,0 +1,382 @@
+package gltf
+
+/*
+Name: glTF 2.0
+Extension: .gltf + .bin | .glb
+Description: Khronos' transmission format for 3D scenes. Only writing is supported, it is used to
+             export models from other formats so they can be opened in common 3D tools.
+*/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+const (
+	ComponentUnsignedShort = 5123
+	ComponentUnsignedInt   = 5125
+	ComponentFloat         = 5126
+
+	TargetArrayBuffer        = 34962
+	TargetElementArrayBuffer = 34963
+
+	ModeTriangles = 4
+
+	PathTranslation = "translation"
+	PathRotation    = "rotation"
+	PathScale       = "scale"
+
+	InterpolationLinear      = "LINEAR"
+	InterpolationStep        = "STEP"
+	InterpolationCubicSpline = "CUBICSPLINE"
+)
+
+type Asset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator,omitempty"`
+}
+
+type Scene struct {
+	Name  string `json:"name,omitempty"`
+	Nodes []int  `json:"nodes,omitempty"`
+}
+
+type Node struct {
+	Name        string    `json:"name,omitempty"`
+	Mesh        *int      `json:"mesh,omitempty"`
+	Skin        *int      `json:"skin,omitempty"`
+	Children    []int     `json:"children,omitempty"`
+	Translation []float32 `json:"translation,omitempty"`
+	Rotation    []float32 `json:"rotation,omitempty"`
+	Scale       []float32 `json:"scale,omitempty"`
+	Matrix      []float32 `json:"matrix,omitempty"`
+}
+
+type Primitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices,omitempty"`
+	Material   *int           `json:"material,omitempty"`
+	Mode       int            `json:"mode"`
+}
+
+type Mesh struct {
+	Name       string      `json:"name,omitempty"`
+	Primitives []Primitive `json:"primitives"`
+}
+
+type TextureInfo struct {
+	Index int `json:"index"`
+}
+
+type PBRMetallicRoughness struct {
+	BaseColorFactor  []float32    `json:"baseColorFactor,omitempty"`
+	BaseColorTexture *TextureInfo `json:"baseColorTexture,omitempty"`
+	MetallicFactor   float32      `json:"metallicFactor"`
+	RoughnessFactor  float32      `json:"roughnessFactor"`
+}
+
+type Material struct {
+	Name                 string                `json:"name,omitempty"`
+	PBRMetallicRoughness *PBRMetallicRoughness `json:"pbrMetallicRoughness,omitempty"`
+	AlphaMode            string                `json:"alphaMode,omitempty"`
+	DoubleSided          bool                  `json:"doubleSided,omitempty"`
+}
+
+type Texture struct {
+	Sampler *int `json:"sampler,omitempty"`
+	Source  int  `json:"source"`
+}
+
+type Image struct {
+	Name       string `json:"name,omitempty"`
+	BufferView *int   `json:"bufferView,omitempty"`
+	MimeType   string `json:"mimeType,omitempty"`
+	URI        string `json:"uri,omitempty"`
+}
+
+type Sampler struct {
+	MagFilter int `json:"magFilter,omitempty"`
+	MinFilter int `json:"minFilter,omitempty"`
+	WrapS     int `json:"wrapS,omitempty"`
+	WrapT     int `json:"wrapT,omitempty"`
+}
+
+type Accessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float32 `json:"min,omitempty"`
+	Max           []float32 `json:"max,omitempty"`
+}
+
+type Skin struct {
+	Name                string `json:"name,omitempty"`
+	InverseBindMatrices *int   `json:"inverseBindMatrices,omitempty"`
+	Skeleton            *int   `json:"skeleton,omitempty"`
+	Joints              []int  `json:"joints"`
+}
+
+type AnimationTarget struct {
+	Node *int   `json:"node,omitempty"`
+	Path string `json:"path"`
+}
+
+type AnimationChannel struct {
+	Sampler int             `json:"sampler"`
+	Target  AnimationTarget `json:"target"`
+}
+
+type AnimationSampler struct {
+	Input         int    `json:"input"`
+	Interpolation string `json:"interpolation,omitempty"`
+	Output        int    `json:"output"`
+}
+
+type Animation struct {
+	Name     string             `json:"name,omitempty"`
+	Channels []AnimationChannel `json:"channels"`
+	Samplers []AnimationSampler `json:"samplers"`
+}
+
+type BufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target,omitempty"`
+}
+
+type Buffer struct {
+	ByteLength int    `json:"byteLength"`
+	URI        string `json:"uri,omitempty"`
+}
+
+/*
+Document ...
+A glTF document together with the binary buffer its accessors and images point into.
+Everything is stored in one buffer, which becomes the .bin file or the BIN chunk of a .glb.
+*/
+type Document struct {
+	Asset       Asset        `json:"asset"`
+	Scene       *int         `json:"scene,omitempty"`
+	Scenes      []Scene      `json:"scenes,omitempty"`
+	Nodes       []Node       `json:"nodes,omitempty"`
+	Meshes      []Mesh       `json:"meshes,omitempty"`
+	Materials   []Material   `json:"materials,omitempty"`
+	Textures    []Texture    `json:"textures,omitempty"`
+	Images      []Image      `json:"images,omitempty"`
+	Samplers    []Sampler    `json:"samplers,omitempty"`
+	Skins       []Skin       `json:"skins,omitempty"`
+	Animations  []Animation  `json:"animations,omitempty"`
+	Accessors   []Accessor   `json:"accessors,omitempty"`
+	BufferViews []BufferView `json:"bufferViews,omitempty"`
+	Buffers     []Buffer     `json:"buffers,omitempty"`
+
+	bin bytes.Buffer
+}
+
+/*
+NewDocument ...
+Create an empty glTF 2.0 document
+*/
+func NewDocument() *Document {
+	return &Document{Asset: Asset{Version: "2.0", Generator: "go-files"}}
+}
+
+/*
+AddBufferView ...
+Append data to the binary buffer and return the index of the buffer view pointing at it
+*/
+func (doc *Document) AddBufferView(data []byte, target int) int {
+	//Accessors need their data aligned to the size of their components, 4 covers every type used here.
+	for doc.bin.Len()%4 != 0 {
+		doc.bin.WriteByte(0x00)
+	}
+
+	doc.BufferViews = append(doc.BufferViews, BufferView{
+		Buffer:     0,
+		ByteOffset: doc.bin.Len(),
+		ByteLength: len(data),
+		Target:     target,
+	})
+	doc.bin.Write(data)
+
+	return len(doc.BufferViews) - 1
+}
+
+/*
+AddFloatAccessor ...
+Store values as float32 vectors with the given number of components and return the accessor index
+*/
+func (doc *Document) AddFloatAccessor(values []float32, components int, target int) (int, error) {
+	accessorType, ok := map[int]string{1: "SCALAR", 2: "VEC2", 3: "VEC3", 4: "VEC4", 16: "MAT4"}[components]
+	if !ok {
+		return 0, fmt.Errorf("accessors can't have %v components", components)
+	}
+	if len(values)%components != 0 {
+		return 0, fmt.Errorf("%v values can't be split into vectors of %v components", len(values), components)
+	}
+
+	data := make([]byte, len(values)*4)
+	min := make([]float32, components)
+	max := make([]float32, components)
+	for i, value := range values {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(value))
+
+		component := i % components
+		if i < components || value < min[component] {
+			min[component] = value
+		}
+		if i < components || value > max[component] {
+			max[component] = value
+		}
+	}
+
+	accessor := Accessor{
+		BufferView:    doc.AddBufferView(data, target),
+		ComponentType: ComponentFloat,
+		Count:         len(values) / components,
+		Type:          accessorType,
+	}
+	if len(values) > 0 {
+		accessor.Min = min
+		accessor.Max = max
+	}
+	doc.Accessors = append(doc.Accessors, accessor)
+
+	return len(doc.Accessors) - 1, nil
+}
+
+/*
+AddIndexAccessor ...
+Store triangle indices and return the accessor index
+*/
+func (doc *Document) AddIndexAccessor(indices []uint32) int {
+	data := make([]byte, len(indices)*4)
+	for i, index := range indices {
+		binary.LittleEndian.PutUint32(data[i*4:], index)
+	}
+
+	doc.Accessors = append(doc.Accessors, Accessor{
+		BufferView:    doc.AddBufferView(data, TargetElementArrayBuffer),
+		ComponentType: ComponentUnsignedInt,
+		Count:         len(indices),
+		Type:          "SCALAR",
+	})
+
+	return len(doc.Accessors) - 1
+}
+
+/*
+AddJointAccessor ...
+Store the four joint indices of every vertex as unsigned shorts and return the accessor index
+*/
+func (doc *Document) AddJointAccessor(joints [][4]uint16) int {
+	data := make([]byte, len(joints)*8)
+	for i, joint := range joints {
+		for j := 0; j < 4; j++ {
+			binary.LittleEndian.PutUint16(data[i*8+j*2:], joint[j])
+		}
+	}
+
+	doc.Accessors = append(doc.Accessors, Accessor{
+		BufferView:    doc.AddBufferView(data, TargetArrayBuffer),
+		ComponentType: ComponentUnsignedShort,
+		Count:         len(joints),
+		Type:          "VEC4",
+	})
+
+	return len(doc.Accessors) - 1
+}
+
+/*
+FindNode ...
+Return the index of the first node with the given name
+*/
+func (doc *Document) FindNode(name string) (int, bool) {
+	for i, node := range doc.Nodes {
+		if node.Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+/*
+AddImage ...
+Embed an encoded png image in the binary buffer and return the image index
+*/
+func (doc *Document) AddImage(name string, png []byte) int {
+	view := doc.AddBufferView(png, 0)
+	doc.Images = append(doc.Images, Image{Name: name, BufferView: &view, MimeType: "image/png"})
+
+	return len(doc.Images) - 1
+}
+
+/*
+WriteGLTF ...
+Write the document as .gltf json and the matching .bin data, binName is the uri the json refers to
+*/
+func (doc *Document) WriteGLTF(binName string) ([]byte, []byte, error) {
+	doc.Buffers = nil
+	if doc.bin.Len() > 0 {
+		doc.Buffers = []Buffer{{ByteLength: doc.bin.Len(), URI: binName}}
+	}
+
+	raw, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("encoding the gltf json failed, %w", err)
+	}
+
+	return raw, doc.bin.Bytes(), nil
+}
+
+/*
+WriteGLB ...
+Write the document and its binary buffer as a single .glb file
+*/
+func (doc *Document) WriteGLB() ([]byte, error) {
+	doc.Buffers = nil
+	if doc.bin.Len() > 0 {
+		doc.Buffers = []Buffer{{ByteLength: doc.bin.Len()}}
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("encoding the json chunk of the glb failed, %w", err)
+	}
+
+	//Both chunks have to be 4 byte aligned, json is padded with spaces and the binary data with zeroes.
+	for len(raw)%4 != 0 {
+		raw = append(raw, ' ')
+	}
+	bin := append([]byte{}, doc.bin.Bytes()...)
+	for len(bin)%4 != 0 {
+		bin = append(bin, 0x00)
+	}
+
+	size := 12 + 8 + len(raw)
+	if len(bin) > 0 {
+		size += 8 + len(bin)
+	}
+
+	buffer := &bytes.Buffer{}
+	buffer.WriteString("glTF")
+	binary.Write(buffer, binary.LittleEndian, uint32(2))
+	binary.Write(buffer, binary.LittleEndian, uint32(size))
+
+	binary.Write(buffer, binary.LittleEndian, uint32(len(raw)))
+	buffer.WriteString("JSON")
+	buffer.Write(raw)
+
+	if len(bin) > 0 {
+		binary.Write(buffer, binary.LittleEndian, uint32(len(bin)))
+		buffer.WriteString("BIN\x00")
+		buffer.Write(bin)
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,141 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+/*
+testDocument ...
+A triangle with indices and an embedded image, the binary buffer holds 36 bytes of positions,
+12 of indices and the 5 image bytes
+*/
+func testDocument(t *testing.T) *Document {
+	t.Helper()
+
+	doc := NewDocument()
+	positions, err := doc.AddFloatAccessor([]float32{0, 0, 0, 1, 0, -1, 0, 2, 0}, 3, TargetArrayBuffer)
+	if err != nil {
+		t.Fatalf("adding positions: %v", err)
+	}
+	indices := doc.AddIndexAccessor([]uint32{0, 1, 2})
+	doc.Textures = []Texture{{Source: doc.AddImage("tex", []byte("image"))}}
+
+	doc.Meshes = []Mesh{{Name: "triangle", Primitives: []Primitive{{Attributes: map[string]int{"POSITION": positions}, Indices: &indices, Mode: ModeTriangles}}}}
+	mesh := 0
+	doc.Nodes = []Node{{Name: "triangle", Mesh: &mesh}}
+	doc.Scenes = []Scene{{Nodes: []int{0}}}
+
+	return doc
+}
+
+func TestAccessors(t *testing.T) {
+	doc := testDocument(t)
+
+	want := []Accessor{
+		{BufferView: 0, ComponentType: ComponentFloat, Count: 3, Type: "VEC3", Min: []float32{0, 0, -1}, Max: []float32{1, 2, 0}},
+		{BufferView: 1, ComponentType: ComponentUnsignedInt, Count: 3, Type: "SCALAR"},
+	}
+	if !reflect.DeepEqual(doc.Accessors, want) {
+		t.Fatalf("the accessors are %+v, they have to be %+v", doc.Accessors, want)
+	}
+	wantViews := []BufferView{
+		{ByteOffset: 0, ByteLength: 36, Target: TargetArrayBuffer},
+		{ByteOffset: 36, ByteLength: 12, Target: TargetElementArrayBuffer},
+		{ByteOffset: 48, ByteLength: 5},
+	}
+	if !reflect.DeepEqual(doc.BufferViews, wantViews) {
+		t.Fatalf("the buffer views are %+v, they have to be %+v", doc.BufferViews, wantViews)
+	}
+
+	//The next view starts aligned to 4 bytes after the image.
+	joints := doc.AddJointAccessor([][4]uint16{{1, 2, 3, 4}})
+	if doc.BufferViews[doc.Accessors[joints].BufferView].ByteOffset != 56 || doc.Accessors[joints].ComponentType != ComponentUnsignedShort {
+		t.Fatalf("the joints are %+v in %+v", doc.Accessors[joints], doc.BufferViews)
+	}
+
+	if _, err := doc.AddFloatAccessor([]float32{1, 2, 3}, 5, 0); err == nil {
+		t.Fatalf("accessors of 5 components have to fail")
+	}
+	if _, err := doc.AddFloatAccessor([]float32{1, 2, 3, 4}, 3, 0); err == nil {
+		t.Fatalf("4 values split into vectors of 3 have to fail")
+	}
+}
+
+func TestWriteGLTF(t *testing.T) {
+	doc := testDocument(t)
+
+	raw, bin, err := doc.WriteGLTF("model.bin")
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	if len(bin) != 53 || math.Float32frombits(binary.LittleEndian.Uint32(bin[12:])) != 1 || binary.LittleEndian.Uint32(bin[44:]) != 2 || string(bin[48:]) != "image" {
+		t.Fatalf("the binary buffer is % X", bin)
+	}
+
+	var written Document
+	err = json.Unmarshal(raw, &written)
+	if err != nil {
+		t.Fatalf("the gltf json doesn't parse: %v", err)
+	}
+	if written.Asset.Version != "2.0" || !reflect.DeepEqual(written.Buffers, []Buffer{{ByteLength: 53, URI: "model.bin"}}) {
+		t.Fatalf("the json has asset %+v and buffers %+v", written.Asset, written.Buffers)
+	}
+	if !reflect.DeepEqual(written.Accessors, doc.Accessors) || !reflect.DeepEqual(written.Meshes, doc.Meshes) || *written.Images[0].BufferView != 2 {
+		t.Fatalf("the json doesn't hold the document, it holds %s", raw)
+	}
+}
+
+func TestWriteGLB(t *testing.T) {
+	doc := testDocument(t)
+	_, bin, err := doc.WriteGLTF("model.bin")
+	if err != nil {
+		t.Fatalf("writing the gltf: %v", err)
+	}
+
+	glb, err := doc.WriteGLB()
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	if string(glb[0:4]) != "glTF" || binary.LittleEndian.Uint32(glb[4:]) != 2 || binary.LittleEndian.Uint32(glb[8:]) != uint32(len(glb)) {
+		t.Fatalf("the glb header is % X, the file is 0x%X bytes", glb[:12], len(glb))
+	}
+
+	jsonLength := int(binary.LittleEndian.Uint32(glb[12:]))
+	if string(glb[16:20]) != "JSON" || jsonLength%4 != 0 {
+		t.Fatalf("the json chunk is %q of 0x%X bytes", glb[16:20], jsonLength)
+	}
+	var written Document
+	err = json.Unmarshal(glb[20:20+jsonLength], &written)
+	if err != nil {
+		t.Fatalf("the json chunk doesn't parse: %v", err)
+	}
+	//A glb buffer has no uri, it is the BIN chunk.
+	if !reflect.DeepEqual(written.Buffers, []Buffer{{ByteLength: 53}}) {
+		t.Fatalf("the json chunk has buffers %+v", written.Buffers)
+	}
+
+	chunk := glb[20+jsonLength:]
+	binLength := int(binary.LittleEndian.Uint32(chunk))
+	if string(chunk[4:8]) != "BIN\x00" || binLength != 56 || len(chunk) != 8+binLength {
+		t.Fatalf("the binary chunk is %q of 0x%X bytes, 0x%X are left", chunk[4:8], binLength, len(chunk))
+	}
+	if !bytes.Equal(chunk[8:8+len(bin)], bin) || !bytes.Equal(chunk[8+len(bin):], []byte{0, 0, 0}) {
+		t.Fatalf("the binary chunk is % X, it has to be the buffer % X padded with zeroes", chunk[8:], bin)
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	glb, err := NewDocument().WriteGLB()
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	//Without binary data there is no BIN chunk.
+	if binary.LittleEndian.Uint32(glb[8:]) != uint32(len(glb)) || len(glb) != 20+int(binary.LittleEndian.Uint32(glb[12:])) {
+		t.Fatalf("the empty glb is %q", glb)
+	}
+}
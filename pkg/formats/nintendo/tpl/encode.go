@@ -0,0 +1,290 @@
+package tpl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+ReplaceImage ...
+Encode img into image index of a TPL file in the image format it already has, or error. The image
+has to be the size of the one it replaces, so the layout of the file stays the same, and the
+mipmaps the image has are made again from it. Palette formats keep the palette of the image and
+every pixel gets the index of the nearest color in it.
+*/
+func ReplaceImage(data *File, index int, img image.Image) error {
+	if index < 0 || index >= len(data.ImgTable) {
+		return fmt.Errorf("there is no image %v, the file has %v", index, len(data.ImgTable))
+	}
+	header := data.ImgTable[index].ImgHeader
+
+	width, height := int(header.Width), int(header.Height)
+	if img.Bounds().Dx() != width || img.Bounds().Dy() != height {
+		return fmt.Errorf("image %v is %vx%v, a %vx%v image can't replace it", index, width, height, img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	var palette []color.NRGBA
+	switch ImgFormat(header.Format) {
+	case C4, C8, C14X2:
+		img := data.ImgTable[index]
+		palette = decodePalette(PalFormat(img.palHeader.PalFormat), img.palData)
+		if len(palette) == 0 {
+			return fmt.Errorf("%w, image %v has no palette to encode it with", formats.ErrInvalid, index)
+		}
+	}
+
+	level := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(level, level.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	var encoded []byte
+	for lod := 0; lod <= int(header.MaxLOD); lod++ {
+		levelData, err := encodeLevel(ImgFormat(header.Format), level, palette)
+		if err != nil {
+			return err
+		}
+		encoded = append(encoded, levelData...)
+
+		if level.Rect.Dx() == 1 && level.Rect.Dy() == 1 {
+			break
+		}
+		level = halve(level)
+	}
+
+	if len(encoded) != len(data.ImgTable[index].ImgData) {
+		return fmt.Errorf("image %v encodes to %x bytes, the file has room for %x", index, len(encoded), len(data.ImgTable[index].ImgData))
+	}
+	data.ImgTable[index].ImgData = encoded
+
+	return nil
+}
+
+/*
+encodeLevel ...
+Encode one level of an image, palette is the palette of the image for the palette formats
+*/
+func encodeLevel(format ImgFormat, img *image.NRGBA, palette []color.NRGBA) ([]byte, error) {
+	info, ok := blockInfos[format]
+	if !ok {
+		return nil, fmt.Errorf("%w, image format 0x%X", formats.ErrUnsupportedFormat, uint32(format))
+	}
+	//An index can only reach as many colors as its bits can count.
+	switch {
+	case format == C4 && len(palette) > 0x10:
+		palette = palette[:0x10]
+	case format == C8 && len(palette) > 0x100:
+		palette = palette[:0x100]
+	case format == C14X2 && len(palette) > 0x4000:
+		palette = palette[:0x4000]
+	}
+
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	out := make([]byte, levelSize(format, width, height))
+
+	if format == CMPR {
+		encodeCMPR(out, img)
+		return out, nil
+	}
+
+	blockBytes := info.blockWidth * info.blockHeight * info.bitsPerPixel / 8
+	dataIndex := 0
+
+	for blockY := 0; blockY < height; blockY += info.blockHeight {
+		for blockX := 0; blockX < width; blockX += info.blockWidth {
+			block := out[dataIndex : dataIndex+blockBytes]
+			dataIndex += blockBytes
+
+			for y := 0; y < info.blockHeight; y++ {
+				for x := 0; x < info.blockWidth; x++ {
+					pixel := y*info.blockWidth + x
+					//Pixels past the edge of the image pad the block and stay zero.
+					if blockX+x >= width || blockY+y >= height {
+						continue
+					}
+
+					c := img.NRGBAAt(blockX+x, blockY+y)
+					switch format {
+					case I4:
+						i := to4(intensity(c))
+						if pixel%2 == 0 {
+							block[pixel/2] |= i << 4
+						} else {
+							block[pixel/2] |= i
+						}
+					case I8:
+						block[pixel] = intensity(c)
+					case IA4:
+						block[pixel] = to4(c.A)<<4 | to4(intensity(c))
+					case IA8:
+						block[pixel*2] = c.A
+						block[pixel*2+1] = intensity(c)
+					case RGB565:
+						binary.BigEndian.PutUint16(block[pixel*2:], toRGB565(c))
+					case RGB5A3:
+						binary.BigEndian.PutUint16(block[pixel*2:], toRGB5A3(c))
+					case RGBA32:
+						block[pixel*2] = c.A
+						block[pixel*2+1] = c.R
+						block[32+pixel*2] = c.G
+						block[32+pixel*2+1] = c.B
+					case C4:
+						i := uint8(nearest(palette, c))
+						if pixel%2 == 0 {
+							block[pixel/2] |= i << 4
+						} else {
+							block[pixel/2] |= i
+						}
+					case C8:
+						block[pixel] = uint8(nearest(palette, c))
+					case C14X2:
+						binary.BigEndian.PutUint16(block[pixel*2:], uint16(nearest(palette, c)))
+					}
+				}
+			}
+		}
+	}
+
+	return out, nil
+}
+
+/*
+encodeCMPR ...
+A plain DXT1 encoder: every 4x4 block gets its darkest and brightest colors as end points, and
+the three color mode with transparent pixels when any pixel is mostly transparent
+*/
+func encodeCMPR(out []byte, img *image.NRGBA) {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	dataIndex := 0
+
+	for tileY := 0; tileY < height; tileY += 8 {
+		for tileX := 0; tileX < width; tileX += 8 {
+			for sub := 0; sub < 4; sub++ {
+				block := out[dataIndex : dataIndex+8]
+				dataIndex += 8
+				subX, subY := tileX+(sub%2)*4, tileY+(sub/2)*4
+
+				var darkest, brightest color.NRGBA
+				found, transparent := false, false
+				for y := 0; y < 4; y++ {
+					for x := 0; x < 4; x++ {
+						if subX+x >= width || subY+y >= height {
+							continue
+						}
+						c := img.NRGBAAt(subX+x, subY+y)
+						if c.A < 0x80 {
+							transparent = true
+							continue
+						}
+						if !found || intensity(c) < intensity(darkest) {
+							darkest = c
+						}
+						if !found || intensity(c) > intensity(brightest) {
+							brightest = c
+						}
+						found = true
+					}
+				}
+
+				//color0 above color1 picks the four color mode, otherwise three colors and transparent.
+				color0, color1 := toRGB565(brightest), toRGB565(darkest)
+				if transparent == (color0 > color1) {
+					color0, color1 = color1, color0
+				}
+				binary.BigEndian.PutUint16(block[0:2], color0)
+				binary.BigEndian.PutUint16(block[2:4], color1)
+
+				colors := cmprColors(color0, color1)
+				choices := 4
+				if color0 <= color1 {
+					choices = 3
+				}
+
+				for y := 0; y < 4; y++ {
+					for x := 0; x < 4; x++ {
+						if subX+x >= width || subY+y >= height {
+							continue
+						}
+						c := img.NRGBAAt(subX+x, subY+y)
+						index := 3
+						if c.A >= 0x80 || !transparent {
+							index = nearest(colors[:choices], c)
+						}
+						block[4+y] |= uint8(index) << uint(6-x*2)
+					}
+				}
+			}
+		}
+	}
+}
+
+/*
+nearest ...
+Index of the color closest to c, alpha counts like the other channels
+*/
+func nearest(colors []color.NRGBA, c color.NRGBA) int {
+	best, bestDistance := 0, -1
+	for idx, candidate := range colors {
+		dr, dg, db := int(candidate.R)-int(c.R), int(candidate.G)-int(c.G), int(candidate.B)-int(c.B)
+		da := int(candidate.A) - int(c.A)
+		distance := dr*dr + dg*dg + db*db + da*da
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = idx, distance
+		}
+	}
+	return best
+}
+
+/*
+halve ...
+The next mipmap of an image, every pixel the average of a 2x2 square
+*/
+func halve(img *image.NRGBA) *image.NRGBA {
+	width, height := maxInt(img.Rect.Dx()/2, 1), maxInt(img.Rect.Dy()/2, 1)
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var r, g, b, a, count int
+			for dy := 0; dy < 2; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					sx, sy := x*2+dx, y*2+dy
+					if sx >= img.Rect.Dx() || sy >= img.Rect.Dy() {
+						continue
+					}
+					c := img.NRGBAAt(sx, sy)
+					r, g, b, a, count = r+int(c.R), g+int(c.G), b+int(c.B), a+int(c.A), count+1
+				}
+			}
+			out.SetNRGBA(x, y, color.NRGBA{uint8(r / count), uint8(g / count), uint8(b / count), uint8(a / count)})
+		}
+	}
+
+	return out
+}
+
+func intensity(c color.NRGBA) uint8 {
+	return uint8((int(c.R)*299 + int(c.G)*587 + int(c.B)*114) / 1000)
+}
+
+func toRGB565(c color.NRGBA) uint16 {
+	return uint16(to5(c.R))<<11 | uint16(to6(c.G))<<5 | uint16(to5(c.B))
+}
+
+func toRGB5A3(c color.NRGBA) uint16 {
+	//A full alpha is stored without one, with five bits for every color.
+	if to3(c.A) == 7 {
+		return 0x8000 | uint16(to5(c.R))<<10 | uint16(to5(c.G))<<5 | uint16(to5(c.B))
+	}
+	return uint16(to3(c.A))<<12 | uint16(to4(c.R))<<8 | uint16(to4(c.G))<<4 | uint16(to4(c.B))
+}
+
+//The nearest value of fewer bits, the opposite of convert3to8 and the others.
+
+func to3(v uint8) uint8 { return uint8((int(v)*7 + 127) / 255) }
+func to4(v uint8) uint8 { return uint8((int(v) + 8) / 17) }
+func to5(v uint8) uint8 { return uint8((int(v)*31 + 127) / 255) }
+func to6(v uint8) uint8 { return uint8((int(v)*63 + 127) / 255) }
@@ -0,0 +1,70 @@
+package tpl
+
+import (
+	"bytes"
+	"image"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+func init() {
+	formats.Register(format{})
+}
+
+/*
+format ...
+TPL in the formats registry, files are *File and works are *Work.
+It is a formats.ImageReplacer, see ReplaceImage, and a formats.Inspector.
+*/
+type format struct{}
+
+func (format) Name() string         { return "tpl" }
+func (format) Extensions() []string { return []string{".tpl"} }
+
+func (format) Probe(data []byte) bool {
+	return len(data) >= 12 && bytes.Equal(data[:4], []byte{0x00, 0x20, 0xAF, 0x30})
+}
+
+func (format) Read(data []byte) (interface{}, error) {
+	return Read(data)
+}
+
+func (format) Write(file interface{}) ([]byte, error) {
+	tplFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("tpl", "*tpl.File", file)
+	}
+	return Write(tplFile)
+}
+
+func (format) Decode(file interface{}) (interface{}, error) {
+	tplFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("tpl", "*tpl.File", file)
+	}
+	return Decode(tplFile)
+}
+
+func (format) Encode(work interface{}) (interface{}, error) {
+	return nil, formats.ErrNotSupported
+}
+
+func (format) Inspect(file interface{}) (*formats.Inspection, error) {
+	tplFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("tpl", "*tpl.File", file)
+	}
+	return Inspect(tplFile), nil
+}
+
+func (format) ReplaceImage(data []byte, index int, img image.Image) ([]byte, error) {
+	file, err := Read(data)
+	if err != nil {
+		return nil, err
+	}
+	err = ReplaceImage(file, index, img)
+	if err != nil {
+		return nil, err
+	}
+	return Write(file)
+}
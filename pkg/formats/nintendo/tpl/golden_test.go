@@ -0,0 +1,150 @@
+package tpl
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+)
+
+/*
+checkGolden ...
+Read a generated TPL, write it back byte for byte, read that again and decode every image to
+the pixels the file was made of
+*/
+func checkGolden(t *testing.T, texture testgen.Texture) {
+	t.Helper()
+
+	file, err := Read(texture.Data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	if len(file.ImgTable) != len(texture.Images) {
+		t.Fatalf("read %v images, the file has %v", len(file.ImgTable), len(texture.Images))
+	}
+
+	written, err := Write(file)
+	if err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	if !bytes.Equal(written, texture.Data) {
+		t.Fatalf("writing changed the file, 0x%X bytes became 0x%X", len(texture.Data), len(written))
+	}
+	if _, err = Read(written); err != nil {
+		t.Fatalf("reading the written file: %v", err)
+	}
+
+	work, err := Decode(file)
+	if err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+	for idx, want := range texture.Images {
+		comparePixels(t, fmt.Sprintf("image %v", idx), work.Images[idx], want)
+	}
+}
+
+func comparePixels(t *testing.T, name string, got *image.NRGBA, want *image.NRGBA) {
+	t.Helper()
+
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("%v is %v, it has to be %v", name, got.Bounds(), want.Bounds())
+	}
+	for y := 0; y < want.Bounds().Dy(); y++ {
+		for x := 0; x < want.Bounds().Dx(); x++ {
+			if got.NRGBAAt(x, y) != want.NRGBAAt(x, y) {
+				t.Fatalf("%v pixel %v,%v is %v, it has to be %v", name, x, y, got.NRGBAAt(x, y), want.NRGBAAt(x, y))
+			}
+		}
+	}
+}
+
+func TestGoldenFormats(t *testing.T) {
+	//10x6 isn't a whole number of tiles for any format, so the padding of edge tiles is covered too.
+	for _, format := range testgen.TPLFormats {
+		t.Run(fmt.Sprintf("format 0x%X", format), func(t *testing.T) {
+			checkGolden(t, testgen.TPL(testgen.TPLImage{Format: format, Width: 10, Height: 6}))
+		})
+	}
+}
+
+func TestGoldenImages(t *testing.T) {
+	var images []testgen.TPLImage
+	for idx, format := range testgen.TPLFormats {
+		images = append(images, testgen.TPLImage{Format: format, Width: 8 + idx, Height: 16 - idx})
+	}
+	checkGolden(t, testgen.TPL(images...))
+}
+
+func TestInspect(t *testing.T) {
+	texture := testgen.TPL(testgen.TPLImage{Format: testgen.RGB5A3, Width: 8, Height: 4}, testgen.TPLImage{Format: testgen.C8, Width: 8, Height: 4})
+	file, err := Read(texture.Data)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+
+	inspection := Inspect(file)
+	images := inspection.Sections[1]
+	if images.Name != "images" || len(images.Rows) != 2 {
+		t.Fatalf("inspected %+v", images)
+	}
+	if images.Rows[0][2] != "RGB5A3 (0x5)" || images.Rows[0][3] != "8x4" || images.Rows[1][2] != "C8 (0x9)" {
+		t.Fatalf("inspected images %v", images.Rows)
+	}
+	if len(inspection.Sections) != 3 || len(inspection.Sections[2].Rows) != 1 || inspection.Sections[2].Rows[0][0] != 1 {
+		t.Fatalf("only the second image has a palette, inspected %+v", inspection.Sections[2:])
+	}
+}
+
+func TestReplaceImage(t *testing.T) {
+	for _, format := range testgen.TPLFormats {
+		t.Run(fmt.Sprintf("format 0x%X", format), func(t *testing.T) {
+			texture := testgen.TPL(testgen.TPLImage{Format: format, Width: 10, Height: 6})
+			file, err := Read(texture.Data)
+			if err != nil {
+				t.Fatalf("reading: %v", err)
+			}
+
+			//A color that isn't in the palette gets the nearest one that is, here the opaque red.
+			img := image.NewNRGBA(texture.Images[0].Rect)
+			copy(img.Pix, texture.Images[0].Pix)
+			want := img
+			if format == testgen.C8 {
+				want = image.NewNRGBA(img.Rect)
+				copy(want.Pix, img.Pix)
+				img.SetNRGBA(0, 0, color.NRGBA{0xF0, 0x08, 0x08, 0xF8})
+				want.SetNRGBA(0, 0, color.NRGBA{0xFF, 0x00, 0x00, 0xFF})
+			}
+
+			if err := ReplaceImage(file, 0, img); err != nil {
+				t.Fatalf("encoding: %v", err)
+			}
+
+			written, err := Write(file)
+			if err != nil {
+				t.Fatalf("writing: %v", err)
+			}
+			//Everything before the image data, the headers and the palette, stays as it was.
+			start := file.ImgTable[0].ImgHeader.ImgDataADR
+			if len(written) != len(texture.Data) || !bytes.Equal(written[:start], texture.Data[:start]) {
+				t.Fatalf("encoding changed the file around the image data")
+			}
+			if format == testgen.CMPR {
+				//CMPR is lossy, its pixels don't come back the same.
+				return
+			}
+
+			read, err := Read(written)
+			if err != nil {
+				t.Fatalf("reading the written file: %v", err)
+			}
+			work, err := Decode(read)
+			if err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+			comparePixels(t, "the encoded image", work.Images[0], want)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package tpl
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+var imgFormatNames = map[ImgFormat]string{
+	I4:     "I4",
+	I8:     "I8",
+	IA4:    "IA4",
+	IA8:    "IA8",
+	RGB565: "RGB565",
+	RGB5A3: "RGB5A3",
+	RGBA32: "RGBA32",
+	C4:     "C4",
+	C8:     "C8",
+	C14X2:  "C14X2",
+	CMPR:   "CMPR",
+}
+
+var palFormatNames = map[PalFormat]string{
+	PalIA8:    "IA8",
+	PalRGB565: "RGB565",
+	PalRGB5A3: "RGB5A3",
+}
+
+var (
+	wrapNames   = []string{"clamp", "repeat", "mirror"}
+	filterNames = []string{"near", "linear", "near mip near", "linear mip near", "near mip linear", "linear mip linear"}
+)
+
+/*
+Inspect ...
+Show the header of a TPL file, the headers of its images with their format, size, wrap, filter
+and LOD, and the headers of their palettes
+*/
+func Inspect(data *File) *formats.Inspection {
+	inspection := formats.NewInspection("tpl")
+
+	inspection.Section("header").
+		Field("magic", formats.Hex(data.Header.magic)).
+		Field("images", data.Header.ImgNum).
+		Field("offset table", formats.Hex(data.Header.ImgOffsetTableOffset))
+
+	images := inspection.Table("images", "image", "header", "format", "size", "data", "data size", "wrap s", "wrap t", "min filter", "mag filter", "lod bias", "edge lod", "min lod", "max lod", "unpacked")
+	for idx, img := range data.ImgTable {
+		header := img.ImgHeader
+		//LOD bias is a float, bits that aren't a finite one are shown as they are.
+		var lodBias interface{} = formats.Hex(header.LODBias)
+		if bias := math.Float32frombits(header.LODBias); !math.IsNaN(float64(bias)) && !math.IsInf(float64(bias), 0) {
+			lodBias = bias
+		}
+		images.Row(idx, formats.Hex(data.ImgOffsetTable[idx].ImgHeaderOffset),
+			named(imgFormatNames[ImgFormat(header.Format)], header.Format),
+			fmt.Sprintf("%vx%v", header.Width, header.Height),
+			formats.Hex(header.ImgDataADR), formats.Hex(len(img.ImgData)),
+			named(indexName(wrapNames, header.WrapS), header.WrapS), named(indexName(wrapNames, header.WrapT), header.WrapT),
+			named(indexName(filterNames, header.MinFilter), header.MinFilter), named(indexName(filterNames, header.MagFilter), header.MagFilter),
+			lodBias, header.EdgeLODEnable, header.MinLOD, header.MaxLOD, header.Unpacked)
+	}
+
+	var palettes *formats.Section
+	for idx, img := range data.ImgTable {
+		if data.ImgOffsetTable[idx].ImgPalHeaderOffset == 0 {
+			continue
+		}
+		if palettes == nil {
+			palettes = inspection.Table("palettes", "image", "header", "format", "entries", "data", "unpacked")
+		}
+		header := img.palHeader
+		palettes.Row(idx, formats.Hex(data.ImgOffsetTable[idx].ImgPalHeaderOffset),
+			named(palFormatNames[PalFormat(header.PalFormat)], header.PalFormat),
+			header.EntryCount, formats.Hex(header.PalDataADR), header.Unpacked)
+	}
+
+	return inspection
+}
+
+/*
+named ...
+A named value as its name and raw value, or only the raw value when it has no name
+*/
+func named(name string, value uint32) string {
+	if name == "" {
+		return fmt.Sprintf("0x%X", value)
+	}
+	return fmt.Sprintf("%v (0x%X)", name, value)
+}
+
+func indexName(names []string, value uint32) string {
+	if int(value) < len(names) {
+		return names[value]
+	}
+	return ""
+}
@@ -0,0 +1,286 @@
+package tpl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+blockInfo ...
+Every GX texture format stores its pixels in tiles of blockWidth by blockHeight pixels,
+left to right and top to bottom, using bitsPerPixel bits for each pixel.
+*/
+type blockInfo struct {
+	blockWidth   int
+	blockHeight  int
+	bitsPerPixel int
+}
+
+var blockInfos = map[ImgFormat]blockInfo{
+	I4:     {8, 8, 4},
+	I8:     {8, 4, 8},
+	IA4:    {8, 4, 8},
+	IA8:    {4, 4, 16},
+	RGB565: {4, 4, 16},
+	RGB5A3: {4, 4, 16},
+	RGBA32: {4, 4, 32},
+	C4:     {8, 8, 4},
+	C8:     {8, 4, 8},
+	C14X2:  {4, 4, 16},
+	CMPR:   {8, 8, 4},
+}
+
+/*
+levelSize ...
+Size in bytes of one level of a width by height image, both sides get padded to whole blocks
+*/
+func levelSize(format ImgFormat, width int, height int) int {
+	info, ok := blockInfos[format]
+	if !ok {
+		return 0
+	}
+
+	blocksWide := (width + info.blockWidth - 1) / info.blockWidth
+	blocksHigh := (height + info.blockHeight - 1) / info.blockHeight
+
+	return blocksWide * blocksHigh * info.blockWidth * info.blockHeight * info.bitsPerPixel / 8
+}
+
+/*
+dataSize ...
+Size in bytes of the image data, including the mipmaps that follow the first level
+*/
+func (header ImgHeader) dataSize() int {
+	size := 0
+	width, height := int(header.Width), int(header.Height)
+
+	for level := 0; level <= int(header.MaxLOD); level++ {
+		size += levelSize(ImgFormat(header.Format), width, height)
+
+		if width == 1 && height == 1 {
+			break
+		}
+		width, height = maxInt(width/2, 1), maxInt(height/2, 1)
+	}
+
+	return size
+}
+
+func decodeImage(img Img) (*image.NRGBA, error) {
+	format := ImgFormat(img.ImgHeader.Format)
+	info, ok := blockInfos[format]
+	if !ok {
+		return nil, fmt.Errorf("%w, image format 0x%X", formats.ErrUnsupportedFormat, img.ImgHeader.Format)
+	}
+
+	width, height := int(img.ImgHeader.Width), int(img.ImgHeader.Height)
+	if len(img.ImgData) < levelSize(format, width, height) {
+		return nil, fmt.Errorf("%w, a %vx%v image needs 0x%X bytes and there are 0x%X", formats.ErrTruncated, width, height, levelSize(format, width, height), len(img.ImgData))
+	}
+
+	var palette []color.NRGBA
+	if format == C4 || format == C8 || format == C14X2 {
+		palette = decodePalette(PalFormat(img.palHeader.PalFormat), img.palData)
+	}
+
+	rgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	if format == CMPR {
+		decodeCMPR(rgba, img.ImgData)
+		return rgba, nil
+	}
+
+	blockBytes := info.blockWidth * info.blockHeight * info.bitsPerPixel / 8
+	dataIndex := 0
+
+	for blockY := 0; blockY < height; blockY += info.blockHeight {
+		for blockX := 0; blockX < width; blockX += info.blockWidth {
+			block := img.ImgData[dataIndex : dataIndex+blockBytes]
+			dataIndex += blockBytes
+
+			for y := 0; y < info.blockHeight; y++ {
+				for x := 0; x < info.blockWidth; x++ {
+					pixel := y*info.blockWidth + x
+					if blockX+x >= width || blockY+y >= height {
+						continue
+					}
+
+					var c color.NRGBA
+					switch format {
+					case I4:
+						i := convert4to8(nibble(block, pixel))
+						c = color.NRGBA{i, i, i, 0xFF}
+					case I8:
+						i := block[pixel]
+						c = color.NRGBA{i, i, i, 0xFF}
+					case IA4:
+						i := convert4to8(block[pixel] & 0xF)
+						c = color.NRGBA{i, i, i, convert4to8(block[pixel] >> 4)}
+					case IA8:
+						i := block[pixel*2+1]
+						c = color.NRGBA{i, i, i, block[pixel*2]}
+					case RGB565:
+						c = rgb565(binary.BigEndian.Uint16(block[pixel*2:]))
+					case RGB5A3:
+						c = rgb5a3(binary.BigEndian.Uint16(block[pixel*2:]))
+					case RGBA32:
+						//The first 32 bytes of a block hold the AR pairs, the last 32 the GB pairs.
+						c = color.NRGBA{block[pixel*2+1], block[32+pixel*2], block[32+pixel*2+1], block[pixel*2]}
+					case C4:
+						c = paletteColor(palette, int(nibble(block, pixel)))
+					case C8:
+						c = paletteColor(palette, int(block[pixel]))
+					case C14X2:
+						c = paletteColor(palette, int(binary.BigEndian.Uint16(block[pixel*2:])&0x3FFF))
+					}
+
+					rgba.SetNRGBA(blockX+x, blockY+y, c)
+				}
+			}
+		}
+	}
+
+	return rgba, nil
+}
+
+/*
+decodeCMPR ...
+CMPR tiles are 8x8 pixels made of four 4x4 DXT1 blocks, top left, top right, bottom left and bottom right
+*/
+func decodeCMPR(rgba *image.NRGBA, data []byte) {
+	width, height := rgba.Rect.Dx(), rgba.Rect.Dy()
+	dataIndex := 0
+
+	for tileY := 0; tileY < height; tileY += 8 {
+		for tileX := 0; tileX < width; tileX += 8 {
+			for sub := 0; sub < 4; sub++ {
+				block := data[dataIndex : dataIndex+8]
+				dataIndex += 8
+
+				colors := cmprColors(binary.BigEndian.Uint16(block[0:2]), binary.BigEndian.Uint16(block[2:4]))
+				subX, subY := tileX+(sub%2)*4, tileY+(sub/2)*4
+
+				for y := 0; y < 4; y++ {
+					for x := 0; x < 4; x++ {
+						if subX+x >= width || subY+y >= height {
+							continue
+						}
+						index := (block[4+y] >> uint(6-x*2)) & 0x3
+						rgba.SetNRGBA(subX+x, subY+y, colors[index])
+					}
+				}
+			}
+		}
+	}
+}
+
+func cmprColors(color0 uint16, color1 uint16) [4]color.NRGBA {
+	var colors [4]color.NRGBA
+	colors[0] = rgb565(color0)
+	colors[1] = rgb565(color1)
+
+	if color0 > color1 {
+		colors[2] = mixColor(colors[0], colors[1], 2, 1)
+		colors[3] = mixColor(colors[0], colors[1], 1, 2)
+	} else {
+		colors[2] = mixColor(colors[0], colors[1], 1, 1)
+		colors[3] = color.NRGBA{0, 0, 0, 0}
+	}
+
+	return colors
+}
+
+func mixColor(a color.NRGBA, b color.NRGBA, weightA int, weightB int) color.NRGBA {
+	total := weightA + weightB
+	return color.NRGBA{
+		R: uint8((int(a.R)*weightA + int(b.R)*weightB) / total),
+		G: uint8((int(a.G)*weightA + int(b.G)*weightB) / total),
+		B: uint8((int(a.B)*weightA + int(b.B)*weightB) / total),
+		A: 0xFF,
+	}
+}
+
+func decodePalette(format PalFormat, data []byte) []color.NRGBA {
+	palette := make([]color.NRGBA, len(data)/2)
+
+	for i := range palette {
+		pixel := binary.BigEndian.Uint16(data[i*2:])
+		switch format {
+		case PalIA8:
+			palette[i] = color.NRGBA{uint8(pixel), uint8(pixel), uint8(pixel), uint8(pixel >> 8)}
+		case PalRGB565:
+			palette[i] = rgb565(pixel)
+		default:
+			palette[i] = rgb5a3(pixel)
+		}
+	}
+
+	return palette
+}
+
+func paletteColor(palette []color.NRGBA, index int) color.NRGBA {
+	if index >= len(palette) {
+		return color.NRGBA{}
+	}
+	return palette[index]
+}
+
+func nibble(block []byte, pixel int) uint8 {
+	if pixel%2 == 0 {
+		return block[pixel/2] >> 4
+	}
+	return block[pixel/2] & 0xF
+}
+
+func rgb565(pixel uint16) color.NRGBA {
+	return color.NRGBA{
+		R: convert5to8(uint8(pixel >> 11 & 0x1F)),
+		G: convert6to8(uint8(pixel >> 5 & 0x3F)),
+		B: convert5to8(uint8(pixel & 0x1F)),
+		A: 0xFF,
+	}
+}
+
+func rgb5a3(pixel uint16) color.NRGBA {
+	if pixel&0x8000 != 0 {
+		return color.NRGBA{
+			R: convert5to8(uint8(pixel >> 10 & 0x1F)),
+			G: convert5to8(uint8(pixel >> 5 & 0x1F)),
+			B: convert5to8(uint8(pixel & 0x1F)),
+			A: 0xFF,
+		}
+	}
+	return color.NRGBA{
+		R: convert4to8(uint8(pixel >> 8 & 0xF)),
+		G: convert4to8(uint8(pixel >> 4 & 0xF)),
+		B: convert4to8(uint8(pixel & 0xF)),
+		A: convert3to8(uint8(pixel >> 12 & 0x7)),
+	}
+}
+
+func convert3to8(v uint8) uint8 {
+	return (v << 5) | (v << 2) | (v >> 1)
+}
+
+func convert4to8(v uint8) uint8 {
+	return (v << 4) | v
+}
+
+func convert5to8(v uint8) uint8 {
+	return (v << 3) | (v >> 2)
+}
+
+func convert6to8(v uint8) uint8 {
+	return (v << 2) | (v >> 4)
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -10,6 +10,10 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"image"
+
+	"github.com/ProfElements/go-files/internal/binstruct"
+	"github.com/ProfElements/go-files/pkg/formats"
 )
 
 type ImgFormat uint32
@@ -28,6 +32,19 @@ const (
 	CMPR   ImgFormat = 0x0E
 )
 
+type PalFormat uint32
+
+const (
+	PalIA8    PalFormat = 0x00
+	PalRGB565 PalFormat = 0x01
+	PalRGB5A3 PalFormat = 0x02
+)
+
+const (
+	imgHeaderSize = 0x24
+	palHeaderSize = 0x0C
+)
+
 type ImgHeader struct {
 	Height        uint16
 	Width         uint16
@@ -44,11 +61,11 @@ type ImgHeader struct {
 	Unpacked      uint8
 }
 type PaletteHeader struct {
-	entryCount uint16
-	unpacked   uint8
-	padding    uint8
+	EntryCount uint16
+	Unpacked   uint8
+	_          uint8
 	PalFormat  uint32
-	palDataADR uint32
+	PalDataADR uint32
 }
 type Img struct {
 	palHeader PaletteHeader
@@ -57,8 +74,8 @@ type Img struct {
 	ImgData   []byte
 }
 type ImgOffset struct {
-	imgHeaderOffset    uint32
-	imgPalHeaderOffset uint32
+	ImgHeaderOffset    uint32
+	ImgPalHeaderOffset uint32
 }
 type Header struct {
 	magic                uint32
@@ -72,33 +89,37 @@ type File struct {
 	Data           []byte
 }
 
-type Work struct{}
+type Work struct {
+	Images []*image.NRGBA
+}
 
 func Read(data []byte) (*File, error) {
 
 	tpl := &File{}
 	if len(data) < 12 {
-		return nil, fmt.Errorf("Data size is too small to be a .tpl file")
+		return nil, formatError(0, "header", fmt.Errorf("%w, it is 0xC bytes and there are 0x%X", formats.ErrTruncated, len(data)))
 	}
 
 	if !bytes.Equal(data[:4], []byte{0x00, 0x20, 0xAF, 0x30}) {
-		return nil, fmt.Errorf("Wrong file magic, It isn't `0x00,0x20,0xAF,0x30`")
+		return nil, formatError(0, "magic", fmt.Errorf("%w, % X isn't 00 20 AF 30", formats.ErrBadMagic, data[:4]))
 	}
 
 	tpl.Header.magic = binary.BigEndian.Uint32(data[:4])
 	tpl.Header.ImgNum = binary.BigEndian.Uint32(data[4:8])
 	tpl.Header.ImgOffsetTableOffset = binary.BigEndian.Uint32(data[8:12])
 
-	idx := uint32(12)
+	idx := tpl.Header.ImgOffsetTableOffset
+	if uint64(idx)+uint64(tpl.Header.ImgNum)*8 > uint64(len(data)) {
+		return nil, formatError(int(idx), "image offset table", fmt.Errorf("%w, %v images don't fit in 0x%X bytes", formats.ErrTruncated, tpl.Header.ImgNum, len(data)))
+	}
 	var imgOffsets []ImgOffset
 	imgOffsets = make([]ImgOffset, tpl.Header.ImgNum)
 
 	for i := 0; i < int(tpl.Header.ImgNum); i++ {
-		imgOffset := ImgOffset{
-			imgHeaderOffset:    binary.BigEndian.Uint32(data[idx : idx+4]),
-			imgPalHeaderOffset: binary.BigEndian.Uint32(data[idx+4 : idx+8]),
+		err := binstruct.Read(data, int(idx), binary.BigEndian, &imgOffsets[i])
+		if err != nil {
+			return nil, binstruct.Wrap(err, "tpl", fmt.Sprintf("image %v offsets", i))
 		}
-		imgOffsets[i] = imgOffset
 		idx += 8
 	}
 	tpl.ImgOffsetTable = imgOffsets
@@ -108,85 +129,144 @@ func Read(data []byte) (*File, error) {
 
 	for i := 0; i < int(tpl.Header.ImgNum); i++ {
 		img := Img{}
-		if tpl.ImgOffsetTable[i].imgPalHeaderOffset == 0 {
-			tempPalHeader := PaletteHeader{
-				entryCount: 0,
-				unpacked:   0,
-				padding:    0,
-				PalFormat:  0,
-				palDataADR: 0,
+		if tpl.ImgOffsetTable[i].ImgPalHeaderOffset != 0 {
+			err := binstruct.Read(data, int(tpl.ImgOffsetTable[i].ImgPalHeaderOffset), binary.BigEndian, &img.palHeader)
+			if err != nil {
+				return nil, binstruct.Wrap(err, "tpl", fmt.Sprintf("image %v palette header", i))
 			}
-			img.palHeader = tempPalHeader
 
-		} else {
-
-			idx = tpl.ImgOffsetTable[i].imgPalHeaderOffset
-			//tempPalFormat := binary.BigEndian.Uint32(data[idx+4:idx+8])
-			//tempPalDataADR := binary.BigEndian.Uint32(data[idx+8:idx+12])
-
-			tempPalHeader := PaletteHeader{
-				entryCount: binary.BigEndian.Uint16(data[idx : idx+2]),
-				unpacked:   uint8(data[idx+2]),
-				padding:    uint8(data[idx+3]),
-				PalFormat:  binary.BigEndian.Uint32(data[idx+4 : idx+8]),
-				palDataADR: binary.BigEndian.Uint32(data[idx+8 : idx+12]),
+			palStart := int(img.palHeader.PalDataADR)
+			palEnd := palStart + int(img.palHeader.EntryCount)*2
+			if palEnd > len(data) {
+				return nil, formatError(palStart, fmt.Sprintf("image %v palette data", i), fmt.Errorf("%w, it ends at 0x%X of 0x%X bytes", formats.ErrTruncated, palEnd, len(data)))
 			}
-			img.palHeader = tempPalHeader
-			img.palData = []byte{0x00} //DO PALETTE DATA RIGHT LATER
+			img.palData = data[palStart:palEnd]
 		}
 
-		idx = tpl.ImgOffsetTable[i].imgHeaderOffset
+		idx = tpl.ImgOffsetTable[i].ImgHeaderOffset
+		err := binstruct.Read(data, int(idx), binary.BigEndian, &img.ImgHeader)
+		if err != nil {
+			return nil, binstruct.Wrap(err, "tpl", fmt.Sprintf("image %v header", i))
+		}
+		if img.ImgHeader.Height > 1024 || img.ImgHeader.Width > 1024 {
+			return nil, formatError(int(idx), fmt.Sprintf("image %v size", i), fmt.Errorf("%w, %vx%v is past the 1024x1024 GX textures can be", formats.ErrInvalid, img.ImgHeader.Width, img.ImgHeader.Height))
+		}
 
-		if int(idx) > len(data) {
-			return nil, nil
+		imgStart := int(img.ImgHeader.ImgDataADR)
+		imgEnd := imgStart + img.ImgHeader.dataSize()
+		if imgEnd > len(data) {
+			return nil, formatError(imgStart, fmt.Sprintf("image %v data", i), fmt.Errorf("%w, it ends at 0x%X of 0x%X bytes", formats.ErrTruncated, imgEnd, len(data)))
 		}
-		tempImgHeight := binary.BigEndian.Uint16(data[idx : idx+2])
-		tempImgWidth := binary.BigEndian.Uint16(data[idx+2 : idx+4])
+		img.ImgData = data[imgStart:imgEnd]
 
-		if tempImgHeight > 1024 || tempImgWidth > 1024 {
+		imgs[i] = img
+	}
+	tpl.ImgTable = imgs
 
-			return nil, nil
+	//The file ends wherever the last image or palette data ends.
+	fileEnd := 12 + len(tpl.ImgOffsetTable)*8
+	for i := 0; i < len(tpl.ImgTable); i++ {
+		imgEnd := int(tpl.ImgTable[i].ImgHeader.ImgDataADR) + len(tpl.ImgTable[i].ImgData)
+		if imgEnd > fileEnd {
+			fileEnd = imgEnd
 		}
-
-		tempImgHeader := ImgHeader{
-			Height:        binary.BigEndian.Uint16(data[idx : idx+2]),
-			Width:         binary.BigEndian.Uint16(data[idx+2 : idx+4]),
-			Format:        binary.BigEndian.Uint32(data[idx+4 : idx+8]),
-			ImgDataADR:    binary.BigEndian.Uint32(data[idx+8 : idx+12]),
-			WrapS:         binary.BigEndian.Uint32(data[idx+12 : idx+16]),
-			WrapT:         binary.BigEndian.Uint32(data[idx+16 : idx+20]),
-			MinFilter:     binary.BigEndian.Uint32(data[idx+20 : idx+24]),
-			MagFilter:     binary.BigEndian.Uint32(data[idx+24 : idx+28]),
-			LODBias:       binary.BigEndian.Uint32(data[idx+28 : idx+32]),
-			EdgeLODEnable: uint8(data[idx+32]),
-			MinLOD:        uint8(data[idx+33]),
-			MaxLOD:        uint8(data[idx+34]),
-			Unpacked:      uint8(data[idx+35]),
+		palEnd := int(tpl.ImgTable[i].palHeader.PalDataADR) + len(tpl.ImgTable[i].palData)
+		if palEnd > fileEnd {
+			fileEnd = palEnd
 		}
-		img.ImgHeader = tempImgHeader
+	}
+	tpl.Data = data[:fileEnd]
 
-		idx = img.ImgHeader.ImgDataADR
+	return tpl, nil
 
-		tempPxSize := (tempImgWidth * tempImgHeight) / 2
+}
 
-		img.ImgData = data[int(idx) : int(idx)+int(tempPxSize)] //supports only CMPR for now
+/*
+Decode ...
+Decode every image of a TPL file to an NRGBA image, or error
+*/
+func Decode(data *File) (*Work, error) {
+	work := &Work{}
 
-		imgs[i] = img
+	for i, img := range data.ImgTable {
+		rgba, err := decodeImage(img)
+		if err != nil {
+			return nil, formatError(int(img.ImgHeader.ImgDataADR), fmt.Sprintf("image %v", i), err)
+		}
+		work.Images = append(work.Images, rgba)
 	}
-	tpl.ImgTable = imgs
 
-	totalImgData := 0
-	for i := 0; i < len(tpl.ImgTable); i++ {
+	return work, nil
+}
 
-		totalImgData += len(tpl.ImgTable[i].ImgData)
+/*
+Write ...
+Write a TPL File to data, or error. Headers, palettes and image data are written at the offsets
+they are read from, the bytes between them are taken from data.Data, so a file that was read
+writes back the same.
+*/
+func Write(data *File) ([]byte, error) {
+	if len(data.ImgOffsetTable) != len(data.ImgTable) {
+		return nil, fmt.Errorf("there are %v image offsets for %v images", len(data.ImgOffsetTable), len(data.ImgTable))
 	}
-	idx = tpl.ImgTable[0].ImgHeader.ImgDataADR
-	tpl.Data = data[:int(idx)+totalImgData]
 
-	return tpl, nil
+	size := 12
+	grow := func(end int) {
+		if end > size {
+			size = end
+		}
+	}
+	grow(len(data.Data))
+	grow(int(data.Header.ImgOffsetTableOffset) + len(data.ImgOffsetTable)*8)
+	for i, offset := range data.ImgOffsetTable {
+		img := data.ImgTable[i]
+		grow(int(offset.ImgHeaderOffset) + imgHeaderSize)
+		grow(int(img.ImgHeader.ImgDataADR) + len(img.ImgData))
+		if offset.ImgPalHeaderOffset != 0 {
+			grow(int(offset.ImgPalHeaderOffset) + palHeaderSize)
+			grow(int(img.palHeader.PalDataADR) + len(img.palData))
+		}
+	}
+
+	out := make([]byte, size)
+	copy(out, data.Data)
+
+	binary.BigEndian.PutUint32(out[0:4], 0x0020AF30)
+	binary.BigEndian.PutUint32(out[4:8], uint32(len(data.ImgTable)))
+	binary.BigEndian.PutUint32(out[8:12], data.Header.ImgOffsetTableOffset)
 
+	//The size covers every header and data, so writing them only fails on a broken File.
+	for i, offset := range data.ImgOffsetTable {
+		err := binstruct.Write(out, int(data.Header.ImgOffsetTableOffset)+i*8, binary.BigEndian, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		img := data.ImgTable[i]
+		err = binstruct.Write(out, int(offset.ImgHeaderOffset), binary.BigEndian, img.ImgHeader)
+		if err != nil {
+			return nil, err
+		}
+		copy(out[img.ImgHeader.ImgDataADR:], img.ImgData)
+
+		if offset.ImgPalHeaderOffset != 0 {
+			err = binstruct.Write(out, int(offset.ImgPalHeaderOffset), binary.BigEndian, img.palHeader)
+			if err != nil {
+				return nil, err
+			}
+			copy(out[img.palHeader.PalDataADR:], img.palData)
+		}
+	}
+
+	return out, nil
 }
 
-//func Write(data File) ([]byte, error) {}
-//func Decode(data File) (*Work, error) {}
 //func Encode(data Work) (*File, error) {}
+
+/*
+formatError ...
+A problem of the TPL file at offset, err is one of the formats errors or wraps one
+*/
+func formatError(offset int, field string, err error) error {
+	return &formats.FormatError{Format: "tpl", Offset: offset, Field: field, Err: err}
+}
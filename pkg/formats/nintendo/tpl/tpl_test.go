@@ -0,0 +1,27 @@
+package tpl
+
+import (
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+)
+
+func FuzzRead(f *testing.F) {
+	//One 8x8 image of every format, and every format in one file.
+	var images []testgen.TPLImage
+	for _, format := range testgen.TPLFormats {
+		image := testgen.TPLImage{Format: format, Width: 8, Height: 8}
+		images = append(images, image)
+		f.Add(testgen.TPL(image).Data)
+	}
+	f.Add(testgen.TPL(images...).Data)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := Read(data)
+		if err != nil {
+			return
+		}
+		Write(file)
+		Decode(file)
+	})
+}
@@ -0,0 +1,75 @@
+package yaz0
+
+import (
+	"bytes"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+func init() {
+	formats.Register(format{})
+}
+
+/*
+format ...
+Yaz0 in the formats registry, files are *File and works are Work.
+It is a formats.Wrapper and a formats.Inspector.
+*/
+type format struct{}
+
+func (format) Name() string         { return "yaz0" }
+func (format) Extensions() []string { return []string{".szs", ".yaz0"} }
+
+func (format) Probe(data []byte) bool {
+	return len(data) >= headerSize && (bytes.Equal(data[:4], []byte("Yaz0")) || bytes.Equal(data[:4], []byte("YAZ0")))
+}
+
+func (format) Read(data []byte) (interface{}, error) {
+	return Read(data)
+}
+
+func (format) Write(file interface{}) ([]byte, error) {
+	yaz0File, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("yaz0", "*yaz0.File", file)
+	}
+	return Write(yaz0File)
+}
+
+func (format) Decode(file interface{}) (interface{}, error) {
+	yaz0File, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("yaz0", "*yaz0.File", file)
+	}
+	return Decode(yaz0File)
+}
+
+/*
+Unwrap ...
+Return the decompressed data, yaz0 only ever wraps another file
+*/
+func (format) Unwrap(data []byte) ([]byte, error) {
+	file, err := Read(data)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(file)
+}
+
+func (format) Encode(work interface{}) (interface{}, error) {
+	switch work := work.(type) {
+	case Work:
+		return Encode(work), nil
+	case []byte:
+		return Encode(Work(work)), nil
+	}
+	return nil, formats.WrongType("yaz0", "yaz0.Work", work)
+}
+
+func (format) Inspect(file interface{}) (*formats.Inspection, error) {
+	yaz0File, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("yaz0", "*yaz0.File", file)
+	}
+	return Inspect(yaz0File), nil
+}
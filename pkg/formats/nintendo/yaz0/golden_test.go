@@ -0,0 +1,55 @@
+package yaz0
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+)
+
+func TestGolden(t *testing.T) {
+	payloads := map[string][]byte{
+		"empty":    {},
+		"literals": []byte("The quick brown fox"),
+		//Runs just below, at and above the lengths where the back reference kinds change.
+		"short runs": append(bytes.Repeat([]byte{'a'}, 3), bytes.Repeat([]byte{'b'}, 0x12)...),
+		"long runs":  append(bytes.Repeat([]byte{'c'}, 0x13), bytes.Repeat([]byte{0}, 0x400)...),
+		"mixed":      []byte("xxxxxxxxyz yyyyyyyyyyyyyyyyyyyyyyyyyyyy!"),
+	}
+
+	for name, payload := range payloads {
+		t.Run(name, func(t *testing.T) {
+			data := testgen.Yaz0(payload)
+
+			file, err := Read(data)
+			if err != nil {
+				t.Fatalf("reading: %v", err)
+			}
+			if int(file.Header.DataSize) != len(payload) {
+				t.Fatalf("the header has size 0x%X, it has to be 0x%X", file.Header.DataSize, len(payload))
+			}
+
+			written, err := Write(file)
+			if err != nil {
+				t.Fatalf("writing: %v", err)
+			}
+			if !bytes.Equal(written, data) {
+				t.Fatalf("writing changed the file, 0x%X bytes became 0x%X", len(data), len(written))
+			}
+
+			work, err := Decode(file)
+			if err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+			if !bytes.Equal(work, payload) {
+				t.Fatalf("decoded %q, it has to be %q", work, payload)
+			}
+
+			//What the package compresses has to decompress the same too.
+			work, err = Decode(Encode(work))
+			if err != nil || !bytes.Equal(work, payload) {
+				t.Fatalf("compressing again gave back %q, %v", work, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package yaz0
+
+import (
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+/*
+Inspect ...
+Show the fields of a Yaz0 header and how much the data is compressed
+*/
+func Inspect(data *File) *formats.Inspection {
+	inspection := formats.NewInspection("yaz0")
+
+	inspection.Section("header").
+		Field("magic", data.Header.Magic).
+		Field("uncompressed size", formats.Hex(data.Header.DataSize)).
+		Field("reserved 1", formats.Hex(data.Header.reserved1)).
+		Field("reserved 2", formats.Hex(data.Header.reserved2))
+
+	inspection.Section("data").
+		Field("offset", formats.Hex(headerSize)).
+		Field("compressed size", formats.Hex(len(data.Data)))
+
+	return inspection
+}
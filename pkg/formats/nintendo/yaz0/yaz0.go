@@ -4,14 +4,25 @@ package yaz0
 NAME: YAZ0
 EXTENSION: .szs
 DESCRIPTION: Nintendo's run-length encoding, It is used a lot in Nintendo games across their various consoles
+BINARY STRUCTURE:
+
+	magic;             "Yaz0", some tools write "YAZ0"
+	uncompressed size; uint32
+	reserved;          8 bytes, usually zero
+	data;              groups of a code byte and 8 chunks, a set bit in the code is one literal byte,
+	                   a clear bit a back reference of 2 or 3 bytes
 */
 
 import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+
+	"github.com/ProfElements/go-files/pkg/formats"
 )
 
+const headerSize = 0x10
+
 type Header struct {
 	Magic     string
 	DataSize  uint32
@@ -27,8 +38,12 @@ type Work []byte
 func Read(data []byte) (*File, error) {
 	file := &File{}
 
-	if !bytes.Equal(data[:4], []byte("YAZ0")) {
-		return nil, fmt.Errorf("This is not a yaz0 encoded file, the  file magic is wrong!")
+	if len(data) < headerSize {
+		return nil, formatError(0, "header", fmt.Errorf("%w, it is 0x10 bytes and there are 0x%X", formats.ErrTruncated, len(data)))
+	}
+
+	if !bytes.Equal(data[:4], []byte("Yaz0")) && !bytes.Equal(data[:4], []byte("YAZ0")) {
+		return nil, formatError(0, "magic", fmt.Errorf("%w, %q isn't Yaz0 or YAZ0", formats.ErrBadMagic, data[:4]))
 	}
 
 	file.Header.Magic = string(data[:4])
@@ -40,8 +55,16 @@ func Read(data []byte) (*File, error) {
 	return file, nil
 }
 func Write(data *File) ([]byte, error) {
+	magic := data.Header.Magic
+	if magic == "" {
+		magic = "Yaz0"
+	}
+	if magic != "Yaz0" && magic != "YAZ0" {
+		return nil, fmt.Errorf("yaz0 magic has to be Yaz0 or YAZ0, not %v", magic)
+	}
+
 	buffer := &bytes.Buffer{}
-	_, err := buffer.WriteString("YAZ0")
+	_, err := buffer.WriteString(magic)
 	if err != nil {
 		return nil, err
 	}
@@ -51,12 +74,12 @@ func Write(data *File) ([]byte, error) {
 		return nil, err
 	}
 
-	err = binary.Write(buffer, binary.BigEndian, uint32(0))
+	err = binary.Write(buffer, binary.BigEndian, data.Header.reserved1)
 	if err != nil {
 		return nil, err
 	}
 
-	err = binary.Write(buffer, binary.BigEndian, uint32(0))
+	err = binary.Write(buffer, binary.BigEndian, data.Header.reserved2)
 	if err != nil {
 		return nil, err
 	}
@@ -68,13 +91,18 @@ func Write(data *File) ([]byte, error) {
 
 	return buffer.Bytes(), nil
 }
-func Decode(data *File) Work {
-	return decompress(data.Data)
+
+/*
+Decode ...
+Decompress a yaz0 file, or error when its data runs out or refers back past its start
+*/
+func Decode(data *File) (Work, error) {
+	return decompress(data.Data, int(data.Header.DataSize))
 }
 func Encode(data Work) *File {
 	file := &File{}
 
-	file.Header.Magic = "YAZ0"
+	file.Header.Magic = "Yaz0"
 	file.Header.DataSize = uint32(len(data))
 	file.Header.reserved1 = uint32(0)
 	file.Header.reserved2 = uint32(0)
@@ -83,11 +111,149 @@ func Encode(data Work) *File {
 	return file
 }
 
-func decompress(data []byte) []byte {
-	//temporarily just return data
-	return data
+func decompress(data []byte, size int) ([]byte, error) {
+	//A chunk expands to at most 0x111 bytes, so a bogus size can't make a huge allocation.
+	capacity := size
+	if limit := len(data) * 0x111; capacity > limit {
+		capacity = limit
+	}
+	out := make([]byte, 0, capacity)
+
+	readIndex := 0
+	for len(out) < size {
+		if readIndex >= len(data) {
+			return nil, formatError(headerSize+readIndex, "compressed data", fmt.Errorf("%w, it ends after 0x%X of 0x%X bytes", formats.ErrTruncated, len(out), size))
+		}
+		code := data[readIndex]
+		readIndex++
+
+		for bit := 7; bit >= 0 && len(out) < size; bit-- {
+			if code&(1<<uint(bit)) != 0 {
+				if readIndex >= len(data) {
+					return nil, formatError(headerSize+readIndex, "compressed data", fmt.Errorf("%w, it ends after 0x%X of 0x%X bytes", formats.ErrTruncated, len(out), size))
+				}
+				out = append(out, data[readIndex])
+				readIndex++
+				continue
+			}
+
+			if readIndex+2 > len(data) {
+				return nil, formatError(headerSize+readIndex, "back reference", formats.ErrTruncated)
+			}
+			distance := (int(data[readIndex]&0x0F)<<8 | int(data[readIndex+1])) + 1
+			length := int(data[readIndex] >> 4)
+			readIndex += 2
+			if length == 0 {
+				if readIndex >= len(data) {
+					return nil, formatError(headerSize+readIndex-2, "back reference", formats.ErrTruncated)
+				}
+				length = int(data[readIndex]) + 0x12
+				readIndex++
+			} else {
+				length += 2
+			}
+
+			if distance > len(out) {
+				return nil, formatError(headerSize+readIndex, "back reference", fmt.Errorf("%w, it goes 0x%X bytes back and only 0x%X are decoded", formats.ErrInvalid, distance, len(out)))
+			}
+			//The copy can overlap what it writes, so it goes byte by byte.
+			for i := 0; i < length && len(out) < size; i++ {
+				out = append(out, out[len(out)-distance])
+			}
+		}
+	}
+
+	return out, nil
 }
+
+const (
+	window     = 0x1000 //How far back a back reference reaches.
+	maxLength  = 0x111  //The longest back reference, 0xFF + 0x12.
+	hashBits   = 15
+	chainLimit = 64 //How many earlier positions are tried for every match.
+)
+
+/*
+compress ...
+Greedy LZ compression: at every position the longest match among the last positions that
+start with the same 3 bytes is taken, found through hash chains, or a literal when there is
+none of at least 3 bytes
+*/
 func compress(data []byte) []byte {
-	//temporarily just return data
-	return data
+	out := make([]byte, 0, len(data)+(len(data)+7)/8)
+
+	head := make([]int, 1<<hashBits)
+	for idx := range head {
+		head[idx] = -1
+	}
+	prev := make([]int, len(data))
+	insert := func(pos int) {
+		if pos+3 > len(data) {
+			return
+		}
+		key := hash3(data[pos:])
+		prev[pos] = head[key]
+		head[key] = pos
+	}
+
+	codeIndex, bit := 0, 8
+	for pos := 0; pos < len(data); bit++ {
+		if bit == 8 {
+			codeIndex = len(out)
+			out = append(out, 0)
+			bit = 0
+		}
+
+		length, distance := 0, 0
+		if pos+3 <= len(data) {
+			candidate := head[hash3(data[pos:])]
+			for tries := 0; candidate >= 0 && pos-candidate <= window && tries < chainLimit; tries++ {
+				matched := 0
+				for matched < maxLength && pos+matched < len(data) && data[candidate+matched] == data[pos+matched] {
+					matched++
+				}
+				if matched > length {
+					length, distance = matched, pos-candidate
+					if length == maxLength {
+						break
+					}
+				}
+				candidate = prev[candidate]
+			}
+		}
+
+		if length < 3 {
+			out[codeIndex] |= 0x80 >> uint(bit)
+			out = append(out, data[pos])
+			insert(pos)
+			pos++
+			continue
+		}
+
+		back := distance - 1
+		if length < 0x12 {
+			out = append(out, byte((length-2)<<4|back>>8), byte(back))
+		} else {
+			out = append(out, byte(back>>8), byte(back), byte(length-0x12))
+		}
+		for idx := 0; idx < length; idx++ {
+			insert(pos + idx)
+		}
+		pos += length
+	}
+
+	return out
+}
+
+func hash3(data []byte) int {
+	key := uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2])
+	return int((key * 2654435761) >> (32 - hashBits))
+}
+
+/*
+formatError ...
+A problem of the Yaz0 file at offset, err is one of the formats errors or wraps one
+*/
+func formatError(offset int, field string, err error) error {
+	return &formats.FormatError{Format: "yaz0", Offset: offset, Field: field, Err: err}
 }
@@ -0,0 +1,35 @@
+package yaz0
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+)
+
+func FuzzRead(f *testing.F) {
+	for _, work := range []Work{{}, Work("a"), Work(bytes.Repeat([]byte("abcdefg"), 100)), Work(bytes.Repeat([]byte{0}, 0x1000))} {
+		data, err := Write(Encode(work))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	//Both kinds of back reference, made without the package's own compressor.
+	f.Add(testgen.Yaz0(append([]byte("abc"), bytes.Repeat([]byte{'d'}, 0x200)...)))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := Read(data)
+		if err != nil {
+			return
+		}
+		Write(file)
+		Decode(file)
+
+		//Whatever the data is, compressing it has to give it back.
+		work, err := Decode(Encode(Work(data)))
+		if err != nil || !bytes.Equal(work, data) {
+			t.Fatalf("compressing %x gave back %x, %v", data, work, err)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package tga
+
+import (
+	"image"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+func init() {
+	formats.Register(format{})
+}
+
+/*
+format ...
+TGA in the formats registry, files are *File and works are *image.NRGBA.
+Any image.Image can be encoded. It is a formats.Inspector.
+*/
+type format struct{}
+
+func (format) Name() string         { return "tga" }
+func (format) Extensions() []string { return []string{".tga"} }
+
+func (format) Probe(data []byte) bool {
+	//TGA has no magic, the footer of TGA 2.0 files is the only sure sign.
+	return len(data) >= footerSize && hasFooter(data[len(data)-footerSize:])
+}
+
+func (format) Read(data []byte) (interface{}, error) {
+	return Read(data)
+}
+
+func (format) Write(file interface{}) ([]byte, error) {
+	tgaFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("tga", "*tga.File", file)
+	}
+	return Write(tgaFile)
+}
+
+func (format) Decode(file interface{}) (interface{}, error) {
+	tgaFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("tga", "*tga.File", file)
+	}
+	return Decode(tgaFile)
+}
+
+func (format) Encode(work interface{}) (interface{}, error) {
+	img, ok := work.(image.Image)
+	if !ok {
+		return nil, formats.WrongType("tga", "image.Image", work)
+	}
+	return Encode(img), nil
+}
+
+func (format) Inspect(file interface{}) (*formats.Inspection, error) {
+	tgaFile, ok := file.(*File)
+	if !ok {
+		return nil, formats.WrongType("tga", "*tga.File", file)
+	}
+	return Inspect(tgaFile), nil
+}
@@ -0,0 +1,55 @@
+package tga
+
+import (
+	"fmt"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+var imageTypeNames = map[ImageType]string{
+	NoImage:        "no image",
+	ColorMapped:    "color mapped",
+	TrueColor:      "true color",
+	Grayscale:      "grayscale",
+	RLEColorMapped: "rle color mapped",
+	RLETrueColor:   "rle true color",
+	RLEGrayscale:   "rle grayscale",
+}
+
+/*
+Inspect ...
+Show the fields of a TGA header and where the ID, color map, image data and trailer are
+*/
+func Inspect(data *File) *formats.Inspection {
+	inspection := formats.NewInspection("tga")
+	header := data.Header
+
+	imageType := fmt.Sprintf("0x%X", uint8(header.ImageType))
+	if name, ok := imageTypeNames[header.ImageType]; ok {
+		imageType = fmt.Sprintf("%v (0x%X)", name, uint8(header.ImageType))
+	}
+
+	inspection.Section("header").
+		Field("id length", header.IDLength).
+		Field("color map type", header.ColorMapType).
+		Field("image type", imageType).
+		Field("color map first", header.ColorMapFirst).
+		Field("color map length", header.ColorMapLength).
+		Field("color map depth", header.ColorMapDepth).
+		Field("origin", fmt.Sprintf("%v,%v", header.XOrigin, header.YOrigin)).
+		Field("size", fmt.Sprintf("%vx%v", header.Width, header.Height)).
+		Field("pixel depth", header.PixelDepth).
+		Field("descriptor", formats.Hex(header.Descriptor))
+
+	offset := headerSize
+	parts := inspection.Table("parts", "part", "offset", "size")
+	for _, part := range []struct {
+		name string
+		data []byte
+	}{{"id", data.ID}, {"color map", data.ColorMap}, {"image data", data.ImageData}, {"trailer", data.Trailer}} {
+		parts.Row(part.name, formats.Hex(offset), formats.Hex(len(part.data)))
+		offset += len(part.data)
+	}
+
+	return inspection
+}
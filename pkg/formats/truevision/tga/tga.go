@@ -0,0 +1,438 @@
+package tga
+
+/*
+Name: Truevision TGA
+Extension: .tga
+Description: A simple image format used for a lot of UI textures. Colour-mapped, truecolour and
+             greyscale images are supported, both uncompressed and run-length encoded.
+             The optional TGA 2.0 extension area and footer are kept as raw data.
+BINARY STRUCTURE:
+
+             header;          0x12 bytes, little endian
+             image id;        IDLength bytes
+             colour map;      ColorMapLength entries of ColorMapDepth bits
+             image data;      Width*Height pixels, run-length encoded for image types 9, 10 and 11
+             extension area;  optional, 495 bytes
+             footer;          optional, 26 bytes ending in "TRUEVISION-XFILE.\x00"
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+type ImageType uint8
+
+const (
+	NoImage        ImageType = 0x00
+	ColorMapped    ImageType = 0x01
+	TrueColor      ImageType = 0x02
+	Grayscale      ImageType = 0x03
+	RLEColorMapped ImageType = 0x09
+	RLETrueColor   ImageType = 0x0A
+	RLEGrayscale   ImageType = 0x0B
+)
+
+const (
+	headerSize        = 0x12
+	extensionAreaSize = 495
+	footerSize        = 26
+	footerSignature   = "TRUEVISION-XFILE.\x00"
+)
+
+type Header struct {
+	IDLength       uint8
+	ColorMapType   uint8
+	ImageType      ImageType
+	ColorMapFirst  uint16
+	ColorMapLength uint16
+	ColorMapDepth  uint8
+	XOrigin        uint16
+	YOrigin        uint16
+	Width          uint16
+	Height         uint16
+	PixelDepth     uint8
+	Descriptor     uint8
+}
+
+type File struct {
+	Header    Header
+	ID        []byte
+	ColorMap  []byte
+	ImageData []byte
+	//Trailer holds the extension area and footer, when the file has them.
+	Trailer []byte
+	Data    []byte
+}
+
+func init() {
+	//TGA has no magic, the colour map type and image type right after the id length are the closest thing.
+	for _, magic := range []string{"?\x00\x02", "?\x00\x03", "?\x00\x0A", "?\x00\x0B", "?\x01\x01", "?\x01\x09"} {
+		image.RegisterFormat("tga", magic, decodeReader, decodeConfig)
+	}
+}
+
+/*
+Read ...
+Read a TGA file from the start of data and return a File structure pointer, or error.
+data may go on past the end of the image, File.Data only holds the bytes that belong to it.
+*/
+func Read(data []byte) (*File, error) {
+	file := &File{}
+
+	if len(data) < headerSize {
+		return nil, formatError(0, "header", fmt.Errorf("%w, it is 0x%X bytes and there are 0x%X", formats.ErrTruncated, headerSize, len(data)))
+	}
+
+	file.Header = Header{
+		IDLength:       data[0],
+		ColorMapType:   data[1],
+		ImageType:      ImageType(data[2]),
+		ColorMapFirst:  binary.LittleEndian.Uint16(data[3:5]),
+		ColorMapLength: binary.LittleEndian.Uint16(data[5:7]),
+		ColorMapDepth:  data[7],
+		XOrigin:        binary.LittleEndian.Uint16(data[8:10]),
+		YOrigin:        binary.LittleEndian.Uint16(data[10:12]),
+		Width:          binary.LittleEndian.Uint16(data[12:14]),
+		Height:         binary.LittleEndian.Uint16(data[14:16]),
+		PixelDepth:     data[16],
+		Descriptor:     data[17],
+	}
+
+	switch file.Header.ImageType {
+	case ColorMapped, TrueColor, Grayscale, RLEColorMapped, RLETrueColor, RLEGrayscale:
+	default:
+		return nil, formatError(2, "image type", fmt.Errorf("%w, image type %v", formats.ErrUnsupportedFormat, file.Header.ImageType))
+	}
+
+	if file.Header.ColorMapType > 1 {
+		return nil, formatError(1, "colour map type", fmt.Errorf("%w, %v is not a tga colour map type", formats.ErrInvalid, file.Header.ColorMapType))
+	}
+
+	pixelSize := (int(file.Header.PixelDepth) + 7) / 8
+	if pixelSize == 0 || pixelSize > 4 {
+		return nil, formatError(16, "pixel depth", fmt.Errorf("%w, pixel depth %v", formats.ErrUnsupportedFormat, file.Header.PixelDepth))
+	}
+
+	idx := headerSize
+	if idx+int(file.Header.IDLength) > len(data) {
+		return nil, formatError(idx, "image id", formats.ErrTruncated)
+	}
+	file.ID = data[idx : idx+int(file.Header.IDLength)]
+	idx += int(file.Header.IDLength)
+
+	if file.Header.ColorMapType == 1 {
+		colorMapSize := int(file.Header.ColorMapLength) * ((int(file.Header.ColorMapDepth) + 7) / 8)
+		if idx+colorMapSize > len(data) {
+			return nil, formatError(idx, "colour map", formats.ErrTruncated)
+		}
+		file.ColorMap = data[idx : idx+colorMapSize]
+		idx += colorMapSize
+	}
+
+	pixels := int(file.Header.Width) * int(file.Header.Height)
+	imageSize := pixels * pixelSize
+	if file.Header.ImageType&0x08 != 0 {
+		size, err := rleSize(data[idx:], pixels, pixelSize)
+		if err != nil {
+			return nil, formatError(idx, "image data", err)
+		}
+		imageSize = size
+	}
+	if idx+imageSize > len(data) {
+		return nil, formatError(idx, "image data", fmt.Errorf("%w, it is 0x%X bytes and there are 0x%X", formats.ErrTruncated, imageSize, len(data)-idx))
+	}
+	file.ImageData = data[idx : idx+imageSize]
+	idx += imageSize
+
+	trailerSize := 0
+	if hasFooter(data[idx:]) {
+		trailerSize = footerSize
+	} else if len(data[idx:]) >= 2 && binary.LittleEndian.Uint16(data[idx:]) == extensionAreaSize && hasFooter(data[idx+extensionAreaSize:]) {
+		trailerSize = extensionAreaSize + footerSize
+	}
+	file.Trailer = data[idx : idx+trailerSize]
+	idx += trailerSize
+
+	file.Data = data[:idx]
+
+	return file, nil
+}
+
+/*
+Write ...
+Write a File structure to a .tga file, or error
+*/
+func Write(data *File) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+
+	header := data.Header
+	header.IDLength = uint8(len(data.ID))
+
+	err := binary.Write(buffer, binary.LittleEndian, header)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer.Write(data.ID)
+	buffer.Write(data.ColorMap)
+	buffer.Write(data.ImageData)
+	buffer.Write(data.Trailer)
+
+	return buffer.Bytes(), nil
+}
+
+/*
+Decode ...
+Decode the image of a TGA file to an NRGBA image, or error
+*/
+func Decode(data *File) (*image.NRGBA, error) {
+	header := data.Header
+	width, height := int(header.Width), int(header.Height)
+	pixelSize := (int(header.PixelDepth) + 7) / 8
+
+	pixels := data.ImageData
+	if header.ImageType&0x08 != 0 {
+		var err error
+		pixels, err = rleDecode(data.ImageData, width*height, pixelSize)
+		if err != nil {
+			return nil, formatError(data.imageOffset(), "image data", err)
+		}
+	}
+	if len(pixels) < width*height*pixelSize {
+		return nil, formatError(data.imageOffset(), "image data", fmt.Errorf("%w, a %vx%v image needs 0x%X bytes and there are 0x%X", formats.ErrTruncated, width, height, width*height*pixelSize, len(pixels)))
+	}
+
+	alphaBits := header.Descriptor & 0x0F
+
+	var palette []color.NRGBA
+	if header.ImageType == ColorMapped || header.ImageType == RLEColorMapped {
+		entrySize := (int(header.ColorMapDepth) + 7) / 8
+		for i := 0; entrySize > 0 && i+entrySize <= len(data.ColorMap); i += entrySize {
+			palette = append(palette, pixelColor(data.ColorMap[i:i+entrySize], header.ColorMapDepth, alphaBits))
+		}
+	}
+
+	rightToLeft := header.Descriptor&0x10 != 0
+	topToBottom := header.Descriptor&0x20 != 0
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < width*height; i++ {
+		pixel := pixels[i*pixelSize : (i+1)*pixelSize]
+
+		var c color.NRGBA
+		switch header.ImageType {
+		case ColorMapped, RLEColorMapped:
+			index := int(pixel[0])
+			if pixelSize == 2 {
+				index = int(binary.LittleEndian.Uint16(pixel))
+			}
+			index -= int(header.ColorMapFirst)
+			if index >= 0 && index < len(palette) {
+				c = palette[index]
+			}
+		case Grayscale, RLEGrayscale:
+			c = color.NRGBA{pixel[0], pixel[0], pixel[0], 0xFF}
+			if pixelSize == 2 {
+				c.A = pixel[1]
+			}
+		default:
+			c = pixelColor(pixel, header.PixelDepth, alphaBits)
+		}
+
+		x, y := i%width, i/width
+		if rightToLeft {
+			x = width - 1 - x
+		}
+		if !topToBottom {
+			y = height - 1 - y
+		}
+		img.SetNRGBA(x, y, c)
+	}
+
+	return img, nil
+}
+
+/*
+Encode ...
+Encode an image to an uncompressed 32 bit truecolour TGA file
+*/
+func Encode(img image.Image) *File {
+	bounds := img.Bounds()
+	file := &File{
+		Header: Header{
+			ImageType:  TrueColor,
+			Width:      uint16(bounds.Dx()),
+			Height:     uint16(bounds.Dy()),
+			PixelDepth: 32,
+			//8 alpha bits, rows stored top to bottom.
+			Descriptor: 0x28,
+		},
+	}
+
+	file.ImageData = make([]byte, 0, bounds.Dx()*bounds.Dy()*4)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			file.ImageData = append(file.ImageData, c.B, c.G, c.R, c.A)
+		}
+	}
+
+	return file
+}
+
+/*
+pixelColor ...
+Convert one little endian BGR(A) pixel of the given bit depth to a colour
+*/
+func pixelColor(pixel []byte, depth uint8, alphaBits uint8) color.NRGBA {
+	switch {
+	case depth <= 16 && len(pixel) >= 2:
+		value := binary.LittleEndian.Uint16(pixel)
+		c := color.NRGBA{
+			R: convert5to8(uint8(value >> 10 & 0x1F)),
+			G: convert5to8(uint8(value >> 5 & 0x1F)),
+			B: convert5to8(uint8(value & 0x1F)),
+			A: 0xFF,
+		}
+		if depth == 16 && alphaBits == 1 && value&0x8000 == 0 {
+			c.A = 0x00
+		}
+		return c
+	case len(pixel) == 3:
+		return color.NRGBA{pixel[2], pixel[1], pixel[0], 0xFF}
+	case len(pixel) >= 4:
+		return color.NRGBA{pixel[2], pixel[1], pixel[0], pixel[3]}
+	}
+	return color.NRGBA{pixel[0], pixel[0], pixel[0], 0xFF}
+}
+
+/*
+rleSize ...
+Walk the run-length packets of pixels pixels and return how many bytes they take up
+*/
+func rleSize(data []byte, pixels int, pixelSize int) (int, error) {
+	idx := 0
+	for count := 0; count < pixels; {
+		if idx >= len(data) {
+			return 0, fmt.Errorf("%w, run-length data ends after %v of %v pixels", formats.ErrTruncated, count, pixels)
+		}
+
+		packet := data[idx]
+		length := int(packet&0x7F) + 1
+		if packet&0x80 != 0 {
+			idx += 1 + pixelSize
+		} else {
+			idx += 1 + length*pixelSize
+		}
+		count += length
+	}
+
+	if idx > len(data) {
+		return 0, fmt.Errorf("%w, run-length data ends past the end of the data", formats.ErrTruncated)
+	}
+
+	return idx, nil
+}
+
+/*
+rleDecode ...
+Decode the run-length packets of pixels pixels, or error. A packet covers at most 128 pixels, a
+header asking for more than the packets in data can cover fails before anything is allocated.
+*/
+func rleDecode(data []byte, pixels int, pixelSize int) ([]byte, error) {
+	if maxPixels := (len(data) + pixelSize) / (1 + pixelSize) * 128; pixels > maxPixels {
+		return nil, fmt.Errorf("%w, 0x%X bytes of run-length data hold at most %v pixels and the image has %v", formats.ErrTruncated, len(data), maxPixels, pixels)
+	}
+	out := make([]byte, 0, pixels*pixelSize)
+
+	idx := 0
+	for len(out) < pixels*pixelSize {
+		if idx >= len(data) {
+			return nil, fmt.Errorf("%w, run-length data ends before the image is complete", formats.ErrTruncated)
+		}
+
+		packet := data[idx]
+		idx++
+		length := int(packet&0x7F) + 1
+
+		if packet&0x80 != 0 {
+			if idx+pixelSize > len(data) {
+				return nil, fmt.Errorf("%w, run-length packet at 0x%X ends past the end of the data", formats.ErrTruncated, idx-1)
+			}
+			for i := 0; i < length; i++ {
+				out = append(out, data[idx:idx+pixelSize]...)
+			}
+			idx += pixelSize
+		} else {
+			if idx+length*pixelSize > len(data) {
+				return nil, fmt.Errorf("%w, raw packet at 0x%X ends past the end of the data", formats.ErrTruncated, idx-1)
+			}
+			out = append(out, data[idx:idx+length*pixelSize]...)
+			idx += length * pixelSize
+		}
+	}
+
+	//A run may cross the last pixel, it is cut off there.
+	return out[:pixels*pixelSize], nil
+}
+
+/*
+imageOffset ...
+Where the image data of a file that was read starts, after the header, id and colour map
+*/
+func (data *File) imageOffset() int {
+	return headerSize + len(data.ID) + len(data.ColorMap)
+}
+
+func hasFooter(data []byte) bool {
+	return len(data) >= footerSize && string(data[footerSize-len(footerSignature):footerSize]) == footerSignature
+}
+
+func decodeReader(r io.Reader) (image.Image, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := Read(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode(file)
+}
+
+func decodeConfig(r io.Reader) (image.Config, error) {
+	header := make([]byte, headerSize)
+	_, err := io.ReadFull(bufio.NewReader(r), header)
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	return image.Config{
+		ColorModel: color.NRGBAModel,
+		Width:      int(binary.LittleEndian.Uint16(header[12:14])),
+		Height:     int(binary.LittleEndian.Uint16(header[14:16])),
+	}, nil
+}
+
+func convert5to8(v uint8) uint8 {
+	return (v << 3) | (v >> 2)
+}
+
+/*
+formatError ...
+A problem of the TGA file at offset, err is one of the formats errors or wraps one
+*/
+func formatError(offset int, field string, err error) error {
+	return &formats.FormatError{Format: "tga", Offset: offset, Field: field, Err: err}
+}
@@ -0,0 +1,150 @@
+package tga
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"runtime"
+	"testing"
+
+	"github.com/ProfElements/go-files/internal/testgen"
+	"github.com/ProfElements/go-files/pkg/formats"
+)
+
+func FuzzRead(f *testing.F) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	for idx := 0; idx < 12; idx++ {
+		img.SetNRGBA(idx%4, idx/4, color.NRGBA{uint8(idx * 20), 0x40, 0x80, uint8(255 - idx)})
+	}
+	data, err := Write(Encode(img))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+
+	//Run-length encoded greyscale with a raw and a repeated packet, then the same colour mapped.
+	f.Add([]byte{0, 0, 11, 0, 0, 0, 0, 0, 0, 0, 0, 0, 4, 0, 1, 0, 8, 0, 0x01, 0x10, 0x20, 0x82, 0x30})
+	f.Add([]byte{0, 1, 9, 0, 0, 2, 0, 24, 0, 0, 0, 0, 4, 0, 1, 0, 8, 0, 1, 2, 3, 4, 5, 6, 0x01, 0x00, 0x01, 0x82, 0x00})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := Read(data)
+		if err != nil {
+			return
+		}
+		Write(file)
+		Decode(file)
+	})
+}
+
+var (
+	red   = color.NRGBA{0xFF, 0x00, 0x00, 0xFF}
+	green = color.NRGBA{0x00, 0xFF, 0x00, 0xFF}
+	blue  = color.NRGBA{0x00, 0x00, 0xFF, 0xFF}
+	white = color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF}
+)
+
+func TestDecode(t *testing.T) {
+	//Every image is 2x2, want holds its rows from the top.
+	tests := []struct {
+		name string
+		tga  testgen.TGAImage
+		want [2][2]color.NRGBA
+	}{
+		{
+			name: "truecolour bottom to top",
+			tga:  testgen.TGAImage{Type: 2, Width: 2, Height: 2, PixelDepth: 24, Data: []byte{0, 0, 0xFF, 0, 0xFF, 0, 0xFF, 0, 0, 0xFF, 0xFF, 0xFF}},
+			want: [2][2]color.NRGBA{{blue, white}, {red, green}},
+		},
+		{
+			name: "truecolour top to bottom with alpha",
+			tga:  testgen.TGAImage{Type: 2, Width: 2, Height: 2, PixelDepth: 32, Descriptor: 0x28, Data: []byte{0, 0, 0xFF, 0xFF, 0, 0xFF, 0, 0x80, 0xFF, 0, 0, 0x00, 0xFF, 0xFF, 0xFF, 0xFF}},
+			want: [2][2]color.NRGBA{{red, {0x00, 0xFF, 0x00, 0x80}}, {{0x00, 0x00, 0xFF, 0x00}, white}},
+		},
+		{
+			name: "truecolour right to left",
+			tga:  testgen.TGAImage{Type: 2, Width: 2, Height: 2, PixelDepth: 24, Descriptor: 0x30, Data: []byte{0, 0, 0xFF, 0, 0xFF, 0, 0xFF, 0, 0, 0xFF, 0xFF, 0xFF}},
+			want: [2][2]color.NRGBA{{green, red}, {white, blue}},
+		},
+		{
+			name: "16 bit with an alpha bit",
+			tga:  testgen.TGAImage{Type: 2, Width: 2, Height: 2, PixelDepth: 16, Descriptor: 0x21, Data: []byte{0x00, 0xFC, 0xE0, 0x03, 0x1F, 0x80, 0xFF, 0xFF}},
+			want: [2][2]color.NRGBA{{red, {0x00, 0xFF, 0x00, 0x00}}, {blue, white}},
+		},
+		{
+			name: "greyscale",
+			tga:  testgen.TGAImage{Type: 3, Width: 2, Height: 2, PixelDepth: 8, Descriptor: 0x20, Data: []byte{0x00, 0x40, 0x80, 0xFF}},
+			want: [2][2]color.NRGBA{{{0, 0, 0, 0xFF}, {0x40, 0x40, 0x40, 0xFF}}, {{0x80, 0x80, 0x80, 0xFF}, white}},
+		},
+		{
+			name: "colour mapped",
+			tga: testgen.TGAImage{Type: 1, Width: 2, Height: 2, PixelDepth: 8, Descriptor: 0x20, ColorMapDepth: 24, ColorMap: []byte{0, 0, 0xFF, 0, 0xFF, 0, 0xFF, 0, 0},
+				Data: []byte{2, 1, 0, 2}},
+			want: [2][2]color.NRGBA{{blue, green}, {red, blue}},
+		},
+		{
+			name: "colour mapped from a first entry",
+			tga: testgen.TGAImage{Type: 1, Width: 2, Height: 2, PixelDepth: 8, Descriptor: 0x20, ColorMapFirst: 4, ColorMapDepth: 24, ColorMap: []byte{0, 0, 0xFF, 0xFF, 0xFF, 0xFF},
+				Data: []byte{4, 5, 5, 4}},
+			want: [2][2]color.NRGBA{{red, white}, {white, red}},
+		},
+		{
+			name: "run-length truecolour",
+			//A run of three red pixels, then a raw packet of one blue one.
+			tga:  testgen.TGAImage{Type: 10, Width: 2, Height: 2, PixelDepth: 24, Descriptor: 0x20, Data: []byte{0x82, 0, 0, 0xFF, 0x00, 0xFF, 0, 0}},
+			want: [2][2]color.NRGBA{{red, red}, {red, blue}},
+		},
+		{
+			name: "run-length colour mapped bottom to top",
+			tga: testgen.TGAImage{Type: 9, Width: 2, Height: 2, PixelDepth: 8, ColorMapDepth: 24, ColorMap: []byte{0, 0xFF, 0, 0xFF, 0xFF, 0xFF},
+				Data: []byte{0x01, 0, 1, 0x81, 1}},
+			want: [2][2]color.NRGBA{{white, white}, {green, white}},
+		},
+		{
+			name: "run-length greyscale with a run past the last pixel",
+			tga:  testgen.TGAImage{Type: 11, Width: 2, Height: 2, PixelDepth: 8, Descriptor: 0x20, Data: []byte{0x00, 0x10, 0x85, 0x20}},
+			want: [2][2]color.NRGBA{{{0x10, 0x10, 0x10, 0xFF}, {0x20, 0x20, 0x20, 0xFF}}, {{0x20, 0x20, 0x20, 0xFF}, {0x20, 0x20, 0x20, 0xFF}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := Read(testgen.TGA(test.tga))
+			if err != nil {
+				t.Fatalf("reading: %v", err)
+			}
+			img, err := Decode(file)
+			if err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+
+			for y := range test.want {
+				for x, want := range test.want[y] {
+					if got := img.NRGBAAt(x, y); got != want {
+						t.Fatalf("pixel %v,%v is %v, it has to be %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+/*
+TestHugeHeader ...
+A header can ask for a 65535x65535 image in a few bytes, that has to fail without allocating it
+*/
+func TestHugeHeader(t *testing.T) {
+	data := testgen.TGA(testgen.TGAImage{Type: 10, Width: 0xFFFF, Height: 0xFFFF, PixelDepth: 32, Data: []byte{0xFF, 1, 2}})
+	if _, err := Read(data); !errors.Is(err, formats.ErrTruncated) {
+		t.Fatalf("reading gave %v, the image data has to be truncated", err)
+	}
+
+	//Decode is given the header without Read checking it first.
+	file := &File{Header: Header{ImageType: RLETrueColor, Width: 0xFFFF, Height: 0xFFFF, PixelDepth: 32}, ImageData: data[0x12:]}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := Decode(file)
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, formats.ErrTruncated) || after.TotalAlloc-before.TotalAlloc > 1<<20 {
+		t.Fatalf("decoding gave %v after allocating 0x%X bytes", err, after.TotalAlloc-before.TotalAlloc)
+	}
+}